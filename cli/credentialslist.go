@@ -20,7 +20,8 @@ var listCredentialsCmd = &cobra.Command{
 			logger.Log.Errorf("The sources section of the config file is invalid: %v", err)
 			return err
 		}
-		allCredentials, err := configuration.Sources.Credentials()
+		configuration.Sources.SetDefaultRetryPolicy(configuration.Retry)
+		allCredentials, err := configuration.Sources.Credentials(cmd.Context())
 		if err != nil {
 			logger.Log.Errorf("The credential extraction for all configured sources failed: %v", err)
 			return err
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/coveooss/credentials-sync/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	planOutputFormat string
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Shows what the sync command would change on targets, without modifying them",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if planOutputFormat != "text" && planOutputFormat != "json" {
+			return fmt.Errorf("Invalid output format: %s. Valid formats are `text` and `json`", planOutputFormat)
+		}
+		if err := configuration.ValidateConfiguration(); err != nil {
+			logger.Log.Errorf("The config file is invalid: %v", err)
+			return err
+		}
+		if err := configuration.Sources.ValidateConfiguration(); err != nil {
+			logger.Log.Errorf("The sources section of the config file is invalid: %v", err)
+			return err
+		}
+		if err := configuration.Targets.ValidateConfiguration(); err != nil {
+			logger.Log.Errorf("The targets section of the config file is invalid: %v", err)
+			return err
+		}
+		plans, planErr := configuration.Plan(cmd.Context())
+		if plans != nil {
+			if planOutputFormat == "json" {
+				output, err := json.MarshalIndent(plans, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(output))
+			} else {
+				for _, plan := range plans {
+					fmt.Println(plan.ToString())
+				}
+			}
+		}
+		if planErr != nil {
+			logger.Log.Errorf("The planning process failed: %v", planErr)
+			return planErr
+		}
+		return nil
+	},
+}
+
+func initPlan() {
+	planCmd.Flags().StringVarP(&planOutputFormat, "output", "o", "text", "output format, `text` or `json`")
+	rootCmd.AddCommand(planCmd)
+}
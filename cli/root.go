@@ -1,11 +1,15 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
@@ -55,8 +59,8 @@ var rootCmd = &cobra.Command{
 		}
 
 		configuration = sync.NewConfiguration()
-		sourcesConfiguration := &credentials.SourcesConfiguration{}
-		targetsConfiguration := &targets.Configuration{}
+		sourcesConfiguration := credentials.NewSourcesConfiguration()
+		targetsConfiguration := targets.NewConfiguration()
 
 		if strings.HasPrefix(configurationFile, "s3://") {
 			sess := session.Must(session.NewSessionWithOptions(session.Options{
@@ -91,26 +95,95 @@ var rootCmd = &cobra.Command{
 		}
 
 		// Get the config
-		if err = mapstructure.Decode(configurationDict, configuration); err != nil {
+		if err = decode(configurationDict, configuration); err != nil {
 			return err
 		}
 
 		// Get sources from config
-		if err = mapstructure.Decode(configurationDict["sources"], sourcesConfiguration); err != nil {
+		sources, err := decodeRegistered(configurationDict["sources"], "sources", credentials.RegisteredSources(), credentials.NewSource)
+		if err != nil {
 			return err
 		}
+		sourcesConfiguration.AddSources(sources...)
 		configuration.SetSources(sourcesConfiguration)
 
 		// Get targets from config
-		if err = mapstructure.Decode(configurationDict["targets"], targetsConfiguration); err != nil {
+		targetsList, err := decodeRegistered(configurationDict["targets"], "targets", targets.Registered(), targets.New)
+		if err != nil {
 			return err
 		}
+		targetsConfiguration.AddTargets(targetsList...)
 		configuration.SetTargets(targetsConfiguration)
 
 		return nil
 	},
 }
 
+// decode decodes a section of the configuration file. Durations can be given as strings (ex: `30s` or `5m`)
+func decode(input interface{}, output interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+		Result:     output,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
+
+// decodeRegistered decodes a section of the configuration file in which each key is a registered type (of source or
+// target), associated with a list of configurations. The items are returned in the order in which the types are registered
+func decodeRegistered[T any](section interface{}, sectionName string, registered []string, newItem func(name string) (T, error)) ([]T, error) {
+	items := []T{}
+	if section == nil {
+		return items, nil
+	}
+	sectionMap, ok := section.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("The %s section of the config file must be a map", sectionName)
+	}
+	for name := range sectionMap {
+		if !slices.Contains(registered, name) {
+			return nil, fmt.Errorf("Unknown type `%s` in the %s section of the config file. Valid types are: %s", name, sectionName, strings.Join(registered, ", "))
+		}
+	}
+
+	for _, name := range registered {
+		configurations, ok := sectionMap[name].([]interface{})
+		if !ok {
+			if sectionMap[name] != nil {
+				return nil, fmt.Errorf("The `%s` entry of the %s section of the config file must be a list", name, sectionName)
+			}
+			continue
+		}
+		for _, itemConfiguration := range configurations {
+			item, err := newItem(name)
+			if err != nil {
+				return nil, err
+			}
+			if err := decode(itemConfiguration, item); err != nil {
+				return nil, fmt.Errorf("Invalid `%s` entry in the %s section of the config file: %v", name, sectionName, err)
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// shutdownContext returns a context that is cancelled when the process receives SIGINT or SIGTERM
+// This lets the operations in progress complete before exiting. Receiving a second signal exits immediately
+func shutdownContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	stopWarning := context.AfterFunc(ctx, func() {
+		stop()
+		logger.Log.Warning("Received a termination signal. Waiting for the operations in progress to complete, send it again to exit immediately")
+	})
+	return ctx, func() {
+		stopWarning()
+		stop()
+	}
+}
+
 func init() {
 	logger.Log.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
 
@@ -124,13 +197,18 @@ func init() {
 	viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level"))
 
 	initListCredentials()
-	rootCmd.AddCommand(listTargetsCmd, syncCmd, validateCmd)
+	initPlan()
+	initSync()
+	rootCmd.AddCommand(listTargetsCmd, validateCmd)
 }
 
 // Execute runs the CLI
 func Execute(commit string, date string, version string) {
 	rootCmd.Version = fmt.Sprintf("%s %s (%s)", version, commit, date)
-	if err := rootCmd.Execute(); err != nil {
+	logger.InitSentry()
+	ctx, stop := shutdownContext()
+	defer stop()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		logger.Log.Fatal("Credential sync failed, the errors encountered are listed above.")
 	}
 }
@@ -1,14 +1,29 @@
 package cli
 
 import (
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/coveooss/credentials-sync/logger"
+	"github.com/coveooss/credentials-sync/sync"
 	"github.com/spf13/cobra"
 )
 
+var (
+	fullSync        bool
+	reportFile      string
+	junitReportFile string
+)
+
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Fetches credentials and syncs them to targets",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := configuration.ValidateConfiguration(); err != nil {
+			logger.Log.Errorf("The config file is invalid: %v", err)
+			return err
+		}
 		if err := configuration.Sources.ValidateConfiguration(); err != nil {
 			logger.Log.Errorf("The sources section of the config file is invalid: %v", err)
 			return err
@@ -17,10 +32,52 @@ var syncCmd = &cobra.Command{
 			logger.Log.Errorf("The targets section of the config file is invalid: %v", err)
 			return err
 		}
-		if err := configuration.Sync(); err != nil {
-			logger.Log.Errorf("The synchronization process failed: %v", err)
-			return err
+		configuration.FullSync = fullSync
+		results, syncErr := configuration.Sync(cmd.Context())
+		if err := writeReports(sync.NewReport(results, syncErr)); err != nil {
+			logger.Log.Errorf("Unable to write the sync report: %v", err)
+			if syncErr == nil {
+				return err
+			}
+		}
+		if syncErr != nil {
+			logger.Log.Errorf("The synchronization process failed: %v", syncErr)
+			return syncErr
 		}
 		return nil
 	},
 }
+
+// writeReports writes the report of the sync to the files given with the --report and --junit flags
+func writeReports(report *sync.Report) error {
+	if reportFile != "" {
+		if err := writeReportFile(reportFile, report.WriteJSON); err != nil {
+			return err
+		}
+	}
+	if junitReportFile != "" {
+		if err := writeReportFile(junitReportFile, report.WriteJUnit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeReportFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return file.Close()
+}
+
+func initSync() {
+	syncCmd.Flags().BoolVar(&fullSync, "full", false, "sync all credentials, even those that did not change since the last sync")
+	syncCmd.Flags().StringVar(&reportFile, "report", "", "write a JSON report of the sync to the given file")
+	syncCmd.Flags().StringVar(&junitReportFile, "junit", "", "write a JUnit XML report of the sync to the given file")
+	rootCmd.AddCommand(syncCmd)
+}
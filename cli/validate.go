@@ -9,6 +9,10 @@ var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Parses and validates the given configuration",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := configuration.ValidateConfiguration(); err != nil {
+			logger.Log.Errorf("The config file is invalid: %v", err)
+			return err
+		}
 		if err := configuration.Sources.ValidateConfiguration(); err != nil {
 			logger.Log.Errorf("The sources section of the config file is invalid: %v", err)
 			return err
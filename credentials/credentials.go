@@ -2,6 +2,7 @@ package credentials
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/coveooss/credentials-sync/logger"
 	"github.com/hashicorp/go-multierror"
@@ -12,6 +13,7 @@ import (
 type Credentials interface {
 	BaseValidate() error
 	GetID() string
+	GetSensitiveValues() []string
 	GetTargetID() string
 	ShouldSync(targetName string, targetTags map[string]string) bool
 	ToString(bool) string
@@ -32,11 +34,17 @@ type Base struct {
 	TargetTags  targetTagsMatcher `mapstructure:"target_tags"`
 	TargetID    string            `mapstructure:"target_id"`
 
+	// Target type (e.g. `gitlab`) -> Options of the credentials on the targets of that type, decoded by the targets
+	TargetOptions map[string]map[string]interface{} `mapstructure:"target_options"`
+
 	// Field set by constructor
 	CredType string
 
 	// For multi-value fields. Such as SSM
 	Value string
+
+	// Type of the source the credentials were fetched from. Set when fetching from the sources
+	source string
 }
 
 // BaseToString prints out the credentials fields common to all types of credentials
@@ -72,6 +80,21 @@ func (credBase *Base) GetID() string {
 	return credBase.ID
 }
 
+// GetTargetOptions returns the options of the credentials on the given type of target, or nil if there are none
+func (credBase *Base) GetTargetOptions(targetType string) map[string]interface{} {
+	return credBase.TargetOptions[targetType]
+}
+
+// GetSource returns the type of the source the credentials were fetched from, or an empty string if it is unknown
+func (credBase *Base) GetSource() string {
+	return credBase.source
+}
+
+// SetSource records the type of the source the credentials were fetched from
+func (credBase *Base) SetSource(source string) {
+	credBase.source = source
+}
+
 // GetTargetID returns a credentials' Target ID (Essentially, the name that the credentials should have on a target)
 // This is helpful to have different credentials with the same target ID (on different targets)
 func (credBase *Base) GetTargetID() string {
@@ -97,6 +120,7 @@ func (credBase *Base) ShouldSync(targetName string, targetTags map[string]string
 				if key != tagKey {
 					continue
 				}
+				// Other types of values are ignored, a warning is logged when the credentials are fetched
 				if valueAsString, ok := value.(string); ok {
 					if valueAsString == tag {
 						return true
@@ -105,8 +129,6 @@ func (credBase *Base) ShouldSync(targetName string, targetTags map[string]string
 					if listContainsElement(valueAsList, tag) {
 						return true
 					}
-				} else {
-					logger.Log.Warningf("%s ignored. Its value should either be a string or a list of string", key)
 				}
 			}
 		}
@@ -116,8 +138,25 @@ func (credBase *Base) ShouldSync(targetName string, targetTags map[string]string
 	return !findMatch(credBase.TargetTags.DontMatch) && (len(credBase.TargetTags.DoMatch) == 0 || findMatch(credBase.TargetTags.DoMatch))
 }
 
+// invalidTargetTags returns the keys of the target tags whose value is neither a string nor a list of string
+// These tags are ignored when matching targets
+func (credBase *Base) invalidTargetTags() []string {
+	keys := []string{}
+	for _, match := range []map[string]interface{}{credBase.TargetTags.DoMatch, credBase.TargetTags.DontMatch} {
+		for key, value := range match {
+			switch value.(type) {
+			case string, []string:
+			default:
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // ParseCredentials transforms a list of maps into a list of Credentials
-// The credentials type is determined by the `type` attribute
+// The credentials type is determined by the `type` attribute, which must be a registered type (see RegisterType)
 func ParseCredentials(credentialsMaps []map[string]interface{}) ([]Credentials, error) {
 	credentialsList := make([]Credentials, 0)
 	for _, credentialsMap := range credentialsMaps {
@@ -131,10 +170,9 @@ func ParseCredentials(credentialsMaps []map[string]interface{}) ([]Credentials,
 }
 
 // ParseSingleCredentials transforms a map into a Credentials struct
-// The credentials type is determined by the `type` attribute
+// The credentials type is determined by the `type` attribute, which must be a registered type (see RegisterType)
 func ParseSingleCredentials(credentialsMap map[string]interface{}) (Credentials, error) {
 	var credentialsType string
-	var credentials Credentials
 	var id = credentialsMap["id"]
 	if value, ok := credentialsMap["type"]; ok {
 		if credentialsType, ok = value.(string); !ok {
@@ -144,18 +182,8 @@ func ParseSingleCredentials(credentialsMap map[string]interface{}) (Credentials,
 		return nil, fmt.Errorf("entry %s: unable to find the credentials type %s", id, credentialsType)
 	}
 
-	switch credentialsType {
-	case "aws":
-		credentials = NewAmazonWebServicesCredentials()
-	case "usernamepassword":
-		credentials = NewUsernamePassword()
-	case "secret":
-		credentials = NewSecretText()
-	case "ssh":
-		credentials = NewSSHCredentials()
-	case "github_app":
-		credentials = NewGithubAppCredentials()
-	default:
+	credentials, ok := newCredentialsOfType(credentialsType)
+	if !ok {
 		return nil, fmt.Errorf("entry %s: unknown credentials type: %s", id, credentialsType)
 	}
 	var validationErrors error
@@ -168,9 +196,14 @@ func ParseSingleCredentials(credentialsMap map[string]interface{}) (Credentials,
 		validationErrors = multierror.Append(validationErrors, fmt.Errorf("entry %s: unable to create a decoder: %v", id, err))
 	}
 	if err := decoder.Decode(credentialsMap); err != nil {
-		return nil, fmt.Errorf("entry %s: invalid credentials data: %w", id, err)
+		// The decoding errors can include the values of the entry
+		return nil, sensitiveEntryValues(credentialsMap).RedactError(fmt.Errorf("entry %s: invalid credentials data: %w", id, err))
 	}
 	credentialsMap["type"] = credentialsType
+	// The values are collected before and after the validation, since some types convert them while validating
+	// They are registered in the set of sensitive values of the sync once the credentials are fetched (see SourcesConfiguration.Credentials)
+	sensitiveValues := logger.NewSensitiveValues()
+	sensitiveValues.Add(credentials.GetSensitiveValues()...)
 
 	if err := credentials.BaseValidate(); err != nil {
 		validationErrors = multierror.Append(validationErrors, err)
@@ -178,12 +211,30 @@ func ParseSingleCredentials(credentialsMap map[string]interface{}) (Credentials,
 	if err := credentials.Validate(); err != nil {
 		validationErrors = multierror.Append(validationErrors, err)
 	}
+	sensitiveValues.Add(credentials.GetSensitiveValues()...)
 	if validationErrors != nil {
-		return nil, fmt.Errorf("the following credentials failed to validate: %v -> %v", credentials.ToString(false), validationErrors)
+		return nil, sensitiveValues.RedactError(fmt.Errorf("the following credentials failed to validate: %v -> %v", credentials.ToString(false), validationErrors))
 	}
 	return credentials, nil
 }
 
+// sensitiveEntryValues returns all values of a credentials entry that cannot be decoded, except the ones that
+// identify it
+func sensitiveEntryValues(credentialsMap map[string]interface{}) *logger.SensitiveValues {
+	sensitiveValues := logger.NewSensitiveValues()
+	for key, value := range credentialsMap {
+		switch key {
+		case "id", "description", "target", "target_id":
+			continue
+		}
+		switch value.(type) {
+		case string, int, int64, float64:
+			sensitiveValues.Add(fmt.Sprint(value))
+		}
+	}
+	return sensitiveValues
+}
+
 func listContainsElement(list []string, element string) bool {
 	for _, listElement := range list {
 		if listElement == element {
@@ -31,13 +31,18 @@ func (cred *AmazonWebServicesCredentials) ToString(showSensitive bool) string {
 	return fmt.Sprintf("%s - %s:%s", cred.BaseToString(), cred.AccessKey, secretKey)
 }
 
+// GetSensitiveValues returns the values that must not be logged: the secret access key
+func (cred *AmazonWebServicesCredentials) GetSensitiveValues() []string {
+	return []string{cred.Value, cred.SecretKey}
+}
+
 // Validate verifies that the credentials is valid.
 // A AmazonWebServicesCredentials must define an access key and a secret access key
 func (cred *AmazonWebServicesCredentials) Validate() error {
 	if cred.AccessKey == "" && cred.SecretKey == "" && cred.Value != "" {
 		splitValue := strings.Split(cred.Value, ":")
 		if len(splitValue) != 2 {
-			return fmt.Errorf("The credentials with ID %s has an invalid access_key:secret_key value: %s", cred.ID, cred.Value)
+			return fmt.Errorf("The credentials with ID %s has an invalid access_key:secret_key value", cred.ID)
 		}
 		cred.AccessKey = splitValue[0]
 		cred.SecretKey = splitValue[1]
@@ -0,0 +1,127 @@
+package credentials
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+
+	"golang.org/x/crypto/hkdf"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// CertificateCredentials represents a client certificate and its private key, stored in a password-protected PKCS#12 keystore
+// The keystore is given either directly (encoded in base64), or as a PEM certificate (chain) and private key
+type CertificateCredentials struct {
+	Base        `mapstructure:",squash"`
+	PKCS12      string `mapstructure:"pkcs12"`
+	Certificate string `mapstructure:"certificate"`
+	PrivateKey  string `mapstructure:"private_key"`
+	Password    string `mapstructure:"password"`
+}
+
+// NewCertificateCredentials instantiates a CertificateCredentials struct
+func NewCertificateCredentials() *CertificateCredentials {
+	cred := &CertificateCredentials{}
+	cred.CredType = "Certificate"
+	return cred
+}
+
+// GetPKCS12 returns the PKCS#12 keystore, protected by the credentials' password
+// A PEM certificate and private key are converted to a keystore. The conversion is deterministic, so that the keystore
+// does not change as long as the credentials do not change
+func (cred *CertificateCredentials) GetPKCS12() ([]byte, error) {
+	if cred.PKCS12 != "" {
+		keystore, err := base64.StdEncoding.DecodeString(cred.PKCS12)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 keystore: %v", err)
+		}
+		if _, _, _, err := pkcs12.DecodeChain(keystore, cred.Password); err != nil {
+			return nil, fmt.Errorf("invalid keystore: %v", err)
+		}
+		return keystore, nil
+	}
+
+	certificates := []*x509.Certificate{}
+	rest := []byte(cred.Certificate)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %v", err)
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	privateKey, err := parsePEMPrivateKey(cred.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// The salts and IVs are derived from the credentials instead of being random
+	random := hkdf.New(sha256.New, []byte(cred.Certificate+cred.PrivateKey+cred.Password), nil, []byte("credentials-sync pkcs12"))
+	return pkcs12.Modern.WithRand(random).Encode(privateKey, certificates[0], certificates[1:], cred.Password)
+}
+
+// parsePEMPrivateKey parses a PKCS#1, PKCS#8 or EC private key
+func parsePEMPrivateKey(privateKey string) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported private key format (%s)", block.Type)
+}
+
+// ToString prints out the content of a CertificateCredentials struct.
+// If showSensitive is true, the password will be shown
+func (cred *CertificateCredentials) ToString(showSensitive bool) string {
+	password := "********"
+	if showSensitive {
+		password = cred.Password
+	}
+	format := "PEM"
+	if cred.PKCS12 != "" {
+		format = "PKCS#12"
+	}
+	return fmt.Sprintf("%s - %s:%s", cred.BaseToString(), format, password)
+}
+
+// GetSensitiveValues returns the values that must not be logged: the keystore, the private key and the password
+func (cred *CertificateCredentials) GetSensitiveValues() []string {
+	return []string{cred.Value, cred.PKCS12, cred.PrivateKey, cred.Password}
+}
+
+// Validate verifies that the credentials is valid.
+// A CertificateCredentials must have either a PKCS#12 keystore or a PEM certificate and private key, which can be
+// converted to a keystore. A password is required to protect a converted keystore
+func (cred *CertificateCredentials) Validate() error {
+	if (cred.PKCS12 == "") == (cred.Certificate == "" && cred.PrivateKey == "") {
+		return fmt.Errorf("the credentials with ID %s must define either `pkcs12` or `certificate` and `private_key`", cred.ID)
+	}
+	if cred.PKCS12 == "" && cred.Password == "" {
+		return fmt.Errorf("the credentials with ID %s must define a password to protect its keystore", cred.ID)
+	}
+	if _, err := cred.GetPKCS12(); err != nil {
+		return fmt.Errorf("the credentials with ID %s has an invalid certificate: %v", cred.ID, err)
+	}
+	return nil
+}
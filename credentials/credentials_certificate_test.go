@@ -0,0 +1,131 @@
+package credentials
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// newTestCertificate generates a self-signed PEM certificate and its PKCS#8 private key
+func newTestCertificate(t *testing.T) (string, string) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "credentials-sync"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	assert.NoError(t, err)
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	assert.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes}))
+}
+
+func TestCertificateCredentialsToString(t *testing.T) {
+	cred := NewCertificateCredentials()
+	cred.ID = "test"
+	cred.Certificate = "cert"
+	cred.Password = "secret"
+	assert.Equal(t, "test -> Type: Certificate - PEM:********", cred.ToString(false))
+	assert.Equal(t, "test -> Type: Certificate - PEM:secret", cred.ToString(true))
+
+	cred.PKCS12 = "AAEC"
+	assert.Equal(t, "test -> Type: Certificate - PKCS#12:********", cred.ToString(false))
+}
+
+func TestCertificateCredentialsFromPEM(t *testing.T) {
+	t.Parallel()
+
+	certificate, privateKey := newTestCertificate(t)
+	cred := NewCertificateCredentials()
+	cred.ID = "test"
+	cred.Certificate = certificate
+	cred.PrivateKey = privateKey
+	cred.Password = "secret"
+	assert.NoError(t, cred.Validate())
+
+	keystore, err := cred.GetPKCS12()
+	assert.NoError(t, err)
+	decodedKey, decodedCertificate, err := pkcs12.Decode(keystore, "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "credentials-sync", decodedCertificate.Subject.CommonName)
+	assert.IsType(t, &ecdsa.PrivateKey{}, decodedKey)
+
+	// The conversion is deterministic
+	otherKeystore, err := cred.GetPKCS12()
+	assert.NoError(t, err)
+	assert.Equal(t, keystore, otherKeystore)
+
+	// The keystore can be given directly
+	keystoreCred := NewCertificateCredentials()
+	keystoreCred.ID = "test"
+	keystoreCred.PKCS12 = base64.StdEncoding.EncodeToString(keystore)
+	keystoreCred.Password = "secret"
+	assert.NoError(t, keystoreCred.Validate())
+	keystoreCred.Password = "wrong"
+	assert.ErrorContains(t, keystoreCred.Validate(), "the credentials with ID test has an invalid certificate: invalid keystore")
+}
+
+func TestCertificateCredentialsValidation(t *testing.T) {
+	t.Parallel()
+
+	certificate, privateKey := newTestCertificate(t)
+	cases := map[string]struct {
+		credentialsMap map[string]interface{}
+		expectedError  string
+	}{
+		"PEM": {
+			credentialsMap: map[string]interface{}{"certificate": certificate, "private_key": privateKey, "password": "secret"},
+		},
+		"Missing certificate": {
+			credentialsMap: map[string]interface{}{"password": "secret"},
+			expectedError:  "the credentials with ID test must define either `pkcs12` or `certificate` and `private_key`",
+		},
+		"Both formats": {
+			credentialsMap: map[string]interface{}{"pkcs12": "AAEC", "certificate": certificate, "private_key": privateKey, "password": "secret"},
+			expectedError:  "the credentials with ID test must define either `pkcs12` or `certificate` and `private_key`",
+		},
+		"Missing password": {
+			credentialsMap: map[string]interface{}{"certificate": certificate, "private_key": privateKey},
+			expectedError:  "the credentials with ID test must define a password to protect its keystore",
+		},
+		"Missing private key": {
+			credentialsMap: map[string]interface{}{"certificate": certificate, "password": "secret"},
+			expectedError:  "the credentials with ID test has an invalid certificate: no PEM private key found",
+		},
+		"Invalid certificate": {
+			credentialsMap: map[string]interface{}{"certificate": "not a certificate", "private_key": privateKey, "password": "secret"},
+			expectedError:  "the credentials with ID test has an invalid certificate: no PEM certificate found",
+		},
+		"Invalid keystore": {
+			credentialsMap: map[string]interface{}{"pkcs12": "AAEC", "password": "secret"},
+			expectedError:  "the credentials with ID test has an invalid certificate: invalid keystore",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			tt.credentialsMap["id"] = "test"
+			tt.credentialsMap["type"] = "certificate"
+			_, err := ParseSingleCredentials(tt.credentialsMap)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -1,7 +1,11 @@
 package credentials
 
 import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"net/url"
 )
 
 // GithubAppCredentials represents credentials composed of an App ID, private key, and owner
@@ -10,6 +14,8 @@ type GithubAppCredentials struct {
 	AppID      int    `mapstructure:"app_id"`
 	PrivateKey string `mapstructure:"private_key"`
 	Owner      string `mapstructure:"owner"`
+	// URL of the GitHub API, for GitHub Enterprise Server. Targets use their default if it is not set
+	APIURI string `mapstructure:"api_uri"`
 }
 
 // NewGithubAppCredentials instantiates a GithubAppCredentials struct
@@ -30,18 +36,79 @@ func (cred *GithubAppCredentials) ToString(showSensitive bool) string {
 	if len(cred.Owner) > 0 {
 		appIDOwner = fmt.Sprintf("%s(%s)", appIDOwner, cred.Owner)
 	}
+	if cred.APIURI != "" {
+		appIDOwner = fmt.Sprintf("%s@%s", appIDOwner, cred.APIURI)
+	}
 	return fmt.Sprintf("%s - %s:%s", cred.BaseToString(), appIDOwner, privateKeyText)
 }
 
+// GetSensitiveValues returns the values that must not be logged: the private key
+func (cred *GithubAppCredentials) GetSensitiveValues() []string {
+	return []string{cred.Value, cred.PrivateKey}
+}
+
 // Validate verifies that the credentials is valid.
-// A GithubAppCredentials must have an app id and a private key.  Owner is optional.
+// A GithubAppCredentials must have an app id and a RSA private key.  Owner and API URI are optional.
+// The private key is converted to PKCS#8 if it is in the PKCS#1 format given by GitHub, since Jenkins only supports PKCS#8
 func (cred *GithubAppCredentials) Validate() error {
 	switch {
 	case cred.AppID == 0:
 		return fmt.Errorf("the credentials with ID %s does not define an app ID", cred.ID)
 	case len(cred.PrivateKey) == 0:
 		return fmt.Errorf("the credentials with ID %s does not define a private key", cred.ID)
-	default:
-		return nil
 	}
+	if cred.APIURI != "" {
+		if apiURL, err := url.ParseRequestURI(cred.APIURI); err != nil || (apiURL.Scheme != "http" && apiURL.Scheme != "https") || apiURL.Host == "" {
+			return fmt.Errorf("the credentials with ID %s has an invalid API URI: %s", cred.ID, cred.APIURI)
+		}
+	}
+	privateKey, err := toPKCS8PrivateKey(cred.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("the credentials with ID %s has an invalid private key: %v", cred.ID, err)
+	}
+	cred.PrivateKey = privateKey
+	return nil
+}
+
+// GetRSAPrivateKey returns the parsed private key of the GitHub App
+func (cred *GithubAppCredentials) GetRSAPrivateKey() (*rsa.PrivateKey, error) {
+	key, _, err := parseRSAPrivateKey(cred.PrivateKey)
+	return key, err
+}
+
+// parseRSAPrivateKey parses the given PEM RSA private key, in the PKCS#8 or in the PKCS#1 format
+// The second return value is true if the key is in the PKCS#8 format
+func parseRSAPrivateKey(privateKey string) (*rsa.PrivateKey, bool, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, false, fmt.Errorf("no PEM private key found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, isRSA := key.(*rsa.PrivateKey)
+		if !isRSA {
+			return nil, false, fmt.Errorf("the private key is not a RSA key")
+		}
+		return rsaKey, true, nil
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, false, fmt.Errorf("the private key is neither in the PKCS#8 nor in the PKCS#1 format")
+	}
+	return key, false, nil
+}
+
+// toPKCS8PrivateKey returns the given PEM RSA private key in the PKCS#8 format. PKCS#1 keys are converted
+func toPKCS8PrivateKey(privateKey string) (string, error) {
+	key, isPKCS8, err := parseRSAPrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	if isPKCS8 {
+		return privateKey, nil
+	}
+	pkcs8Key, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Key})), nil
 }
@@ -11,6 +11,7 @@ func TestGithubAppCredentials(t *testing.T) {
 		givenID            string
 		givenAppID         int
 		givenOwner         string
+		givenAPIURI        string
 		givenPrivate       string
 		givenShowSensitive bool
 		expectString       string
@@ -19,6 +20,7 @@ func TestGithubAppCredentials(t *testing.T) {
 		"with owner":                  {givenID: "test", givenAppID: 2, givenOwner: "owner", givenPrivate: "private", givenShowSensitive: false, expectString: "test -> Type: Github App - 2(owner):********"},
 		"without owner showSensitive": {givenID: "test", givenAppID: 1, givenOwner: "", givenPrivate: "private", givenShowSensitive: true, expectString: "test -> Type: Github App - 1:private"},
 		"with owner showSensitive":    {givenID: "test", givenAppID: 2, givenOwner: "owner", givenPrivate: "private", givenShowSensitive: true, expectString: "test -> Type: Github App - 2(owner):private"},
+		"with api uri":                {givenID: "test", givenAppID: 2, givenOwner: "owner", givenAPIURI: "https://github.example.com/api/v3", givenPrivate: "private", givenShowSensitive: false, expectString: "test -> Type: Github App - 2(owner)@https://github.example.com/api/v3:********"},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -26,6 +28,7 @@ func TestGithubAppCredentials(t *testing.T) {
 			cred.ID = test.givenID
 			cred.AppID = test.givenAppID
 			cred.Owner = test.givenOwner
+			cred.APIURI = test.givenAPIURI
 			cred.PrivateKey = test.givenPrivate
 			assert.Equal(t, test.expectString, cred.ToString(test.givenShowSensitive))
 		})
@@ -34,37 +37,86 @@ func TestGithubAppCredentials(t *testing.T) {
 
 func TestGithubAppCredentialsValidation(t *testing.T) {
 	tests := map[string]struct {
-		givenCred   GithubAppCredentials
-		expectError bool
+		givenCred        GithubAppCredentials
+		expectError      string
+		expectPrivateKey string
 	}{
 		"valid": {givenCred: GithubAppCredentials{
 			AppID:      12345,
-			PrivateKey: "private",
+			PrivateKey: testGithubAppPKCS8Key,
 			Owner:      "Me",
-		}, expectError: false},
+		}, expectPrivateKey: testGithubAppPKCS8Key},
 		"valid no owner": {givenCred: GithubAppCredentials{
 			AppID:      12345,
-			PrivateKey: "private",
-		}, expectError: false},
+			PrivateKey: testGithubAppPKCS8Key,
+		}, expectPrivateKey: testGithubAppPKCS8Key},
+		"valid api uri": {givenCred: GithubAppCredentials{
+			AppID:      12345,
+			PrivateKey: testGithubAppPKCS8Key,
+			APIURI:     "https://github.example.com/api/v3",
+		}, expectPrivateKey: testGithubAppPKCS8Key},
+		"pkcs1 private key is converted": {givenCred: GithubAppCredentials{
+			AppID:      12345,
+			PrivateKey: testGithubAppPKCS1Key,
+		}, expectPrivateKey: testGithubAppPKCS8Key},
 		"missing app id": {givenCred: GithubAppCredentials{
-			PrivateKey: "private",
-		}, expectError: true},
+			PrivateKey: testGithubAppPKCS8Key,
+		}, expectError: "the credentials with ID test does not define an app ID"},
 		"missing private key": {givenCred: GithubAppCredentials{
 			AppID: 12345,
-		}, expectError: true},
+		}, expectError: "the credentials with ID test does not define a private key"},
+		"invalid private key": {givenCred: GithubAppCredentials{
+			AppID:      12345,
+			PrivateKey: "private",
+		}, expectError: "the credentials with ID test has an invalid private key: no PEM private key found"},
+		"invalid api uri": {givenCred: GithubAppCredentials{
+			AppID:      12345,
+			PrivateKey: testGithubAppPKCS8Key,
+			APIURI:     "github.example.com",
+		}, expectError: "the credentials with ID test has an invalid API URI: github.example.com"},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
 			cred := NewGithubAppCredentials()
+			cred.ID = "test"
 			cred.AppID = test.givenCred.AppID
 			cred.PrivateKey = test.givenCred.PrivateKey
 			cred.Owner = test.givenCred.Owner
+			cred.APIURI = test.givenCred.APIURI
 			err := cred.Validate()
-			if test.expectError {
-				assert.Error(t, err)
+			if test.expectError != "" {
+				assert.EqualError(t, err, test.expectError)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, test.expectPrivateKey, cred.PrivateKey)
 			}
 		})
 	}
 }
+
+func TestGithubAppCredentialsWithNonRSAKey(t *testing.T) {
+	_, privateKey := newTestCertificate(t)
+	cred := NewGithubAppCredentials()
+	cred.ID = "test"
+	cred.AppID = 12345
+	cred.PrivateKey = privateKey
+	assert.EqualError(t, cred.Validate(), "the credentials with ID test has an invalid private key: the private key is not a RSA key")
+}
+
+func TestGithubAppCredentialsGetRSAPrivateKey(t *testing.T) {
+	pkcs1Cred := NewGithubAppCredentials()
+	pkcs1Cred.PrivateKey = testGithubAppPKCS1Key
+	pkcs1Key, err := pkcs1Cred.GetRSAPrivateKey()
+	assert.NoError(t, err)
+
+	pkcs8Cred := NewGithubAppCredentials()
+	pkcs8Cred.PrivateKey = testGithubAppPKCS8Key
+	pkcs8Key, err := pkcs8Cred.GetRSAPrivateKey()
+	assert.NoError(t, err)
+	assert.True(t, pkcs1Key.Equal(pkcs8Key))
+
+	invalidCred := NewGithubAppCredentials()
+	invalidCred.PrivateKey = "private"
+	_, err = invalidCred.GetRSAPrivateKey()
+	assert.EqualError(t, err, "no PEM private key found")
+}
@@ -60,6 +60,20 @@ func (mr *MockCredentialsMockRecorder) GetID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetID", reflect.TypeOf((*MockCredentials)(nil).GetID))
 }
 
+// GetSensitiveValues mocks base method
+func (m *MockCredentials) GetSensitiveValues() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSensitiveValues")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetSensitiveValues indicates an expected call of GetSensitiveValues
+func (mr *MockCredentialsMockRecorder) GetSensitiveValues() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSensitiveValues", reflect.TypeOf((*MockCredentials)(nil).GetSensitiveValues))
+}
+
 // GetTargetID mocks base method
 func (m *MockCredentials) GetTargetID() string {
 	m.ctrl.T.Helper()
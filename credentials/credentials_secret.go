@@ -30,6 +30,11 @@ func (cred *SecretTextCredentials) ToString(showSensitive bool) string {
 	return fmt.Sprintf("%s - %s", cred.BaseToString(), secretText)
 }
 
+// GetSensitiveValues returns the values that must not be logged: the secret text
+func (cred *SecretTextCredentials) GetSensitiveValues() []string {
+	return []string{cred.Value, cred.Secret}
+}
+
 // Validate verifies that the credentials is valid.
 // A SecretTextCredentials is always considered valid, as empty values are accepted.
 func (cred *SecretTextCredentials) Validate() error {
@@ -0,0 +1,63 @@
+package credentials
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// SecretFileCredentials represents credentials composed of a file name and the content of the file
+// The content is given either as plain text or encoded in base64 (for binary files)
+type SecretFileCredentials struct {
+	Base          `mapstructure:",squash"`
+	Filename      string `mapstructure:"filename"`
+	Content       string `mapstructure:"content"`
+	ContentBase64 string `mapstructure:"content_base64"`
+}
+
+// NewSecretFileCredentials instantiates a SecretFileCredentials struct
+func NewSecretFileCredentials() *SecretFileCredentials {
+	cred := &SecretFileCredentials{}
+	cred.CredType = "Secret file"
+	return cred
+}
+
+// GetContent returns the content of the file, decoded if it is given in base64
+func (cred *SecretFileCredentials) GetContent() ([]byte, error) {
+	if cred.ContentBase64 != "" {
+		return base64.StdEncoding.DecodeString(cred.ContentBase64)
+	}
+	return []byte(cred.Content), nil
+}
+
+// ToString prints out the content of a SecretFileCredentials struct.
+// If showSensitive is true, the content of the file will be shown
+func (cred *SecretFileCredentials) ToString(showSensitive bool) string {
+	content := "********"
+	if showSensitive {
+		content = cred.Content
+		if cred.ContentBase64 != "" {
+			content = "base64:" + cred.ContentBase64
+		}
+	}
+	return fmt.Sprintf("%s - %s:%s", cred.BaseToString(), cred.Filename, content)
+}
+
+// GetSensitiveValues returns the values that must not be logged: the content of the file
+func (cred *SecretFileCredentials) GetSensitiveValues() []string {
+	return []string{cred.Value, cred.Content, cred.ContentBase64}
+}
+
+// Validate verifies that the credentials is valid.
+// A SecretFileCredentials must have a file name and either a plain or a base64 content
+func (cred *SecretFileCredentials) Validate() error {
+	if cred.Filename == "" {
+		return fmt.Errorf("the credentials with ID %s does not define a filename", cred.ID)
+	}
+	if (cred.Content == "") == (cred.ContentBase64 == "") {
+		return fmt.Errorf("the credentials with ID %s must define either `content` or `content_base64`", cred.ID)
+	}
+	if _, err := cred.GetContent(); err != nil {
+		return fmt.Errorf("the credentials with ID %s has an invalid `content_base64`: %v", cred.ID, err)
+	}
+	return nil
+}
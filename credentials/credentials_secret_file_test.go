@@ -0,0 +1,71 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretFileCredentialsToString(t *testing.T) {
+	cred := NewSecretFileCredentials()
+	cred.ID = "test"
+	cred.Filename = "kubeconfig"
+	cred.Content = "apiVersion: v1"
+	assert.Equal(t, "test -> Type: Secret file - kubeconfig:********", cred.ToString(false))
+	assert.Equal(t, "test -> Type: Secret file - kubeconfig:apiVersion: v1", cred.ToString(true))
+
+	cred.Content = ""
+	cred.ContentBase64 = "AAEC"
+	assert.Equal(t, "test -> Type: Secret file - kubeconfig:base64:AAEC", cred.ToString(true))
+}
+
+func TestSecretFileCredentialsValidation(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		credentialsMap map[string]interface{}
+		expectedError  string
+		expectedBytes  []byte
+	}{
+		"Plain content": {
+			credentialsMap: map[string]interface{}{"filename": "kubeconfig", "content": "apiVersion: v1"},
+			expectedBytes:  []byte("apiVersion: v1"),
+		},
+		"Base64 content": {
+			credentialsMap: map[string]interface{}{"filename": "cert.p12", "content_base64": "AAEC"},
+			expectedBytes:  []byte{0, 1, 2},
+		},
+		"Missing filename": {
+			credentialsMap: map[string]interface{}{"content": "apiVersion: v1"},
+			expectedError:  "the credentials with ID test does not define a filename",
+		},
+		"Missing content": {
+			credentialsMap: map[string]interface{}{"filename": "kubeconfig"},
+			expectedError:  "the credentials with ID test must define either `content` or `content_base64`",
+		},
+		"Both contents": {
+			credentialsMap: map[string]interface{}{"filename": "kubeconfig", "content": "a", "content_base64": "AAEC"},
+			expectedError:  "the credentials with ID test must define either `content` or `content_base64`",
+		},
+		"Invalid base64": {
+			credentialsMap: map[string]interface{}{"filename": "kubeconfig", "content_base64": "not base64!"},
+			expectedError:  "the credentials with ID test has an invalid `content_base64`: illegal base64 data at input byte 3",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			tt.credentialsMap["id"] = "test"
+			tt.credentialsMap["type"] = "secret_file"
+			cred, err := ParseSingleCredentials(tt.credentialsMap)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			content, err := cred.(*SecretFileCredentials).GetContent()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBytes, content)
+		})
+	}
+}
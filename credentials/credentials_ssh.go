@@ -36,6 +36,11 @@ func (cred *SSHCredentials) ToString(showSensitive bool) string {
 	return fmt.Sprintf("%s - %s:%s", cred.BaseToString(), username, passphrase)
 }
 
+// GetSensitiveValues returns the values that must not be logged: the private key and its passphrase
+func (cred *SSHCredentials) GetSensitiveValues() []string {
+	return []string{cred.Value, cred.PrivateKey, cred.Passphrase}
+}
+
 // Validate verifies that the credentials is valid.
 // A SSHCredentials must have a private key, the username and passphrase are optional
 func (cred *SSHCredentials) Validate() error {
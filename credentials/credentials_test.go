@@ -3,6 +3,7 @@ package credentials
 import (
 	"testing"
 
+	"github.com/coveooss/credentials-sync/logger"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -298,6 +299,27 @@ func TestParseCredentials(t *testing.T) {
 			}},
 			wantErr: false,
 		},
+		"Valid secret with target options": {
+			credMaps: []map[string]interface{}{
+				{
+					"id":     "stuff",
+					"type":   "secret",
+					"secret": "secret",
+					"target_options": map[string]interface{}{
+						"gitlab": map[string]interface{}{"masked": true},
+					},
+				},
+			},
+			result: []Credentials{&SecretTextCredentials{
+				Base: Base{
+					ID:            "stuff",
+					CredType:      "Secret text",
+					TargetOptions: map[string]map[string]interface{}{"gitlab": {"masked": true}},
+				},
+				Secret: "secret",
+			}},
+			wantErr: false,
+		},
 		"Valid ssh": {
 			credMaps: []map[string]interface{}{
 				{
@@ -327,8 +349,9 @@ func TestParseCredentials(t *testing.T) {
 					"id":          "stuff",
 					"type":        "github_app",
 					"app_id":      12345,
-					"private_key": "private",
+					"private_key": testGithubAppPKCS1Key,
 					"owner":       "owner",
+					"api_uri":     "https://github.example.com/api/v3",
 					"description": "test-desc",
 				},
 			},
@@ -339,8 +362,9 @@ func TestParseCredentials(t *testing.T) {
 					CredType:    "Github App",
 				},
 				AppID:      12345,
-				PrivateKey: "private",
+				PrivateKey: testGithubAppPKCS8Key,
 				Owner:      "owner",
+				APIURI:     "https://github.example.com/api/v3",
 			}},
 			wantErr: false,
 		},
@@ -359,3 +383,32 @@ func TestParseCredentials(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCredentialsRedactsErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		credMap       map[string]interface{}
+		expectedError string
+	}{
+		"Invalid value": {
+			credMap:       map[string]interface{}{"id": "invalid-value", "type": "usernamepassword", "value": "user:registered:password"},
+			expectedError: "the following credentials failed to validate: invalid-value -> Type: Username/Password - <empty>:******** -> 1 error occurred:\n\t* The credentials with ID invalid-value has an invalid username:password value\n\n",
+		},
+		"Undecodable entry": {
+			credMap:       map[string]interface{}{"id": "undecodable", "type": "secret", "secret": []string{"not a string"}, "unknown": "registered-unknown"},
+			expectedError: "entry undecodable: invalid credentials data",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseSingleCredentials(tt.credMap)
+			assert.ErrorContains(t, err, tt.expectedError)
+			assert.NotContains(t, err.Error(), "registered")
+		})
+	}
+
+	// The values are only registered once the credentials are fetched, in the set of the sync
+	assert.Equal(t, "user:registered:password, registered-unknown", logger.Redact("user:registered:password, registered-unknown"))
+}
@@ -36,13 +36,18 @@ func (cred *UsernamePasswordCredentials) ToString(showSensitive bool) string {
 	return fmt.Sprintf("%s - %s:%s", cred.BaseToString(), username, password)
 }
 
+// GetSensitiveValues returns the values that must not be logged: the password
+func (cred *UsernamePasswordCredentials) GetSensitiveValues() []string {
+	return []string{cred.Value, cred.Password}
+}
+
 // Validate verifies that the credentials is valid.
 // A UsernamePasswordCredentials is always considered valid, as empty values are accepted.
 func (cred *UsernamePasswordCredentials) Validate() error {
 	if cred.Username == "" && cred.Password == "" && cred.Value != "" {
 		splitValue := strings.Split(cred.Value, ":")
 		if len(splitValue) != 2 {
-			return fmt.Errorf("The credentials with ID %s has an invalid username:password value: %s", cred.ID, cred.Value)
+			return fmt.Errorf("The credentials with ID %s has an invalid username:password value", cred.ID)
 		}
 		cred.Username = splitValue[0]
 		cred.Password = splitValue[1]
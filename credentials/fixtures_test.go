@@ -1,5 +1,26 @@
 package credentials
 
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+)
+
+// A RSA private key for GitHub App credentials, in the PKCS#1 format given by GitHub and in the PKCS#8 format
+var testGithubAppPKCS1Key, testGithubAppPKCS8Key = func() (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	pkcs8Key, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Key}))
+}()
+
 const (
 	testCredentialsAsMap = `{
 	"test": {
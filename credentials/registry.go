@@ -0,0 +1,106 @@
+package credentials
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMutex sync.RWMutex
+	// Credentials type (as given in the `type` field of the entries) -> Constructor of the credentials
+	credentialsTypes = map[string]func() Credentials{}
+	// Source type (as given in the `sources` section of the configuration) -> Constructor of the source
+	sourceTypes = map[string]func() Source{}
+	// Source types, in the order in which they are registered (and fetched)
+	sourceTypeNames = []string{}
+)
+
+func init() {
+	RegisterType("aws", func() Credentials { return NewAmazonWebServicesCredentials() })
+	RegisterType("usernamepassword", func() Credentials { return NewUsernamePassword() })
+	RegisterType("secret", func() Credentials { return NewSecretText() })
+	RegisterType("ssh", func() Credentials { return NewSSHCredentials() })
+	RegisterType("github_app", func() Credentials { return NewGithubAppCredentials() })
+	RegisterType("secret_file", func() Credentials { return NewSecretFileCredentials() })
+	RegisterType("certificate", func() Credentials { return NewCertificateCredentials() })
+
+	RegisterSource("local", func() Source { return &LocalSource{} })
+	RegisterSource("aws_s3", func() Source { return &AWSS3Source{} })
+	RegisterSource("aws_secretsmanager", func() Source { return &AWSSecretsManagerSource{} })
+	RegisterSource("aws_ssm", func() Source { return &AWSSSMSource{} })
+	RegisterSource("vault", func() Source { return &VaultSource{} })
+	RegisterSource("plugin", func() Source { return &PluginSource{} })
+}
+
+// RegisterType makes a type of credentials available to the sources, under the given `type` name
+// The constructor must return new credentials on each call, to which the entries of the sources are decoded
+// It panics if the name is already registered, so it is meant to be called from the `init` function of a package
+func RegisterType(name string, constructor func() Credentials) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if name == "" || constructor == nil {
+		panic("credentials: RegisterType requires a name and a constructor")
+	}
+	if _, ok := credentialsTypes[name]; ok {
+		panic(fmt.Sprintf("credentials: the credentials type %s is already registered", name))
+	}
+	credentialsTypes[name] = constructor
+}
+
+// RegisteredTypes returns the sorted names of all registered types of credentials
+func RegisteredTypes() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := []string{}
+	for name := range credentialsTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newCredentialsOfType returns new credentials of the given registered type
+func newCredentialsOfType(name string) (Credentials, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	constructor, ok := credentialsTypes[name]
+	if !ok {
+		return nil, false
+	}
+	return constructor(), true
+}
+
+// RegisterSource makes a type of source available in the `sources` section of the configuration, under the given name
+// The constructor must return a new source on each call, to which the configuration of the source is decoded
+// It panics if the name is already registered, so it is meant to be called from the `init` function of a package
+func RegisterSource(name string, constructor func() Source) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if name == "" || constructor == nil {
+		panic("credentials: RegisterSource requires a name and a constructor")
+	}
+	if _, ok := sourceTypes[name]; ok {
+		panic(fmt.Sprintf("credentials: the source type %s is already registered", name))
+	}
+	sourceTypes[name] = constructor
+	sourceTypeNames = append(sourceTypeNames, name)
+}
+
+// RegisteredSources returns the names of all registered types of sources, in the order in which they were registered
+func RegisteredSources() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return append([]string{}, sourceTypeNames...)
+}
+
+// NewSource returns a new source of the given registered type
+func NewSource(name string) (Source, error) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	constructor, ok := sourceTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown source type: %s", name)
+	}
+	return constructor(), nil
+}
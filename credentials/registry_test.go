@@ -0,0 +1,61 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type registryTestCredentials struct {
+	Base  `mapstructure:",squash"`
+	Token string `mapstructure:"token"`
+}
+
+func (cred *registryTestCredentials) ToString(showSensitive bool) string {
+	return cred.BaseToString()
+}
+
+func (cred *registryTestCredentials) GetSensitiveValues() []string {
+	return []string{cred.Token}
+}
+
+func (cred *registryTestCredentials) Validate() error {
+	return nil
+}
+
+func TestRegisterType(t *testing.T) {
+	t.Parallel()
+
+	RegisterType("registry_test", func() Credentials {
+		cred := &registryTestCredentials{}
+		cred.CredType = "Registry test"
+		return cred
+	})
+	assert.Contains(t, RegisteredTypes(), "registry_test")
+	assert.Panics(t, func() { RegisterType("registry_test", func() Credentials { return &registryTestCredentials{} }) })
+	assert.Panics(t, func() { RegisterType("aws", func() Credentials { return &registryTestCredentials{} }) })
+
+	cred, err := ParseSingleCredentials(map[string]interface{}{"id": "test", "type": "registry_test", "token": "a-token"})
+	assert.NoError(t, err)
+	assert.Equal(t, &registryTestCredentials{Base: Base{ID: "test", CredType: "Registry test"}, Token: "a-token"}, cred)
+
+	_, err = ParseSingleCredentials(map[string]interface{}{"id": "test", "type": "unregistered"})
+	assert.EqualError(t, err, "entry test: unknown credentials type: unregistered")
+}
+
+func TestRegisterSource(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"local", "aws_s3", "aws_secretsmanager", "aws_ssm", "vault", "plugin"}, RegisteredSources()[:6])
+	assert.Panics(t, func() { RegisterSource("local", func() Source { return &LocalSource{} }) })
+	assert.Panics(t, func() { RegisterSource("", func() Source { return &LocalSource{} }) })
+
+	source, err := NewSource("local")
+	assert.NoError(t, err)
+	assert.Equal(t, &LocalSource{}, source)
+	otherSource, _ := NewSource("local")
+	assert.NotSame(t, source, otherSource)
+
+	_, err = NewSource("unregistered")
+	assert.EqualError(t, err, "unknown source type: unregistered")
+}
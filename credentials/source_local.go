@@ -1,18 +1,24 @@
 package credentials
 
 import (
+	"context"
 	"fmt"
 	"os"
 )
 
 // LocalSource represents local files containing credentials
 type LocalSource struct {
+	SourceBase `mapstructure:",squash"`
+
 	File string
 }
 
 // Credentials extracts credentials from the source
-func (source *LocalSource) Credentials() ([]Credentials, error) {
-	return getCredentialsFromFile(source.File)
+func (source *LocalSource) Credentials(ctx context.Context) ([]Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return getCredentialsFromFile(ctx, source.File)
 }
 
 // Type returns the type of the source
@@ -28,7 +34,7 @@ func (source *LocalSource) ValidateConfiguration() error {
 	return nil
 }
 
-func getCredentialsFromFile(fileName string) ([]Credentials, error) {
+func getCredentialsFromFile(ctx context.Context, fileName string) ([]Credentials, error) {
 	var (
 		err         error
 		fileContent []byte
@@ -36,5 +42,5 @@ func getCredentialsFromFile(fileName string) ([]Credentials, error) {
 	if fileContent, err = os.ReadFile(fileName); err != nil {
 		return nil, err
 	}
-	return getCredentialsFromBytes(fileContent)
+	return getCredentialsFromBytes(ctx, fileContent)
 }
@@ -1,6 +1,7 @@
 package credentials
 
 import (
+	"context"
 	"os"
 	"path"
 	"testing"
@@ -29,7 +30,7 @@ func TestGetCredentialsFromLocalSource(t *testing.T) {
   password: pass`), 0777)
 
 	assert.Nil(t, localSource.ValidateConfiguration())
-	credentials, err := localSource.Credentials()
+	credentials, err := localSource.Credentials(context.Background())
 	expectedCred := NewUsernamePassword()
 	expectedCred.ID = "test_cred"
 	expectedCred.Description = "a credential"
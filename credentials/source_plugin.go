@@ -0,0 +1,43 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/coveooss/credentials-sync/plugin"
+)
+
+// PluginSource represents an external executable that returns credentials, through the plugin protocol
+// The plugin returns credentials documents, in the same format as the documents of the other sources
+type PluginSource struct {
+	SourceBase           `mapstructure:",squash"`
+	plugin.Configuration `mapstructure:",squash"`
+
+	client *plugin.Client
+}
+
+// Credentials extracts credentials from the source
+func (source *PluginSource) Credentials(ctx context.Context) ([]Credentials, error) {
+	if source.client == nil {
+		source.client = source.NewClient(plugin.KindSource, "")
+	}
+	result := &plugin.CredentialsResult{}
+	if err := source.client.Call(ctx, plugin.MethodCredentials, nil, result); err != nil {
+		return nil, err
+	}
+	return ParseCredentials(result.Credentials)
+}
+
+// Type returns the type of the source
+func (source *PluginSource) Type() string {
+	return fmt.Sprintf("Plugin (%s)", filepath.Base(source.Command))
+}
+
+// ValidateConfiguration verifies that the source's attributes are valid
+func (source *PluginSource) ValidateConfiguration() error {
+	if err := source.Configuration.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration on a plugin source: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package credentials
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coveooss/credentials-sync/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+// The test binary is also used as the reference plugin, when this variable is set
+const testPluginEnv = "CREDENTIALS_SYNC_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) == "reference" {
+		plugin.Main(plugin.ReferenceHandler)
+	}
+	os.Exit(m.Run())
+}
+
+func TestPluginSource(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"test": {"type": "secret", "description": "test-desc", "secret": "my secret"}}`), 0600))
+	source := &PluginSource{Configuration: plugin.Configuration{
+		Command: os.Args[0],
+		Env:     map[string]string{testPluginEnv: "reference"},
+		Options: map[string]interface{}{"path": path},
+	}}
+	assert.NoError(t, source.ValidateConfiguration())
+	assert.Equal(t, "Plugin ("+filepath.Base(os.Args[0])+")", source.Type())
+
+	credentials, err := source.Credentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, testCredentials[0:1], credentials)
+
+	assert.EqualError(t, (&PluginSource{}).ValidateConfiguration(), "invalid configuration on a plugin source: `command` must be defined to run a plugin")
+}
@@ -1,18 +1,23 @@
 package credentials
 
 import (
+	"context"
 	"fmt"
 	"io"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/coveooss/credentials-sync/retry"
 )
 
 // AWSS3Source represents s3 objects containing credentials
 type AWSS3Source struct {
+	SourceBase `mapstructure:",squash"`
+
 	Bucket string
 	Key    string
 
@@ -22,6 +27,7 @@ type AWSS3Source struct {
 func (source *AWSS3Source) getClient() s3iface.S3API {
 	if source.client == nil {
 		sess := session.Must(session.NewSessionWithOptions(session.Options{
+			Config:                  *request.WithRetryer(aws.NewConfig(), &retry.AWSRetryer{Policy: source.Retry, Name: source.Type()}),
 			SharedConfigState:       session.SharedConfigEnable,
 			AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
 		}))
@@ -31,10 +37,10 @@ func (source *AWSS3Source) getClient() s3iface.S3API {
 }
 
 // Credentials extracts credentials from the source
-func (source *AWSS3Source) Credentials() ([]Credentials, error) {
+func (source *AWSS3Source) Credentials(ctx context.Context) ([]Credentials, error) {
 	client := source.getClient()
 
-	response, err := client.GetObject(&s3.GetObjectInput{
+	response, err := client.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(source.Bucket),
 		Key:    aws.String(source.Key),
 	})
@@ -46,7 +52,7 @@ func (source *AWSS3Source) Credentials() ([]Credentials, error) {
 		return nil, err
 	}
 
-	return getCredentialsFromBytes(body)
+	return getCredentialsFromBytes(ctx, body)
 }
 
 // Type returns the type of the source
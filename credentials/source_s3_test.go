@@ -1,11 +1,14 @@
 package credentials
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 
@@ -62,7 +65,7 @@ type mockS3Client struct {
 	t *testing.T
 }
 
-func (m *mockS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+func (m *mockS3Client) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, options ...request.Option) (*s3.GetObjectOutput, error) {
 	assert.Equal(m.t, s3Bucket, *input.Bucket)
 	assert.Equal(m.t, s3Key, *input.Key)
 
@@ -82,7 +85,7 @@ func TestGetCredentialsFromS3Source(t *testing.T) {
 		client: &mockS3Client{t: t},
 	}
 
-	credentials, err := s3Source.Credentials()
+	credentials, err := s3Source.Credentials(context.Background())
 
 	expectedCred := NewUsernamePassword()
 	expectedCred.ID = "test_cred"
@@ -1,18 +1,23 @@
 package credentials
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/coveooss/credentials-sync/retry"
 )
 
 // AWSSecretsManagerSource represents AWS SecretsManager secrets containing credentials
 type AWSSecretsManagerSource struct {
+	SourceBase `mapstructure:",squash"`
+
 	SecretPrefix string `mapstructure:"secret_prefix"`
 	SecretID     string `mapstructure:"secret_id"`
 
@@ -22,6 +27,7 @@ type AWSSecretsManagerSource struct {
 func (source *AWSSecretsManagerSource) getClient() secretsmanageriface.SecretsManagerAPI {
 	if source.client == nil {
 		sess := session.Must(session.NewSessionWithOptions(session.Options{
+			Config:                  *request.WithRetryer(aws.NewConfig(), &retry.AWSRetryer{Policy: source.Retry, Name: source.Type()}),
 			SharedConfigState:       session.SharedConfigEnable,
 			AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
 		}))
@@ -31,13 +37,13 @@ func (source *AWSSecretsManagerSource) getClient() secretsmanageriface.SecretsMa
 }
 
 // Credentials extracts credentials from the source
-func (source *AWSSecretsManagerSource) Credentials() ([]Credentials, error) {
+func (source *AWSSecretsManagerSource) Credentials(ctx context.Context) ([]Credentials, error) {
 	client := source.getClient()
 
 	secretIDs := []string{}
 
 	if source.SecretPrefix != "" {
-		if err := client.ListSecretsPages(&secretsmanager.ListSecretsInput{}, func(output *secretsmanager.ListSecretsOutput, lastPage bool) bool {
+		if err := client.ListSecretsPagesWithContext(ctx, &secretsmanager.ListSecretsInput{}, func(output *secretsmanager.ListSecretsOutput, lastPage bool) bool {
 			for _, secret := range output.SecretList {
 				if strings.HasPrefix(*secret.Name, source.SecretPrefix) {
 					secretIDs = append(secretIDs, *secret.ARN)
@@ -57,13 +63,13 @@ func (source *AWSSecretsManagerSource) Credentials() ([]Credentials, error) {
 
 	credentials := []Credentials{}
 	for _, secretID := range secretIDs {
-		value, err := client.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		value, err := client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
 			SecretId: aws.String(secretID),
 		})
 		if err != nil {
 			return nil, fmt.Errorf("Error while fetching secret %s: %v", secretID, err)
 		}
-		fetchedCredentials, err := getCredentialsFromBytes([]byte(*value.SecretString))
+		fetchedCredentials, err := getCredentialsFromBytes(ctx, []byte(*value.SecretString))
 		if err != nil {
 			return nil, fmt.Errorf("Error while parsing credentials from secret %s: %v", secretID, err)
 		}
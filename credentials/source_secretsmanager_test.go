@@ -1,11 +1,13 @@
 package credentials
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
 
@@ -81,7 +83,7 @@ type mockSecretsManagerClient struct {
 	t *testing.T
 }
 
-func (m *mockSecretsManagerClient) ListSecretsPages(input *secretsmanager.ListSecretsInput, theFunc func(*secretsmanager.ListSecretsOutput, bool) bool) error {
+func (m *mockSecretsManagerClient) ListSecretsPagesWithContext(ctx aws.Context, input *secretsmanager.ListSecretsInput, theFunc func(*secretsmanager.ListSecretsOutput, bool) bool, options ...request.Option) error {
 	theFunc(&secretsmanager.ListSecretsOutput{SecretList: []*secretsmanager.SecretListEntry{
 		{
 			ARN:  aws.String(firstSecretARN),
@@ -99,7 +101,7 @@ func (m *mockSecretsManagerClient) ListSecretsPages(input *secretsmanager.ListSe
 	return nil
 }
 
-func (m *mockSecretsManagerClient) GetSecretValue(input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+func (m *mockSecretsManagerClient) GetSecretValueWithContext(ctx aws.Context, input *secretsmanager.GetSecretValueInput, options ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
 	if *input.SecretId == firstSecretARN {
 		return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(testCredentialsAsMap)}, nil
 	} else if *input.SecretId == secondSecretARN {
@@ -120,7 +122,7 @@ func TestGetCredentialsFromSecretsManagerSourceWithPrefix(t *testing.T) {
 		client:       &mockSecretsManagerClient{t: t},
 	}
 
-	credentials, err := secretsManagerSource.Credentials()
+	credentials, err := secretsManagerSource.Credentials(context.Background())
 	sort.Slice(credentials, func(i, j int) bool { return credentials[i].GetID() < credentials[j].GetID() })
 
 	assert.NoError(t, err)
@@ -135,7 +137,7 @@ func TestGetCredentialsFromSecretsManagerSourceWithID(t *testing.T) {
 		client:   &mockSecretsManagerClient{t: t},
 	}
 
-	credentials, err := secretsManagerSource.Credentials()
+	credentials, err := secretsManagerSource.Credentials(context.Background())
 	sort.Slice(credentials, func(i, j int) bool { return credentials[i].GetID() < credentials[j].GetID() })
 
 	assert.NoError(t, err)
@@ -151,7 +153,7 @@ func TestGetCredentialsFromSecretsManagerSourceWithUnknownPrefix(t *testing.T) {
 		client:       &mockSecretsManagerClient{t: t},
 	}
 
-	credentials, err := secretsManagerSource.Credentials()
+	credentials, err := secretsManagerSource.Credentials(context.Background())
 	assert.EqualError(t, err, "No secrets found with the 'bad' prefix")
 	assert.Nil(t, credentials)
 }
@@ -165,7 +167,7 @@ func TestGetCredentialsFromSecretsManagerSourceWithBadPrefix(t *testing.T) {
 		client:       &mockSecretsManagerClient{t: t},
 	}
 
-	credentials, err := secretsManagerSource.Credentials()
+	credentials, err := secretsManagerSource.Credentials(context.Background())
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Error while fetching secret "+thirdSecretARN)
 	assert.Nil(t, credentials)
@@ -0,0 +1,122 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/coveooss/credentials-sync/retry"
+)
+
+// AWSSSMSource represents AWS SSM Parameter Store parameters containing credentials
+// In raw mode, each parameter is a secret text credentials instead of a credentials document
+type AWSSSMSource struct {
+	SourceBase `mapstructure:",squash"`
+
+	ParameterName string `mapstructure:"parameter_name"`
+	Path          string `mapstructure:"path"`
+	Raw           bool   `mapstructure:"raw"`
+
+	client ssmiface.SSMAPI
+}
+
+func (source *AWSSSMSource) getClient() ssmiface.SSMAPI {
+	if source.client == nil {
+		sess := session.Must(session.NewSessionWithOptions(session.Options{
+			Config:                  *request.WithRetryer(aws.NewConfig(), &retry.AWSRetryer{Policy: source.Retry, Name: source.Type()}),
+			SharedConfigState:       session.SharedConfigEnable,
+			AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
+		}))
+		source.client = ssm.New(sess)
+	}
+	return source.client
+}
+
+// Credentials extracts credentials from the source
+func (source *AWSSSMSource) Credentials(ctx context.Context) ([]Credentials, error) {
+	client := source.getClient()
+
+	parameters := []*ssm.Parameter{}
+	if source.ParameterName != "" {
+		output, err := client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(source.ParameterName),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error while fetching parameter %s: %v", source.ParameterName, err)
+		}
+		parameters = append(parameters, output.Parameter)
+	} else {
+		if err := client.GetParametersByPathPagesWithContext(ctx, &ssm.GetParametersByPathInput{
+			Path:           aws.String(source.Path),
+			Recursive:      aws.Bool(true),
+			WithDecryption: aws.Bool(true),
+		}, func(output *ssm.GetParametersByPathOutput, lastPage bool) bool {
+			parameters = append(parameters, output.Parameters...)
+			return !lastPage
+		}); err != nil {
+			return nil, fmt.Errorf("Error listing parameters: %v", err)
+		}
+
+		if len(parameters) == 0 {
+			return nil, fmt.Errorf("No parameters found under the '%s' path", source.Path)
+		}
+	}
+
+	credentials := []Credentials{}
+	for _, parameter := range parameters {
+		if source.Raw {
+			rawCredentials, err := ParseSingleCredentials(map[string]interface{}{
+				"id":     source.rawCredentialsID(*parameter.Name),
+				"type":   "secret",
+				"secret": *parameter.Value,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Error while creating credentials from parameter %s: %v", *parameter.Name, err)
+			}
+			credentials = append(credentials, rawCredentials)
+			continue
+		}
+		fetchedCredentials, err := getCredentialsFromBytes(ctx, []byte(*parameter.Value))
+		if err != nil {
+			return nil, fmt.Errorf("Error while parsing credentials from parameter %s: %v", *parameter.Name, err)
+		}
+		credentials = append(credentials, fetchedCredentials...)
+	}
+
+	return credentials, nil
+}
+
+// rawCredentialsID derives the ID of raw credentials from the name of their parameter
+// When reading a path, the ID is the name relative to the path, with slashes replaced by underscores
+// Otherwise, it is the last component of the name
+func (source *AWSSSMSource) rawCredentialsID(parameterName string) string {
+	if source.Path == "" {
+		return path.Base(parameterName)
+	}
+	relativeName := strings.TrimPrefix(parameterName, strings.TrimSuffix(source.Path, "/")+"/")
+	return strings.ReplaceAll(relativeName, "/", "_")
+}
+
+// Type returns the type of the source
+func (source *AWSSSMSource) Type() string {
+	return "Amazon SSM Parameter Store"
+}
+
+// ValidateConfiguration verifies that the source's attributes are valid
+func (source *AWSSSMSource) ValidateConfiguration() error {
+	if (source.ParameterName == "" && source.Path == "") || (source.ParameterName != "" && source.Path != "") {
+		return fmt.Errorf("Either `parameter_name` or `path` must be defined on a ssm source")
+	}
+	if source.Path != "" && !strings.HasPrefix(source.Path, "/") {
+		return fmt.Errorf("The `path` of a ssm source must start with a `/`")
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/coveooss/credentials-sync/logger"
+	"github.com/coveooss/credentials-sync/vault"
+)
+
+const (
+	defaultVaultMount     = "secret"
+	defaultVaultKVVersion = 2
+)
+
+// VaultSource represents HashiCorp Vault KV secrets containing credentials
+// A secret's data is either a credentials document or a single credentials, whose type is given by the `type` key
+// or inferred from well-known keys (such as `username` and `password`)
+type VaultSource struct {
+	SourceBase          `mapstructure:",squash"`
+	vault.Configuration `mapstructure:",squash"`
+
+	Mount     string `mapstructure:"mount"`
+	KVVersion int    `mapstructure:"kv_version"`
+	Path      string `mapstructure:"path"`
+	Prefix    string `mapstructure:"prefix"`
+
+	kv *vault.KV
+}
+
+func (source *VaultSource) getKV(ctx context.Context) (*vault.KV, error) {
+	if source.kv == nil {
+		client, err := source.NewClient(ctx, source.Retry, source.Type())
+		if err != nil {
+			return nil, err
+		}
+		source.kv = &vault.KV{Client: client, Mount: source.getMount(), Version: source.getKVVersion()}
+	}
+	return source.kv, nil
+}
+
+func (source *VaultSource) getMount() string {
+	if source.Mount == "" {
+		return defaultVaultMount
+	}
+	return strings.Trim(source.Mount, "/")
+}
+
+func (source *VaultSource) getKVVersion() int {
+	if source.KVVersion == 0 {
+		return defaultVaultKVVersion
+	}
+	return source.KVVersion
+}
+
+// Credentials extracts credentials from the source
+func (source *VaultSource) Credentials(ctx context.Context) ([]Credentials, error) {
+	kv, err := source.getKV(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secretPaths := []string{}
+	if source.Prefix != "" {
+		if secretPaths, err = kv.List(ctx, source.Prefix); err != nil {
+			return nil, fmt.Errorf("Error listing secrets: %v", err)
+		}
+		if len(secretPaths) == 0 {
+			return nil, fmt.Errorf("No secrets found under the '%s' prefix", source.Prefix)
+		}
+	} else {
+		secretPaths = append(secretPaths, source.Path)
+	}
+
+	credentials := []Credentials{}
+	for _, secretPath := range secretPaths {
+		data, err := kv.Read(ctx, secretPath)
+		if err != nil {
+			return nil, fmt.Errorf("Error while fetching secret %s: %v", secretPath, err)
+		}
+		if data == nil && source.Prefix != "" {
+			// Secrets deleted from a KV v2 engine are still listed, but they have no data
+			logger.FromContext(ctx).Debugf("Skipping the deleted secret %s", secretPath)
+			continue
+		} else if data == nil {
+			return nil, fmt.Errorf("Secret %s does not exist", secretPath)
+		}
+		fetchedCredentials, err := source.credentialsFromData(ctx, secretPath, data)
+		if err != nil {
+			return nil, fmt.Errorf("Error while parsing credentials from secret %s: %v", secretPath, err)
+		}
+		credentials = append(credentials, fetchedCredentials...)
+	}
+
+	return credentials, nil
+}
+
+func (source *VaultSource) credentialsFromData(ctx context.Context, secretPath string, data map[string]interface{}) ([]Credentials, error) {
+	credentialsType, ok := data["type"].(string)
+	if !ok {
+		credentialsType = inferCredentialsType(data)
+	}
+	if credentialsType == "" {
+		// Not a single credentials, so this must be a credentials document
+		document, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		return getCredentialsFromBytes(ctx, document)
+	}
+
+	credentialsMap := map[string]interface{}{"id": source.credentialsID(secretPath)}
+	for key, value := range data {
+		credentialsMap[key] = value
+	}
+	credentialsMap["type"] = credentialsType
+	credentials, err := ParseSingleCredentials(credentialsMap)
+	if err != nil {
+		return nil, err
+	}
+	return []Credentials{credentials}, nil
+}
+
+// credentialsID derives the ID of single credentials from the path of their secret, unless it is given by the `id` key
+// When reading a prefix, the ID is the path relative to the prefix, with slashes replaced by underscores
+// Otherwise, it is the last component of the path
+func (source *VaultSource) credentialsID(secretPath string) string {
+	if source.Prefix == "" {
+		return path.Base(secretPath)
+	}
+	relativePath := strings.TrimPrefix(secretPath, strings.Trim(source.Prefix, "/")+"/")
+	return strings.ReplaceAll(relativePath, "/", "_")
+}
+
+// inferCredentialsType returns the type of credentials matching the well-known keys of a secret's data
+// An empty string is returned if the data does not match any type
+func inferCredentialsType(data map[string]interface{}) string {
+	has := func(keys ...string) bool {
+		for _, key := range keys {
+			if _, ok := data[key].(string); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case has("access_key", "secret_key"):
+		return "aws"
+	case has("private_key") && data["app_id"] != nil:
+		return "github_app"
+	case has("private_key"):
+		return "ssh"
+	case has("username", "password"):
+		return "usernamepassword"
+	case has("secret"):
+		return "secret"
+	}
+	return ""
+}
+
+// Type returns the type of the source
+func (source *VaultSource) Type() string {
+	return "HashiCorp Vault"
+}
+
+// ValidateConfiguration verifies that the source's attributes are valid
+func (source *VaultSource) ValidateConfiguration() error {
+	if (source.Path == "" && source.Prefix == "") || (source.Path != "" && source.Prefix != "") {
+		return fmt.Errorf("Either `path` or `prefix` must be defined on a vault source")
+	}
+	if kvVersion := source.getKVVersion(); kvVersion != 1 && kvVersion != 2 {
+		return fmt.Errorf("The `kv_version` of a vault source must be 1 or 2")
+	}
+	return source.Configuration.Validate()
+}
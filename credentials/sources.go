@@ -1,56 +1,87 @@
 package credentials
 
 import (
+	"context"
 	"fmt"
+	"regexp"
 	"sort"
+	"time"
 
 	"github.com/coveooss/credentials-sync/logger"
+	"github.com/coveooss/credentials-sync/retry"
 	"github.com/hashicorp/go-multierror"
 	"gopkg.in/yaml.v3"
 )
 
 // Source represents a location to fetch credentials
 type Source interface {
-	Credentials() ([]Credentials, error)
+	Credentials(ctx context.Context) ([]Credentials, error)
+	GetRetryPolicy() retry.Policy
+	GetTimeout() time.Duration
+	SetDefaultRetryPolicy(defaults retry.Policy)
 	Type() string
 	ValidateConfiguration() error
 }
 
+// SourceBase contains attributes which are common to all sources
+type SourceBase struct {
+	Retry   retry.Policy  `mapstructure:"retry"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// GetRetryPolicy returns the policy used to retry the requests that fail with a transient error
+func (sourceBase *SourceBase) GetRetryPolicy() retry.Policy {
+	return sourceBase.Retry
+}
+
+// SetDefaultRetryPolicy sets the attributes of the retry policy that are not configured on the source
+func (sourceBase *SourceBase) SetDefaultRetryPolicy(defaults retry.Policy) {
+	sourceBase.Retry = sourceBase.Retry.WithDefaults(defaults)
+}
+
+// GetTimeout returns the maximum duration of the fetching of credentials from the source. Zero means no timeout
+func (sourceBase *SourceBase) GetTimeout() time.Duration {
+	return sourceBase.Timeout
+}
+
 // SourcesConfiguration contains all configured sources
 type SourcesConfiguration struct {
-	AWSS3Sources            []*AWSS3Source             `mapstructure:"aws_s3"`
-	AWSSecretsManagerSource []*AWSSecretsManagerSource `mapstructure:"aws_secretsmanager"`
-	LocalSources            []*LocalSource             `mapstructure:"local"`
+	sources []Source
+}
 
-	credentialsList []Credentials
+// NewSourcesConfiguration creates a configuration with the given sources
+func NewSourcesConfiguration(sources ...Source) *SourcesConfiguration {
+	return &SourcesConfiguration{sources: sources}
+}
+
+// AddSources adds sources to the configuration. Credentials are fetched from the sources in the order in which they are added
+func (sc *SourcesConfiguration) AddSources(sources ...Source) {
+	sc.sources = append(sc.sources, sources...)
 }
 
 // SourceCollection represents a collection of sources from which credentials can be fetched
 type SourceCollection interface {
 	AllSources() []Source
-	Credentials() ([]Credentials, error)
+	Credentials(ctx context.Context) ([]Credentials, error)
+	SetDefaultRetryPolicy(defaults retry.Policy)
 	ValidateConfiguration() error
 }
 
 // AllSources returns all configured sources in a single list
 func (sc *SourcesConfiguration) AllSources() []Source {
-	sources := []Source{}
-	for _, source := range sc.LocalSources {
-		sources = append(sources, source)
-	}
-	for _, source := range sc.AWSS3Sources {
-		sources = append(sources, source)
-	}
-	for _, source := range sc.AWSSecretsManagerSource {
-		sources = append(sources, source)
-	}
-	return sources
+	return sc.sources
 }
 
 // ValidateConfiguration verifies that all configured sources are correctly configured
 func (sc *SourcesConfiguration) ValidateConfiguration() error {
 	var validationErrors error
 	for _, source := range sc.AllSources() {
+		if source.GetTimeout() < 0 {
+			validationErrors = multierror.Append(validationErrors, fmt.Errorf("The `timeout` of %s sources cannot be negative", source.Type()))
+		}
+		if err := source.GetRetryPolicy().Validate(); err != nil {
+			validationErrors = multierror.Append(validationErrors, fmt.Errorf("Invalid retry policy on %s source: %v", source.Type(), err))
+		}
 		if err := source.ValidateConfiguration(); err != nil {
 			validationErrors = multierror.Append(validationErrors, err)
 		}
@@ -58,41 +89,70 @@ func (sc *SourcesConfiguration) ValidateConfiguration() error {
 	return validationErrors
 }
 
-// Credentials extracts credentials from all configured sources
-func (sc *SourcesConfiguration) Credentials() ([]Credentials, error) {
-	if sc.credentialsList != nil {
-		return sc.credentialsList, nil
+// SetDefaultRetryPolicy sets the attributes of the retry policy that are not configured on each source
+func (sc *SourcesConfiguration) SetDefaultRetryPolicy(defaults retry.Policy) {
+	for _, source := range sc.AllSources() {
+		source.SetDefaultRetryPolicy(defaults)
 	}
+}
 
-	sc.credentialsList = []Credentials{}
-
+// Credentials extracts credentials from all configured sources
+// Each source is given its own timeout, on top of the deadline of the given context
+// The credentials are not cached, so that each sync fetches the current credentials
+// Their sensitive values are registered in the set of the given context (see logger.WithSensitiveValues)
+func (sc *SourcesConfiguration) Credentials(ctx context.Context) ([]Credentials, error) {
 	// Fetch all credentials
+	credentialsList := []Credentials{}
 	for _, source := range sc.AllSources() {
-		newCredentials, err := source.Credentials()
+		newCredentials, err := fetchCredentials(ctx, source)
 		if err != nil {
 			return nil, err
 		}
-		sc.credentialsList = append(sc.credentialsList, newCredentials...)
+		credentialsList = append(credentialsList, newCredentials...)
 	}
 
 	// Sort credentials by ID
-	sort.Slice(sc.credentialsList[:], func(i, j int) bool {
-		return sc.credentialsList[i].GetID() < sc.credentialsList[j].GetID()
+	sort.Slice(credentialsList, func(i, j int) bool {
+		return credentialsList[i].GetID() < credentialsList[j].GetID()
 	})
 
 	// Throw an error if IDs are not unique
 	credentialIds := map[string]bool{}
-	for _, cred := range sc.credentialsList {
+	for _, cred := range credentialsList {
 		if _, ok := credentialIds[cred.GetID()]; ok {
 			return nil, fmt.Errorf("There more than one credentials with this ID: %s", cred.GetID())
 		}
 		credentialIds[cred.GetID()] = true
 	}
 
-	return sc.credentialsList, nil
+	return credentialsList, nil
+}
+
+func fetchCredentials(ctx context.Context, source Source) ([]Credentials, error) {
+	if timeout := source.GetTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	newCredentials, err := source.Credentials(ctx)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("Timed out while fetching credentials from %s source: %v", source.Type(), err)
+	}
+	for _, cred := range newCredentials {
+		logger.SensitiveValuesFromContext(ctx).Add(cred.GetSensitiveValues()...)
+		if sourced, ok := cred.(interface{ SetSource(string) }); ok {
+			sourced.SetSource(source.Type())
+		}
+		if tagged, ok := cred.(interface{ invalidTargetTags() []string }); ok {
+			for _, key := range tagged.invalidTargetTags() {
+				logger.FromContext(ctx).Warningf("The %s target tag of the credentials with ID %s is ignored. Its value should either be a string or a list of string", key, cred.GetID())
+			}
+		}
+	}
+	return newCredentials, err
 }
 
-func getCredentialsFromBytes(byteArray []byte) ([]Credentials, error) {
+func getCredentialsFromBytes(ctx context.Context, byteArray []byte) ([]Credentials, error) {
 	var (
 		err             error
 		credentialsList []map[string]interface{}
@@ -110,15 +170,25 @@ func getCredentialsFromBytes(byteArray []byte) ([]Credentials, error) {
 	}
 
 	if !success {
-		logger.Log.Warning("Failed to get credential from data using all known formats (details below)")
+		log := logger.FromContext(ctx)
+		log.Warningf("Failed to get credential from data using all known formats (details below)")
 		for _, err := range errors {
 			if err != nil {
-				logger.Log.Warning(err)
+				log.Warningf("%s", redactParseError(ctx, err))
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("Failed to parse %v. See debug for more info", string(byteArray))
+	// The data is not included in the error, since it is likely to contain secrets
+	return nil, fmt.Errorf("Failed to parse the credentials data (%d bytes). See the logs for more info", len(byteArray))
+}
+
+// The YAML errors quote the beginning of the values that cannot be decoded
+var parseErrorQuotedValue = regexp.MustCompile("`[^`]*`")
+
+// redactParseError removes the values quoted in the given parsing error, since they may be secrets
+func redactParseError(ctx context.Context, err error) string {
+	return parseErrorQuotedValue.ReplaceAllString(logger.SensitiveValuesFromContext(ctx).Redact(err.Error()), "`"+logger.RedactedValue+"`")
 }
 
 // Accept list of credentials
@@ -155,13 +225,14 @@ func tryReadingSingleCredential(bytes []byte) ([]map[string]interface{}, error)
 		return nil, fmt.Errorf("Error reading as a map: %v", err)
 	}
 
+	// The parsed credentials are not included in the errors, since they contain secrets
 	id, gotID := singleCredentials["id"]
 	if !gotID {
-		return nil, fmt.Errorf("The parsed credentials doesn't have an ID: %v", singleCredentials)
+		return nil, fmt.Errorf("The parsed credentials doesn't have an ID")
 	}
 
 	if _, idIsString := id.(string); !idIsString {
-		return nil, fmt.Errorf("The given credentials' ID is not a string: %v", singleCredentials)
+		return nil, fmt.Errorf("The given credentials' ID is a %T, not a string", id)
 	}
 
 	return []map[string]interface{}{singleCredentials}, nil
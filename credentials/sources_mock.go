@@ -5,8 +5,11 @@
 package credentials
 
 import (
+	context "context"
+	retry "github.com/coveooss/credentials-sync/retry"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	time "time"
 )
 
 // MockSource is a mock of Source interface
@@ -33,18 +36,46 @@ func (m *MockSource) EXPECT() *MockSourceMockRecorder {
 }
 
 // Credentials mocks base method
-func (m *MockSource) Credentials() ([]Credentials, error) {
+func (m *MockSource) Credentials(ctx context.Context) ([]Credentials, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Credentials")
+	ret := m.ctrl.Call(m, "Credentials", ctx)
 	ret0, _ := ret[0].([]Credentials)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Credentials indicates an expected call of Credentials
-func (mr *MockSourceMockRecorder) Credentials() *gomock.Call {
+func (mr *MockSourceMockRecorder) Credentials(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Credentials", reflect.TypeOf((*MockSource)(nil).Credentials))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Credentials", reflect.TypeOf((*MockSource)(nil).Credentials), ctx)
+}
+
+// GetRetryPolicy mocks base method
+func (m *MockSource) GetRetryPolicy() retry.Policy {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRetryPolicy")
+	ret0, _ := ret[0].(retry.Policy)
+	return ret0
+}
+
+// GetRetryPolicy indicates an expected call of GetRetryPolicy
+func (mr *MockSourceMockRecorder) GetRetryPolicy() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRetryPolicy", reflect.TypeOf((*MockSource)(nil).GetRetryPolicy))
+}
+
+// GetTimeout mocks base method
+func (m *MockSource) GetTimeout() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTimeout")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// GetTimeout indicates an expected call of GetTimeout
+func (mr *MockSourceMockRecorder) GetTimeout() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTimeout", reflect.TypeOf((*MockSource)(nil).GetTimeout))
 }
 
 // Type mocks base method
@@ -61,6 +92,18 @@ func (mr *MockSourceMockRecorder) Type() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Type", reflect.TypeOf((*MockSource)(nil).Type))
 }
 
+// SetDefaultRetryPolicy mocks base method
+func (m *MockSource) SetDefaultRetryPolicy(defaults retry.Policy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetDefaultRetryPolicy", defaults)
+}
+
+// SetDefaultRetryPolicy indicates an expected call of SetDefaultRetryPolicy
+func (mr *MockSourceMockRecorder) SetDefaultRetryPolicy(defaults interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDefaultRetryPolicy", reflect.TypeOf((*MockSource)(nil).SetDefaultRetryPolicy), defaults)
+}
+
 // ValidateConfiguration mocks base method
 func (m *MockSource) ValidateConfiguration() error {
 	m.ctrl.T.Helper()
@@ -113,18 +156,30 @@ func (mr *MockSourceCollectionMockRecorder) AllSources() *gomock.Call {
 }
 
 // Credentials mocks base method
-func (m *MockSourceCollection) Credentials() ([]Credentials, error) {
+func (m *MockSourceCollection) Credentials(ctx context.Context) ([]Credentials, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Credentials")
+	ret := m.ctrl.Call(m, "Credentials", ctx)
 	ret0, _ := ret[0].([]Credentials)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Credentials indicates an expected call of Credentials
-func (mr *MockSourceCollectionMockRecorder) Credentials() *gomock.Call {
+func (mr *MockSourceCollectionMockRecorder) Credentials(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Credentials", reflect.TypeOf((*MockSourceCollection)(nil).Credentials), ctx)
+}
+
+// SetDefaultRetryPolicy mocks base method
+func (m *MockSourceCollection) SetDefaultRetryPolicy(defaults retry.Policy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetDefaultRetryPolicy", defaults)
+}
+
+// SetDefaultRetryPolicy indicates an expected call of SetDefaultRetryPolicy
+func (mr *MockSourceCollectionMockRecorder) SetDefaultRetryPolicy(defaults interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Credentials", reflect.TypeOf((*MockSourceCollection)(nil).Credentials))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDefaultRetryPolicy", reflect.TypeOf((*MockSourceCollection)(nil).SetDefaultRetryPolicy), defaults)
 }
 
 // ValidateConfiguration mocks base method
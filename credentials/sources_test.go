@@ -1,12 +1,20 @@
 package credentials
 
 import (
+	"bytes"
+	"context"
 	"os"
 	"path"
 	"sort"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/coveooss/credentials-sync/logger"
+	"github.com/coveooss/credentials-sync/retry"
+	"github.com/golang/mock/gomock"
 	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -23,11 +31,19 @@ func TestSourcesConfigWithLocalSource(t *testing.T) {
 		File: filePath,
 	}
 
-	sourcesConfig := SourcesConfiguration{LocalSources: []*LocalSource{localSource}}
+	sourcesConfig := NewSourcesConfiguration(localSource)
 
-	credentials, err := sourcesConfig.Credentials()
+	credentials, err := sourcesConfig.Credentials(context.Background())
 	assert.NoError(t, err)
-	assert.Equal(t, []Credentials{testCredentials[0]}, credentials)
+	expected := *testCredentials[0].(*SecretTextCredentials)
+	expected.SetSource("Local file")
+	assert.Equal(t, []Credentials{&expected}, credentials)
+
+	// The credentials are fetched again on each call
+	os.WriteFile(filePath, []byte(`[{"id": "test", "type": "secret", "description": "test-desc", "secret": "my new secret"}]`), 0777)
+	credentials, err = sourcesConfig.Credentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "my new secret", credentials[0].(*SecretTextCredentials).Secret)
 }
 
 func TestGetCredentialsFromBytes(t *testing.T) {
@@ -106,7 +122,7 @@ func TestGetCredentialsFromBytes(t *testing.T) {
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := getCredentialsFromBytes(tt.bytes)
+			result, err := getCredentialsFromBytes(context.Background(), tt.bytes)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -123,3 +139,99 @@ func TestGetCredentialsFromBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCredentialsFromBytesRedactsData(t *testing.T) {
+	t.Parallel()
+
+	_, err := getCredentialsFromBytes(context.Background(), []byte("not-a-document: [secret"))
+	assert.EqualError(t, err, "Failed to parse the credentials data (23 bytes). See the logs for more info")
+
+	_, err = tryReadingList([]byte("my-password"))
+	assert.Equal(t, "Error reading as credentials list: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `********` into []map[string]interface {}", redactParseError(context.Background(), err))
+}
+
+func TestGetCredentialsFromBytesDoesNotLogSecrets(t *testing.T) {
+	t.Parallel()
+
+	var output bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&output)
+	ctx := logger.WithLogger(context.Background(), log)
+
+	_, err := getCredentialsFromBytes(ctx, []byte("{type: secret, secret: hunter2}"))
+	assert.Error(t, err)
+	assert.Contains(t, output.String(), "The parsed credentials doesn't have an ID")
+	assert.NotContains(t, output.String(), "hunter2")
+
+	output.Reset()
+	_, err = getCredentialsFromBytes(ctx, []byte("{id: 42, type: secret, secret: hunter2}"))
+	assert.Error(t, err)
+	assert.Contains(t, output.String(), "The given credentials' ID is a int, not a string")
+	assert.NotContains(t, output.String(), "hunter2")
+}
+
+func TestSourcesConfigRegistersSensitiveValues(t *testing.T) {
+	t.Parallel()
+
+	filePath := path.Join(t.TempDir(), "local_file.yaml")
+	os.WriteFile(filePath, []byte(`[{id: test, type: secret, secret: sources-registered-secret}]`), 0600)
+
+	// The values are registered in the set of the context, not in the default set
+	sensitiveValues := logger.NewSensitiveValues()
+	ctx := logger.WithSensitiveValues(context.Background(), sensitiveValues)
+	_, err := NewSourcesConfiguration(&LocalSource{File: filePath}).Credentials(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret: ********", sensitiveValues.Redact("secret: sources-registered-secret"))
+	assert.Equal(t, "secret: sources-registered-secret", logger.Redact("secret: sources-registered-secret"))
+}
+
+func TestSourcesConfigWarnsAboutInvalidTargetTags(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "local_file.yaml")
+	os.WriteFile(filePath, []byte(`[{id: test, type: secret, secret: my secret, target_tags: {do_match: {team: 42, env: prod}}}]`), 0600)
+
+	var output bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&output)
+	ctx := logger.WithLogger(context.Background(), log)
+
+	_, err := NewSourcesConfiguration(&LocalSource{File: filePath}).Credentials(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, output.String(), "The team target tag of the credentials with ID test is ignored")
+	assert.NotContains(t, output.String(), "The env target tag")
+}
+
+func TestSourcesConfigTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The source hangs until its own timeout is reached
+	source := NewMockSource(ctrl)
+	source.EXPECT().GetTimeout().Return(50 * time.Millisecond)
+	source.EXPECT().Type().Return("Mock").AnyTimes()
+	source.EXPECT().Credentials(gomock.Any()).DoAndReturn(func(ctx context.Context) ([]Credentials, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	credentials, err := fetchCredentials(context.Background(), source)
+	assert.EqualError(t, err, "Timed out while fetching credentials from Mock source: context deadline exceeded")
+	assert.Nil(t, credentials)
+}
+
+func TestSourcesConfigRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	s3Source := &AWSS3Source{Bucket: "bucket", Key: "key", SourceBase: SourceBase{Retry: retry.Policy{MaxAttempts: aws.Int(5)}}}
+	secretsManagerSource := &AWSSecretsManagerSource{SecretID: "id"}
+	sourcesConfig := NewSourcesConfiguration(s3Source, secretsManagerSource)
+
+	// The source's own attributes take precedence over the defaults
+	sourcesConfig.SetDefaultRetryPolicy(retry.Policy{MaxAttempts: aws.Int(2), Jitter: aws.Float64(0)})
+	assert.Equal(t, 5, s3Source.GetRetryPolicy().GetMaxAttempts())
+	assert.Equal(t, 0.0, *s3Source.GetRetryPolicy().Jitter)
+	assert.Equal(t, 2, secretsManagerSource.GetRetryPolicy().GetMaxAttempts())
+	assert.NoError(t, sourcesConfig.ValidateConfiguration())
+
+	secretsManagerSource.Retry.MaxAttempts = aws.Int(0)
+	assert.EqualError(t, sourcesConfig.ValidateConfiguration(), "1 error occurred:\n\t* Invalid retry policy on Amazon SecretsManager source: `max_attempts` must be at least 1\n\n")
+}
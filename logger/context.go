@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the interface of the loggers that can be given to the sync. It is implemented by *logrus.Logger and *logrus.Entry
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Discard is a logger that drops all entries
+var Discard Logger = newDiscardLogger()
+
+func newDiscardLogger() *logrus.Logger {
+	discardLogger := logrus.New()
+	discardLogger.SetOutput(io.Discard)
+	discardLogger.SetLevel(logrus.PanicLevel)
+	return discardLogger
+}
+
+// redactingLogger redacts the sensitive values of the messages before forwarding them to another logger
+type redactingLogger struct {
+	logger Logger
+	values *SensitiveValues
+}
+
+// NewRedactingLogger returns a logger that redacts the given sensitive values from the messages before forwarding
+// them to the given logger, which may not have the redaction hook of Log. The default set is used if values is nil
+func NewRedactingLogger(logger Logger, values *SensitiveValues) Logger {
+	if values == nil {
+		values = defaultSensitiveValues
+	}
+	if redacting, ok := logger.(*redactingLogger); ok && redacting.values == values {
+		return logger
+	}
+	return &redactingLogger{logger: logger, values: values}
+}
+
+func (redacting *redactingLogger) Debugf(format string, args ...interface{}) {
+	redacting.logger.Debugf("%s", redacting.values.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (redacting *redactingLogger) Infof(format string, args ...interface{}) {
+	redacting.logger.Infof("%s", redacting.values.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (redacting *redactingLogger) Warningf(format string, args ...interface{}) {
+	redacting.logger.Warningf("%s", redacting.values.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (redacting *redactingLogger) Errorf(format string, args ...interface{}) {
+	redacting.logger.Errorf("%s", redacting.values.Redact(fmt.Sprintf(format, args...)))
+}
+
+type contextKey struct{}
+
+type sensitiveValuesContextKey struct{}
+
+// WithLogger returns a copy of the given context that carries the given logger, wrapped so that it only receives
+// messages redacted with the sensitive values of the context. It is used by the operations that have no other way to
+// receive a logger, such as the retries of the API clients
+func WithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, NewRedactingLogger(logger, SensitiveValuesFromContext(ctx)))
+}
+
+// WithSensitiveValues returns a copy of the given context that carries the given set of sensitive values
+// The credentials fetched with this context register their sensitive values in this set. The default set is used if
+// values is nil
+func WithSensitiveValues(ctx context.Context, values *SensitiveValues) context.Context {
+	return context.WithValue(ctx, sensitiveValuesContextKey{}, values)
+}
+
+// SensitiveValuesFromContext returns the set of sensitive values carried by the given context, or the default set
+// (used by Log) if it does not carry one
+func SensitiveValuesFromContext(ctx context.Context) *SensitiveValues {
+	if ctx != nil {
+		if values, ok := ctx.Value(sensitiveValuesContextKey{}).(*SensitiveValues); ok && values != nil {
+			return values
+		}
+	}
+	return defaultSensitiveValues
+}
+
+// FromContext returns the logger carried by the given context, or Log if it does not carry one
+func FromContext(ctx context.Context) Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(Logger); ok {
+			return logger
+		}
+	}
+	return Log
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, Logger(Log), FromContext(context.Background()))
+	assert.Equal(t, NewRedactingLogger(Discard, nil), FromContext(WithLogger(context.Background(), Discard)))
+}
+
+func TestRedactingLogger(t *testing.T) {
+	AddSensitiveValues("redacting-logger-secret")
+
+	// The injected logger does not have the redaction hook of Log
+	var output bytes.Buffer
+	injected := logrus.New()
+	injected.SetOutput(&output)
+	log := FromContext(WithLogger(context.Background(), injected))
+	assert.Equal(t, log, NewRedactingLogger(log, nil))
+
+	log.Infof("Logging in with %s", "redacting-logger-secret")
+	log.Errorf("Failed to log in with redacting-logger-secret: %d%%", 403)
+	assert.Contains(t, output.String(), "Logging in with ********")
+	assert.Contains(t, output.String(), "Failed to log in with ********: 403%")
+	assert.NotContains(t, output.String(), "redacting-logger-secret")
+}
+
+func TestRedactingLoggerWithSensitiveValues(t *testing.T) {
+	t.Parallel()
+
+	sensitiveValues := NewSensitiveValues()
+	sensitiveValues.Add("scoped-logger-secret")
+
+	var output bytes.Buffer
+	injected := logrus.New()
+	injected.SetOutput(&output)
+	ctx := WithSensitiveValues(context.Background(), sensitiveValues)
+	assert.Equal(t, sensitiveValues, SensitiveValuesFromContext(ctx))
+	log := FromContext(WithLogger(ctx, injected))
+	assert.Equal(t, log, NewRedactingLogger(log, sensitiveValues))
+
+	log.Warningf("Retrying with scoped-logger-secret")
+	assert.Contains(t, output.String(), "Retrying with ********")
+	assert.Equal(t, "scoped-logger-secret", Redact("scoped-logger-secret"))
+}
@@ -9,23 +9,34 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func initSentry() *logrus.Logger {
+// Log is the logger of the application. Its entries are only sent to Sentry once InitSentry is called
+var Log *logrus.Logger = newLogger()
+
+func newLogger() *logrus.Logger {
 	newLogger := logrus.New()
+	// The redaction hook must be the first one, so that the other hooks only see redacted entries
+	newLogger.AddHook(&redactionHook{})
+	return newLogger
+}
 
+// InitSentry sends the errors logged with Log to Sentry, if the SENTRY_DSN, SENTRY_ENVIRONMENT and SENTRY_RELEASE
+// env variables are set
+func InitSentry() {
 	sentryDsn, ok := os.LookupEnv("SENTRY_DSN")
 	if !ok {
-		newLogger.Info("Not using sentry, SENTRY_DSN not set")
-		return newLogger
+		Log.Info("Not using sentry, SENTRY_DSN not set")
+		return
 	}
 	for _, sentryVariable := range []string{"SENTRY_ENVIRONMENT", "SENTRY_RELEASE"} {
 		if sentryVariableValue := os.Getenv(sentryVariable); sentryVariableValue == "" {
-			newLogger.Infof("Not using sentry, %s not set", sentryVariable)
-			return newLogger
+			Log.Infof("Not using sentry, %s not set", sentryVariable)
+			return
 		}
 	}
 
 	err := sentry.Init(sentry.ClientOptions{
-		Debug: false,
+		Debug:      false,
+		BeforeSend: redactSentryEvent,
 	})
 	if err != nil {
 		logrus.Fatalf("sentry.Init: %s", err)
@@ -41,11 +52,8 @@ func initSentry() *logrus.Logger {
 
 	if err == nil {
 		hook.Timeout = 2 * time.Second
-		newLogger.AddHook(hook)
+		Log.AddHook(hook)
 	}
 
-	newLogger.Info("Sentry initialized")
-	return newLogger
+	Log.Info("Sentry initialized")
 }
-
-var Log *logrus.Logger = initSentry()
\ No newline at end of file
@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/sirupsen/logrus"
+)
+
+// RedactedValue replaces the sensitive values in the redacted texts
+const RedactedValue = "********"
+
+// Values shorter than this are not redacted, since they would also match unrelated parts of the logs
+const minimumSensitiveValueLength = 4
+
+// SensitiveValues is a set of values that must never appear in the logs, in errors or in Sentry events
+// A set can be scoped to a sync with WithSensitiveValues, so that its values are not kept once the sync is done
+type SensitiveValues struct {
+	mutex    sync.RWMutex
+	values   map[string]bool
+	redactor *strings.Replacer
+}
+
+// NewSensitiveValues returns an empty set of sensitive values
+func NewSensitiveValues() *SensitiveValues {
+	return &SensitiveValues{values: map[string]bool{}, redactor: strings.NewReplacer()}
+}
+
+// defaultSensitiveValues is the set used by Log, and by the operations whose context does not carry a set
+var defaultSensitiveValues = NewSensitiveValues()
+
+// AddSensitiveValues registers values that must never appear in the logs, in errors or in Sentry events
+// The values are added to the default set, which is used by Log
+func AddSensitiveValues(values ...string) {
+	defaultSensitiveValues.Add(values...)
+}
+
+// ClearSensitiveValues removes all values from the default set
+func ClearSensitiveValues() {
+	defaultSensitiveValues.Clear()
+}
+
+// Redact replaces all sensitive values of the default set in the given text
+func Redact(text string) string {
+	return defaultSensitiveValues.Redact(text)
+}
+
+// RedactError returns an error whose message has all sensitive values of the default set redacted (see
+// SensitiveValues.RedactError)
+func RedactError(err error) error {
+	return defaultSensitiveValues.RedactError(err)
+}
+
+// Add registers values in the set
+// Multi-line values are also registered line by line, and in their escaped form, since they are often quoted when logged
+func (sensitive *SensitiveValues) Add(values ...string) {
+	sensitive.mutex.Lock()
+	defer sensitive.mutex.Unlock()
+
+	added := false
+	for _, value := range values {
+		variants := []string{value, strings.Trim(strconv.Quote(value), `"`)}
+		if strings.Contains(value, "\n") {
+			variants = append(variants, strings.Split(value, "\n")...)
+		}
+		for _, variant := range variants {
+			variant = strings.TrimSpace(variant)
+			if len(variant) < minimumSensitiveValueLength || sensitive.values[variant] {
+				continue
+			}
+			sensitive.values[variant] = true
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+
+	// The longest values are replaced first, so that a value that contains another one is entirely redacted
+	sortedValues := []string{}
+	for value := range sensitive.values {
+		sortedValues = append(sortedValues, value)
+	}
+	sort.Slice(sortedValues, func(i, j int) bool {
+		if len(sortedValues[i]) != len(sortedValues[j]) {
+			return len(sortedValues[i]) > len(sortedValues[j])
+		}
+		return sortedValues[i] < sortedValues[j]
+	})
+	replacements := []string{}
+	for _, value := range sortedValues {
+		replacements = append(replacements, value, RedactedValue)
+	}
+	sensitive.redactor = strings.NewReplacer(replacements...)
+}
+
+// Clear removes all values from the set
+func (sensitive *SensitiveValues) Clear() {
+	sensitive.mutex.Lock()
+	defer sensitive.mutex.Unlock()
+	sensitive.values = map[string]bool{}
+	sensitive.redactor = strings.NewReplacer()
+}
+
+// Redact replaces all values of the set in the given text
+func (sensitive *SensitiveValues) Redact(text string) string {
+	sensitive.mutex.RLock()
+	defer sensitive.mutex.RUnlock()
+	return sensitive.redactor.Replace(text)
+}
+
+// RedactError returns an error whose message has all values of the set redacted, including the values added after
+// the call. The original error can still be retrieved with errors.Unwrap
+func (sensitive *SensitiveValues) RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if redacted, ok := err.(*redactedError); ok && redacted.values == sensitive {
+		return err
+	}
+	return &redactedError{err: err, values: sensitive}
+}
+
+type redactedError struct {
+	err    error
+	values *SensitiveValues
+}
+
+func (redacted *redactedError) Error() string {
+	return redacted.values.Redact(redacted.err.Error())
+}
+
+func (redacted *redactedError) Unwrap() error {
+	return redacted.err
+}
+
+// redactionHook redacts the message and the fields of all log entries, before they are written or sent to Sentry
+type redactionHook struct{}
+
+func (hook *redactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (hook *redactionHook) Fire(entry *logrus.Entry) error {
+	entry.Message = Redact(entry.Message)
+	for key, value := range entry.Data {
+		switch typedValue := value.(type) {
+		case string:
+			entry.Data[key] = Redact(typedValue)
+		case error:
+			entry.Data[key] = RedactError(typedValue)
+		case fmt.Stringer:
+			entry.Data[key] = Redact(typedValue.String())
+		}
+	}
+	return nil
+}
+
+// redactSentryEvent redacts the events that are sent to Sentry without going through the logger
+func redactSentryEvent(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+	event.Message = Redact(event.Message)
+	for i := range event.Exception {
+		event.Exception[i].Value = Redact(event.Exception[i].Value)
+	}
+	for _, breadcrumb := range event.Breadcrumbs {
+		breadcrumb.Message = Redact(breadcrumb.Message)
+	}
+	return event
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	AddSensitiveValues("s3cr3t-password", "", "abc", "-----BEGIN KEY-----\nbGluZTE=\n-----END KEY-----", "s3cr3t")
+
+	cases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name:     "No sensitive value",
+			text:     "user:abc",
+			expected: "user:abc",
+		},
+		{
+			name:     "Sensitive value",
+			text:     "user:s3cr3t-password, other:s3cr3t",
+			expected: "user:********, other:********",
+		},
+		{
+			name:     "Multi-line value",
+			text:     "key: -----BEGIN KEY-----\nbGluZTE=\n-----END KEY-----",
+			expected: "key: ********",
+		},
+		{
+			name:     "Escaped multi-line value",
+			text:     `"-----BEGIN KEY-----\nbGluZTE=\n-----END KEY-----"`,
+			expected: `"********"`,
+		},
+		{
+			name:     "Line of a multi-line value",
+			text:     "unexpected data: bGluZTE=",
+			expected: "unexpected data: ********",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Redact(tt.text))
+		})
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	assert.Nil(t, RedactError(nil))
+
+	originalErr := errors.New("the token is t0ken-registered-later")
+	err := RedactError(fmt.Errorf("request failed: %w", originalErr))
+	AddSensitiveValues("t0ken-registered-later")
+	assert.EqualError(t, err, "request failed: the token is ********")
+	assert.True(t, errors.Is(err, originalErr))
+	assert.Equal(t, err, RedactError(err))
+}
+
+func TestRedactionHook(t *testing.T) {
+	AddSensitiveValues("hook-s3cr3t")
+
+	var output bytes.Buffer
+	testLogger := logrus.New()
+	testLogger.SetOutput(&output)
+	testLogger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true, DisableQuote: true})
+	testLogger.AddHook(&redactionHook{})
+
+	testLogger.WithField("value", "hook-s3cr3t").WithError(errors.New("invalid hook-s3cr3t")).Errorf("Unable to use %s", "hook-s3cr3t")
+	assert.Equal(t, "level=error msg=Unable to use ******** error=invalid ******** value=********\n", output.String())
+}
+
+func TestRedactSentryEvent(t *testing.T) {
+	AddSensitiveValues("sentry-s3cr3t")
+
+	event := &sentry.Event{
+		Message:     "sentry-s3cr3t",
+		Exception:   []sentry.Exception{{Value: "invalid sentry-s3cr3t"}},
+		Breadcrumbs: []*sentry.Breadcrumb{{Message: "using sentry-s3cr3t"}},
+	}
+	event = redactSentryEvent(event, nil)
+	assert.Equal(t, "********", event.Message)
+	assert.Equal(t, "invalid ********", event.Exception[0].Value)
+	assert.Equal(t, "using ********", event.Breadcrumbs[0].Message)
+}
+
+func TestSensitiveValues(t *testing.T) {
+	t.Parallel()
+
+	sensitiveValues := NewSensitiveValues()
+	sensitiveValues.Add("isolated-value")
+	assert.Equal(t, "using ********", sensitiveValues.Redact("using isolated-value"))
+	assert.Equal(t, "using isolated-value", Redact("using isolated-value"))
+
+	err := sensitiveValues.RedactError(errors.New("invalid isolated-value"))
+	assert.EqualError(t, err, "invalid ********")
+	assert.Equal(t, err, sensitiveValues.RedactError(err))
+
+	sensitiveValues.Clear()
+	assert.Equal(t, "using isolated-value", sensitiveValues.Redact("using isolated-value"))
+}
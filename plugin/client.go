@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Configuration defines how to run a plugin
+// A plugin is an executable that is run for each request. It reads a single JSON request on its standard input and
+// writes a single JSON response on its standard output
+type Configuration struct {
+	Command string            `mapstructure:"command"`
+	Args    []string          `mapstructure:"args"`
+	Env     map[string]string `mapstructure:"env"`
+	// Options given to the plugin in each request
+	Options map[string]interface{} `mapstructure:"options"`
+}
+
+// Validate verifies that the configuration's attributes are valid
+func (config *Configuration) Validate() error {
+	if config.Command == "" {
+		return fmt.Errorf("`command` must be defined to run a plugin")
+	}
+	return nil
+}
+
+// NewClient creates a client that sends the requests of a source or a target (depending on the kind) to the plugin
+// The name is sent in the requests, so that a plugin can serve multiple targets
+func (config *Configuration) NewClient(kind string, name string) *Client {
+	return &Client{config: *config, kind: kind, name: name}
+}
+
+// Client sends requests to a plugin. The version of the protocol is negotiated before the first request
+type Client struct {
+	config  Configuration
+	kind    string
+	name    string
+	version int
+}
+
+// Call sends a request to the plugin and decodes its result
+func (client *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if client.version == 0 {
+		if err := client.handshake(ctx); err != nil {
+			return err
+		}
+	}
+	return client.call(ctx, client.version, method, params, result)
+}
+
+func (client *Client) handshake(ctx context.Context) error {
+	handshakeResult := &HandshakeResult{}
+	if err := client.call(ctx, ProtocolVersion, MethodHandshake, &HandshakeParams{ProtocolVersions: SupportedProtocolVersions}, handshakeResult); err != nil {
+		return err
+	}
+	if !slices.Contains(SupportedProtocolVersions, handshakeResult.ProtocolVersion) {
+		return fmt.Errorf("the plugin %s uses the version %d of the protocol, which is not supported. Supported versions: %v", client.config.Command, handshakeResult.ProtocolVersion, SupportedProtocolVersions)
+	}
+	client.version = handshakeResult.ProtocolVersion
+	return nil
+}
+
+func (client *Client) call(ctx context.Context, version int, method string, params interface{}, result interface{}) error {
+	request := &Request{ProtocolVersion: version, Method: method, Kind: client.kind, Name: client.name, Options: client.config.Options}
+	if params != nil {
+		encodedParams, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		request.Params = encodedParams
+	}
+	input, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, client.config.Command, client.config.Args...)
+	command.Env = os.Environ()
+	for key, value := range client.config.Env {
+		command.Env = append(command.Env, key+"="+value)
+	}
+	command.Stdin = bytes.NewReader(append(input, '\n'))
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("the plugin %s failed on the %s request: %v: %s", client.config.Command, method, err, strings.TrimSpace(stderr.String()))
+	}
+
+	// The output is not included in the errors, since it may contain secrets
+	response := &Response{}
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return fmt.Errorf("the plugin %s returned an invalid response to the %s request", client.config.Command, method)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("the plugin %s returned an error on the %s request: %s", client.config.Command, method, response.Error)
+	}
+	if result != nil && len(response.Result) > 0 {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("the plugin %s returned an invalid result to the %s request", client.config.Command, method)
+		}
+	}
+	return nil
+}
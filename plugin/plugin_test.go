@@ -0,0 +1,141 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The test binary is also used as the plugin executable, depending on this variable
+const testPluginEnv = "CREDENTIALS_SYNC_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(testPluginEnv) {
+	case "reference":
+		Main(ReferenceHandler)
+	case "future":
+		fmt.Println(`{"result": {"protocol_version": 99}}`)
+		os.Exit(0)
+	case "failing":
+		fmt.Fprintln(os.Stderr, "unable to connect")
+		os.Exit(2)
+	}
+	os.Exit(m.Run())
+}
+
+func testPlugin(mode string, options map[string]interface{}) *Configuration {
+	return &Configuration{Command: os.Args[0], Env: map[string]string{testPluginEnv: mode}, Options: options}
+}
+
+func TestConfigurationValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, testPlugin("reference", nil).Validate())
+	assert.EqualError(t, (&Configuration{}).Validate(), "`command` must be defined to run a plugin")
+}
+
+func TestReferencePlugin(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	target := testPlugin("reference", map[string]interface{}{"path": path}).NewClient(KindTarget, "test")
+
+	initializeResult := &InitializeResult{}
+	assert.NoError(t, target.Call(context.Background(), MethodInitialize, &InitializeParams{Credentials: []string{"my-cred"}}, initializeResult))
+	assert.Equal(t, []string{}, initializeResult.ExistingCredentials)
+	assert.Equal(t, 1, target.version)
+
+	document := map[string]interface{}{"id": "my-cred", "type": "secret", "secret": "a-secret"}
+	assert.NoError(t, target.Call(context.Background(), MethodUpdateCredentials, &UpdateCredentialsParams{Credentials: document}, nil))
+	assert.NoError(t, target.Call(context.Background(), MethodUpdateCredentials, &UpdateCredentialsParams{Credentials: map[string]interface{}{"id": "other", "type": "secret", "secret": "other"}}, nil))
+	assert.NoError(t, target.Call(context.Background(), MethodDeleteCredentials, &DeleteCredentialsParams{ID: "other"}, nil))
+	assert.NoError(t, target.Call(context.Background(), MethodInitialize, &InitializeParams{}, initializeResult))
+	assert.Equal(t, []string{"my-cred"}, initializeResult.ExistingCredentials)
+
+	source := testPlugin("reference", map[string]interface{}{"path": path}).NewClient(KindSource, "")
+	credentialsResult := &CredentialsResult{}
+	assert.NoError(t, source.Call(context.Background(), MethodCredentials, nil, credentialsResult))
+	assert.Equal(t, []map[string]interface{}{document}, credentialsResult.Credentials)
+
+	err := source.Call(context.Background(), "unknown", nil, nil)
+	assert.EqualError(t, err, fmt.Sprintf("the plugin %s returned an error on the unknown request: unknown method: unknown", os.Args[0]))
+}
+
+func TestPluginErrors(t *testing.T) {
+	t.Parallel()
+
+	err := testPlugin("future", nil).NewClient(KindSource, "").Call(context.Background(), MethodCredentials, nil, nil)
+	assert.EqualError(t, err, fmt.Sprintf("the plugin %s uses the version 99 of the protocol, which is not supported. Supported versions: [1]", os.Args[0]))
+
+	err = testPlugin("failing", nil).NewClient(KindSource, "").Call(context.Background(), MethodCredentials, nil, nil)
+	assert.EqualError(t, err, fmt.Sprintf("the plugin %s failed on the handshake request: exit status 2: unable to connect", os.Args[0]))
+
+	err = testPlugin("reference", nil).NewClient(KindSource, "").Call(context.Background(), MethodCredentials, nil, nil)
+	assert.EqualError(t, err, fmt.Sprintf("the plugin %s returned an error on the credentials request: the `path` option is required", os.Args[0]))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = testPlugin("reference", nil).NewClient(KindSource, "").Call(ctx, MethodCredentials, nil, nil)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	handler := func(ctx context.Context, request *Request) (interface{}, error) {
+		return map[string]string{"method": request.Method, "name": request.Name}, nil
+	}
+
+	cases := []struct {
+		name             string
+		request          string
+		expectedResponse string
+	}{
+		{
+			name:             "Handshake",
+			request:          `{"method": "handshake", "params": {"protocol_versions": [1, 2]}}`,
+			expectedResponse: `{"result": {"protocol_version": 1}}`,
+		},
+		{
+			name:             "Handshake without a common version",
+			request:          `{"method": "handshake", "params": {"protocol_versions": [2]}}`,
+			expectedResponse: `{"error": "none of the protocol versions [2] are supported. Supported versions: [1]"}`,
+		},
+		{
+			name:             "Request",
+			request:          `{"protocol_version": 1, "method": "initialize", "kind": "target", "name": "test"}`,
+			expectedResponse: `{"result": {"method": "initialize", "name": "test"}}`,
+		},
+		{
+			name:             "Unsupported version",
+			request:          `{"protocol_version": 2, "method": "initialize"}`,
+			expectedResponse: `{"error": "unsupported protocol version: 2"}`,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			assert.NoError(t, Serve(context.Background(), handler, strings.NewReader(tt.request), &output))
+			assert.JSONEq(t, tt.expectedResponse, output.String())
+		})
+	}
+
+	assert.Error(t, Serve(context.Background(), handler, strings.NewReader("not json"), &bytes.Buffer{}))
+}
+
+func TestRequestEncoding(t *testing.T) {
+	t.Parallel()
+
+	request := &Request{ProtocolVersion: 1, Method: MethodDeleteCredentials, Kind: KindTarget, Name: "test", Params: json.RawMessage(`{"id":"my-cred"}`)}
+	encoded, err := json.Marshal(request)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"protocol_version": 1, "method": "delete_credentials", "kind": "target", "name": "test", "params": {"id": "my-cred"}}`, string(encoded))
+}
@@ -0,0 +1,84 @@
+package plugin
+
+import "encoding/json"
+
+// ProtocolVersion is the latest version of the plugin protocol
+const ProtocolVersion = 1
+
+// SupportedProtocolVersions lists the versions of the plugin protocol that can be negotiated with a plugin
+var SupportedProtocolVersions = []int{1}
+
+// Kinds of plugins
+const (
+	KindSource = "source"
+	KindTarget = "target"
+)
+
+// Methods of the plugin protocol
+const (
+	// Negotiates the version of the protocol. Sent before any other request
+	MethodHandshake = "handshake"
+	// Source: returns all credentials documents
+	MethodCredentials = "credentials"
+	// Target: returns the IDs of the existing credentials
+	MethodInitialize = "initialize"
+	// Target: creates or updates a credentials
+	MethodUpdateCredentials = "update_credentials"
+	// Target: deletes a credentials
+	MethodDeleteCredentials = "delete_credentials"
+)
+
+// Request is the JSON document written to the standard input of the plugin
+type Request struct {
+	// Version of the protocol negotiated by the handshake
+	ProtocolVersion int    `json:"protocol_version"`
+	Method          string `json:"method"`
+	Kind            string `json:"kind"`
+	// Name of the target, empty for sources
+	Name string `json:"name,omitempty"`
+	// Options given to the plugin in the configuration file
+	Options map[string]interface{} `json:"options,omitempty"`
+	Params  json.RawMessage        `json:"params,omitempty"`
+}
+
+// Response is the JSON document written by the plugin to its standard output
+// A request fails if its response has an error, or if the plugin exits with a non-zero status
+type Response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// HandshakeParams lists the versions of the protocol supported by credentials-sync
+type HandshakeParams struct {
+	ProtocolVersions []int `json:"protocol_versions"`
+}
+
+// HandshakeResult is the version of the protocol chosen by the plugin among the supported versions
+type HandshakeResult struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// CredentialsResult contains the credentials documents of a source, in the format of the other sources
+type CredentialsResult struct {
+	Credentials []map[string]interface{} `json:"credentials"`
+}
+
+// InitializeParams contains the target IDs of the credentials that are synced to the target
+type InitializeParams struct {
+	Credentials []string `json:"credentials"`
+}
+
+// InitializeResult contains the IDs of the credentials that exist on the target
+type InitializeResult struct {
+	ExistingCredentials []string `json:"existing_credentials"`
+}
+
+// UpdateCredentialsParams contains the credentials document to write. Its `id` is the target ID of the credentials
+type UpdateCredentialsParams struct {
+	Credentials map[string]interface{} `json:"credentials"`
+}
+
+// DeleteCredentialsParams contains the ID of the credentials to delete
+type DeleteCredentialsParams struct {
+	ID string `json:"id"`
+}
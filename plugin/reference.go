@@ -0,0 +1,87 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ReferenceHandler is the handler of the reference plugin, which is used to test the protocol
+// It stores credentials documents in a JSON file (credentials ID -> Document), given by the `path` option
+// It can be used both as a source (the documents of the file) and as a target (which writes the documents to the file)
+func ReferenceHandler(ctx context.Context, request *Request) (interface{}, error) {
+	path, _ := request.Options["path"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("the `path` option is required")
+	}
+	documents, err := readReferenceFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch request.Method {
+	case MethodCredentials:
+		result := &CredentialsResult{Credentials: []map[string]interface{}{}}
+		for _, id := range sortedReferenceIDs(documents) {
+			document := documents[id]
+			document["id"] = id
+			result.Credentials = append(result.Credentials, document)
+		}
+		return result, nil
+	case MethodInitialize:
+		return &InitializeResult{ExistingCredentials: sortedReferenceIDs(documents)}, nil
+	case MethodUpdateCredentials:
+		params := &UpdateCredentialsParams{}
+		if err := DecodeParams(request, params); err != nil {
+			return nil, err
+		}
+		id, _ := params.Credentials["id"].(string)
+		if id == "" {
+			return nil, fmt.Errorf("the credentials document has no `id`")
+		}
+		delete(params.Credentials, "id")
+		documents[id] = params.Credentials
+		return nil, writeReferenceFile(path, documents)
+	case MethodDeleteCredentials:
+		params := &DeleteCredentialsParams{}
+		if err := DecodeParams(request, params); err != nil {
+			return nil, err
+		}
+		delete(documents, params.ID)
+		return nil, writeReferenceFile(path, documents)
+	}
+	return nil, fmt.Errorf("unknown method: %s", request.Method)
+}
+
+func readReferenceFile(path string) (map[string]map[string]interface{}, error) {
+	documents := map[string]map[string]interface{}{}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return documents, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &documents); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return documents, nil
+}
+
+func writeReferenceFile(path string, documents map[string]map[string]interface{}) error {
+	content, err := json.MarshalIndent(documents, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+func sortedReferenceIDs(documents map[string]map[string]interface{}) []string {
+	ids := []string{}
+	for id := range documents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,9 @@
+// The reference plugin stores credentials in a JSON file, given by the `path` option
+// It shows how to write a plugin in Go, and can be used to try the plugin source and target
+package main
+
+import "github.com/coveooss/credentials-sync/plugin"
+
+func main() {
+	plugin.Main(plugin.ReferenceHandler)
+}
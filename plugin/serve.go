@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// Handler handles a request of the plugin protocol (other than the handshake) and returns its result
+type Handler func(ctx context.Context, request *Request) (interface{}, error)
+
+// Serve reads a request from the input, handles it and writes the response to the output
+// The handshake is handled by Serve, so plugins written in Go only have to handle the other methods
+func Serve(ctx context.Context, handler Handler, input io.Reader, output io.Writer) error {
+	request := &Request{}
+	if err := json.NewDecoder(input).Decode(request); err != nil {
+		return fmt.Errorf("unable to read the request: %v", err)
+	}
+
+	var (
+		result interface{}
+		err    error
+	)
+	if request.Method == MethodHandshake {
+		result, err = handshake(request)
+	} else if !slices.Contains(SupportedProtocolVersions, request.ProtocolVersion) {
+		err = fmt.Errorf("unsupported protocol version: %d", request.ProtocolVersion)
+	} else {
+		result, err = handler(ctx, request)
+	}
+
+	response := &Response{}
+	if err != nil {
+		response.Error = err.Error()
+	} else if result != nil {
+		if response.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+	}
+	return json.NewEncoder(output).Encode(response)
+}
+
+// handshake chooses the latest version of the protocol that is supported by both sides
+func handshake(request *Request) (*HandshakeResult, error) {
+	params := &HandshakeParams{}
+	if err := DecodeParams(request, params); err != nil {
+		return nil, err
+	}
+	result := &HandshakeResult{}
+	for _, version := range params.ProtocolVersions {
+		if slices.Contains(SupportedProtocolVersions, version) && version > result.ProtocolVersion {
+			result.ProtocolVersion = version
+		}
+	}
+	if result.ProtocolVersion == 0 {
+		return nil, fmt.Errorf("none of the protocol versions %v are supported. Supported versions: %v", params.ProtocolVersions, SupportedProtocolVersions)
+	}
+	return result, nil
+}
+
+// Main serves a single request on the standard input and output. It is meant to be the main function of a plugin
+func Main(handler Handler) {
+	if err := Serve(context.Background(), handler, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// DecodeParams decodes the parameters of a request
+func DecodeParams(request *Request, params interface{}) error {
+	if err := json.Unmarshal(request.Params, params); err != nil {
+		return fmt.Errorf("invalid parameters for the %s request: %v", request.Method, err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package retry
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/coveooss/credentials-sync/logger"
+)
+
+// AWSRetryer applies a retry policy to the requests sent by the AWS SDK
+// Besides the retryable status codes of the policy, requests are retried on the errors that the SDK considers transient
+type AWSRetryer struct {
+	Policy Policy
+	// Name of the system which sends the requests, used in logs
+	Name string
+}
+
+// MaxRetries returns the number of times a request may be retried
+func (retryer *AWSRetryer) MaxRetries() int {
+	return retryer.Policy.GetMaxAttempts() - 1
+}
+
+// ShouldRetry returns true if the failed request is retryable
+func (retryer *AWSRetryer) ShouldRetry(r *request.Request) bool {
+	if r.HTTPResponse != nil && retryer.Policy.IsRetryableStatusCode(r.HTTPResponse.StatusCode) {
+		return true
+	}
+	if r.Retryable != nil {
+		return *r.Retryable
+	}
+	return r.IsErrorRetryable() || r.IsErrorThrottle()
+}
+
+// RetryRules returns the time to wait before retrying the request
+func (retryer *AWSRetryer) RetryRules(r *request.Request) time.Duration {
+	backoff := retryer.Policy.Backoff(r.RetryCount + 1)
+	logger.FromContext(r.Context()).Warningf("[%s] %s.%s failed (%v), retrying in %s (attempt %d/%d)", retryer.Name, r.ClientInfo.ServiceName, r.Operation.Name, r.Error, backoff, r.RetryCount+2, retryer.Policy.GetMaxAttempts())
+	CountRetry(r.Context())
+	return backoff
+}
@@ -0,0 +1,22 @@
+package retry
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAWSRetryer(t *testing.T) {
+	t.Parallel()
+
+	retryer := &AWSRetryer{Policy: Policy{MaxAttempts: aws.Int(4), RetryableStatusCodes: []int{http.StatusBadGateway}}, Name: "test"}
+	assert.Equal(t, 3, retryer.MaxRetries())
+
+	assert.True(t, retryer.ShouldRetry(&request.Request{HTTPResponse: &http.Response{StatusCode: http.StatusBadGateway}}))
+	assert.False(t, retryer.ShouldRetry(&request.Request{HTTPResponse: &http.Response{StatusCode: http.StatusNotFound}}))
+	assert.False(t, retryer.ShouldRetry(&request.Request{HTTPResponse: &http.Response{StatusCode: http.StatusNotFound}, Retryable: aws.Bool(false)}))
+	assert.True(t, retryer.ShouldRetry(&request.Request{HTTPResponse: &http.Response{StatusCode: http.StatusNotFound}, Retryable: aws.Bool(true)}))
+}
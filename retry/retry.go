@@ -0,0 +1,153 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+	defaultJitter         = 0.2
+)
+
+var defaultRetryableStatusCodes = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// Policy defines how operations that fail with a transient error are retried
+// Unset attributes take the value of the default policy given to WithDefaults, or the built-in defaults
+type Policy struct {
+	MaxAttempts          *int           `mapstructure:"max_attempts"`
+	InitialBackoff       *time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff           *time.Duration `mapstructure:"max_backoff"`
+	Jitter               *float64       `mapstructure:"jitter"`
+	RetryableStatusCodes []int          `mapstructure:"retryable_status_codes"`
+}
+
+// WithDefaults returns a copy of the policy where unset attributes are taken from the given defaults
+func (policy Policy) WithDefaults(defaults Policy) Policy {
+	if policy.MaxAttempts == nil {
+		policy.MaxAttempts = defaults.MaxAttempts
+	}
+	if policy.InitialBackoff == nil {
+		policy.InitialBackoff = defaults.InitialBackoff
+	}
+	if policy.MaxBackoff == nil {
+		policy.MaxBackoff = defaults.MaxBackoff
+	}
+	if policy.Jitter == nil {
+		policy.Jitter = defaults.Jitter
+	}
+	if policy.RetryableStatusCodes == nil {
+		policy.RetryableStatusCodes = defaults.RetryableStatusCodes
+	}
+	return policy
+}
+
+// GetMaxAttempts returns the maximum number of times an operation is attempted, including the first attempt
+func (policy Policy) GetMaxAttempts() int {
+	if policy.MaxAttempts == nil {
+		return defaultMaxAttempts
+	}
+	return *policy.MaxAttempts
+}
+
+// Backoff returns the time to wait after the given failed attempt (starting at 1) before attempting the operation again
+// The backoff doubles after each attempt, up to the maximum backoff, and is then randomized by the jitter factor
+func (policy Policy) Backoff(attempt int) time.Duration {
+	initialBackoff, maxBackoff, jitter := defaultInitialBackoff, defaultMaxBackoff, defaultJitter
+	if policy.InitialBackoff != nil {
+		initialBackoff = *policy.InitialBackoff
+	}
+	if policy.MaxBackoff != nil {
+		maxBackoff = *policy.MaxBackoff
+	}
+	if policy.Jitter != nil {
+		jitter = *policy.Jitter
+	}
+
+	backoff := initialBackoff
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return time.Duration(float64(backoff) * (1 + jitter*(2*rand.Float64()-1)))
+}
+
+// IsRetryableStatusCode returns true if a HTTP response with the given status code should be retried
+func (policy Policy) IsRetryableStatusCode(statusCode int) bool {
+	statusCodes := policy.RetryableStatusCodes
+	if statusCodes == nil {
+		statusCodes = defaultRetryableStatusCodes
+	}
+	for _, retryableStatusCode := range statusCodes {
+		if statusCode == retryableStatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate verifies that the policy's attributes have valid values
+func (policy Policy) Validate() error {
+	if policy.MaxAttempts != nil && *policy.MaxAttempts < 1 {
+		return fmt.Errorf("`max_attempts` must be at least 1")
+	}
+	if policy.InitialBackoff != nil && *policy.InitialBackoff < 0 {
+		return fmt.Errorf("`initial_backoff` cannot be negative")
+	}
+	if policy.MaxBackoff != nil && *policy.MaxBackoff < 0 {
+		return fmt.Errorf("`max_backoff` cannot be negative")
+	}
+	if policy.Jitter != nil && (*policy.Jitter < 0 || *policy.Jitter > 1) {
+		return fmt.Errorf("`jitter` must be between 0 and 1")
+	}
+	for _, statusCode := range policy.RetryableStatusCodes {
+		if statusCode < 100 || statusCode > 599 {
+			return fmt.Errorf("`retryable_status_codes` contains an invalid status code: %d", statusCode)
+		}
+	}
+	return nil
+}
+
+// Counter counts the retries of the operations executed with a context returned by WithCounter
+type Counter struct {
+	count atomic.Int64
+}
+
+// Count returns the number of retries counted so far
+func (counter *Counter) Count() int {
+	return int(counter.count.Load())
+}
+
+type counterKey struct{}
+
+// WithCounter returns a copy of the given context in which the retries are counted by the given counter
+func WithCounter(ctx context.Context, counter *Counter) context.Context {
+	return context.WithValue(ctx, counterKey{}, counter)
+}
+
+// CountRetry records a retry in the counter of the given context, if it has one
+func CountRetry(ctx context.Context) {
+	if counter, ok := ctx.Value(counterKey{}).(*Counter); ok {
+		counter.count.Add(1)
+	}
+}
+
+// wait waits for the given duration, unless the context is done before
+func wait(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
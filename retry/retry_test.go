@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func durationPointer(duration time.Duration) *time.Duration {
+	return &duration
+}
+
+func TestWithDefaults(t *testing.T) {
+	t.Parallel()
+
+	defaults := Policy{MaxAttempts: aws.Int(5), Jitter: aws.Float64(0), RetryableStatusCodes: []int{500}}
+	policy := Policy{MaxAttempts: aws.Int(2)}.WithDefaults(defaults)
+	assert.Equal(t, 2, policy.GetMaxAttempts())
+	assert.Equal(t, 0.0, *policy.Jitter)
+	assert.Nil(t, policy.InitialBackoff)
+	assert.True(t, policy.IsRetryableStatusCode(500))
+	assert.False(t, policy.IsRetryableStatusCode(502))
+
+	// Built-in defaults
+	assert.Equal(t, 3, Policy{}.GetMaxAttempts())
+	assert.True(t, Policy{}.IsRetryableStatusCode(502))
+	assert.False(t, Policy{}.IsRetryableStatusCode(404))
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	policy := Policy{InitialBackoff: durationPointer(time.Second), MaxBackoff: durationPointer(5 * time.Second), Jitter: aws.Float64(0)}
+	assert.Equal(t, time.Second, policy.Backoff(1))
+	assert.Equal(t, 2*time.Second, policy.Backoff(2))
+	assert.Equal(t, 4*time.Second, policy.Backoff(3))
+	assert.Equal(t, 5*time.Second, policy.Backoff(4))
+	assert.Equal(t, 5*time.Second, policy.Backoff(100))
+
+	policy.Jitter = aws.Float64(0.5)
+	for i := 0; i < 100; i++ {
+		backoff := policy.Backoff(1)
+		assert.GreaterOrEqual(t, backoff, 500*time.Millisecond)
+		assert.LessOrEqual(t, backoff, 1500*time.Millisecond)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		policy        Policy
+		expectedError error
+	}{
+		{
+			name:   "Empty",
+			policy: Policy{},
+		},
+		{
+			name:   "Valid",
+			policy: Policy{MaxAttempts: aws.Int(1), InitialBackoff: durationPointer(0), MaxBackoff: durationPointer(time.Minute), Jitter: aws.Float64(1), RetryableStatusCodes: []int{500}},
+		},
+		{
+			name:          "No attempts",
+			policy:        Policy{MaxAttempts: aws.Int(0)},
+			expectedError: fmt.Errorf("`max_attempts` must be at least 1"),
+		},
+		{
+			name:          "Negative initial backoff",
+			policy:        Policy{InitialBackoff: durationPointer(-time.Second)},
+			expectedError: fmt.Errorf("`initial_backoff` cannot be negative"),
+		},
+		{
+			name:          "Negative max backoff",
+			policy:        Policy{MaxBackoff: durationPointer(-time.Second)},
+			expectedError: fmt.Errorf("`max_backoff` cannot be negative"),
+		},
+		{
+			name:          "Invalid jitter",
+			policy:        Policy{Jitter: aws.Float64(1.5)},
+			expectedError: fmt.Errorf("`jitter` must be between 0 and 1"),
+		},
+		{
+			name:          "Invalid status code",
+			policy:        Policy{RetryableStatusCodes: []int{50}},
+			expectedError: fmt.Errorf("`retryable_status_codes` contains an invalid status code: 50"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.policy.Validate())
+		})
+	}
+}
+
+func TestCounter(t *testing.T) {
+	t.Parallel()
+
+	counter := &Counter{}
+	ctx := WithCounter(context.Background(), counter)
+	CountRetry(ctx)
+	CountRetry(ctx)
+	CountRetry(context.Background())
+	assert.Equal(t, 2, counter.Count())
+}
@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/coveooss/credentials-sync/logger"
+)
+
+// Transport is a http.RoundTripper that retries the requests that fail with a network error or a retryable status code
+type Transport struct {
+	Base   http.RoundTripper
+	Policy Policy
+	// Name of the system to which requests are sent, used in logs
+	Name string
+}
+
+// RoundTrip sends the request, retrying it according to the transport's policy
+func (transport *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+	maxAttempts := transport.Policy.GetMaxAttempts()
+	attemptRequest := request
+	for attempt := 1; ; attempt++ {
+		response, err := transport.Base.RoundTrip(attemptRequest)
+
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else if transport.Policy.IsRetryableStatusCode(response.StatusCode) {
+			reason = response.Status
+		}
+		if reason == "" || attempt >= maxAttempts || ctx.Err() != nil {
+			return response, err
+		}
+
+		// The body must be sent again, which is only possible if it can be rewound
+		if request.Body != nil && request.GetBody == nil {
+			return response, err
+		}
+		if response != nil {
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		backoff := transport.Policy.Backoff(attempt)
+		logger.FromContext(ctx).Warningf("[%s] %s %s failed (%s), retrying in %s (attempt %d/%d)", transport.Name, request.Method, request.URL.Path, reason, backoff, attempt+1, maxAttempts)
+		CountRetry(ctx)
+		if err := wait(ctx, backoff); err != nil {
+			return nil, err
+		}
+
+		attemptRequest = request.Clone(ctx)
+		if request.GetBody != nil {
+			if attemptRequest.Body, err = request.GetBody(); err != nil {
+				return nil, fmt.Errorf("unable to rewind the body of the request: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFlakyServer returns a server that responds with the given status codes, in order, and then with 200
+func newFlakyServer(t *testing.T, statusCodes ...int) (*httptest.Server, *[]string) {
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+		if len(bodies) <= len(statusCodes) {
+			w.WriteHeader(statusCodes[len(bodies)-1])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+func newTestClient(maxAttempts int) *http.Client {
+	policy := Policy{MaxAttempts: aws.Int(maxAttempts), InitialBackoff: durationPointer(time.Millisecond)}
+	return &http.Client{Transport: &Transport{Base: http.DefaultTransport, Policy: policy, Name: "test"}}
+}
+
+func TestTransportRetriesRetryableStatusCodes(t *testing.T) {
+	server, bodies := newFlakyServer(t, http.StatusBadGateway, http.StatusServiceUnavailable)
+
+	counter := &Counter{}
+	request, _ := http.NewRequestWithContext(WithCounter(context.Background(), counter), http.MethodPost, server.URL, strings.NewReader("payload"))
+	response, err := newTestClient(3).Do(request)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, 2, counter.Count())
+
+	// The body is sent again on each attempt
+	assert.Equal(t, []string{"payload", "payload", "payload"}, *bodies)
+}
+
+func TestTransportStopsAfterMaxAttempts(t *testing.T) {
+	server, bodies := newFlakyServer(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+
+	response, err := newTestClient(2).Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+	assert.Len(t, *bodies, 2)
+}
+
+func TestTransportDoesNotRetryOtherStatusCodes(t *testing.T) {
+	server, bodies := newFlakyServer(t, http.StatusNotFound)
+
+	response, err := newTestClient(3).Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Len(t, *bodies, 1)
+}
+
+func TestTransportStopsWhenContextIsDone(t *testing.T) {
+	server, bodies := newFlakyServer(t, http.StatusBadGateway, http.StatusBadGateway)
+
+	policy := Policy{InitialBackoff: durationPointer(time.Minute)}
+	client := &http.Client{Transport: &Transport{Base: http.DefaultTransport, Policy: policy, Name: "test"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	_, err := client.Do(request)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, *bodies, 1)
+}
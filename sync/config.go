@@ -1,21 +1,98 @@
 package sync
 
 import (
+	"context"
 	"fmt"
+	gosync "sync"
+	"time"
 
 	"github.com/coveooss/credentials-sync/credentials"
 	"github.com/coveooss/credentials-sync/logger"
+	"github.com/coveooss/credentials-sync/retry"
 	"github.com/coveooss/credentials-sync/targets"
 	"github.com/hashicorp/go-multierror"
 )
 
 // Configuration represents the parsed configuration file given to the application
 type Configuration struct {
+	targets.DeletionLimits `mapstructure:",squash"`
+
 	CredentialsToDelete []string                     `mapstructure:"credentials_to_delete"`
+	FullSync            bool                         `mapstructure:"-"`
+	Metrics             *MetricsConfiguration        `mapstructure:"metrics"`
+	Retry               retry.Policy                 `mapstructure:"retry"`
 	Sources             credentials.SourceCollection `mapstructure:"-"`
+	State               *StateConfiguration          `mapstructure:"state"`
 	StopOnError         bool                         `mapstructure:"stop_on_error"`
 	TargetParallelism   int                          `mapstructure:"target_parallelism"`
 	Targets             targets.TargetCollection     `mapstructure:"-"`
+	Timeout             time.Duration                `mapstructure:"timeout"`
+
+	state *State
+
+	// Logger of the sync. The application's logger is used if it is not set
+	log logger.Logger
+	// Sensitive values of the credentials of the sync, redacted from its logs and errors. The default set is used if
+	// it is not set. Run uses a new set on each call, so that the values are not kept once the sync is done
+	sensitiveValues *logger.SensitiveValues
+	// Called on each event of the sync, if it is set. Calls are serialized
+	eventHandler func(Event)
+	eventMutex   gosync.Mutex
+}
+
+// TargetResult contains the outcome of the sync of a single target
+type TargetResult struct {
+	Target      string
+	Initialized bool
+	Cancelled   bool
+	Changes     int
+	Retries     int
+	Duration    time.Duration
+	Err         error
+	// Outcome of each change that was applied on the target
+	Credentials []*CredentialsResult
+}
+
+// succeeded returns true if the target was initialized and all its changes were applied successfully
+func (result *TargetResult) succeeded() bool {
+	return result.Initialized && !result.Cancelled && result.Err == nil
+}
+
+// CredentialsResult contains the outcome of a single change on the credentials of a target
+type CredentialsResult struct {
+	ID     string
+	Action Action
+	Err    error
+}
+
+// ToString prints out a human readable description of the result
+func (result *TargetResult) ToString() string {
+	switch {
+	case !result.Initialized:
+		return fmt.Sprintf("%s: failed initialization", result.Target)
+	case result.Cancelled:
+		return fmt.Sprintf("%s: cancelled after %d changes (%s)", result.Target, result.Changes, result.details())
+	case result.Err != nil:
+		return fmt.Sprintf("%s: failed after %d changes (%s)", result.Target, result.Changes, result.details())
+	}
+	return fmt.Sprintf("%s: succeeded with %d changes (%s)", result.Target, result.Changes, result.details())
+}
+
+func (result *TargetResult) details() string {
+	details := result.Duration.Round(time.Millisecond).String()
+	if result.Retries > 0 {
+		details = fmt.Sprintf("%s, %d retries", details, result.Retries)
+	}
+	return details
+}
+
+// getLogger returns the logger of the sync, which redacts the sensitive values of the sync
+func (config *Configuration) getLogger() logger.Logger {
+	log := config.log
+	if log == nil {
+		log = logger.Log
+	}
+	return logger.NewRedactingLogger(log, config.sensitiveValues)
 }
 
 // NewConfiguration creates a new configuration with default values
@@ -36,106 +113,309 @@ func (config *Configuration) SetTargets(targets targets.TargetCollection) {
 	config.Targets = targets
 }
 
+// ValidateConfiguration verifies that the global options of the configuration are valid
+func (config *Configuration) ValidateConfiguration() error {
+	if err := config.DeletionLimits.Validate(); err != nil {
+		return fmt.Errorf("Invalid global deletion limits: %v", err)
+	}
+	if config.Timeout < 0 {
+		return fmt.Errorf("The global `timeout` cannot be negative")
+	}
+	if err := config.Retry.Validate(); err != nil {
+		return fmt.Errorf("Invalid global retry policy: %v", err)
+	}
+	if config.State != nil {
+		if err := config.State.ValidateConfiguration(); err != nil {
+			return err
+		}
+	}
+	if config.Metrics != nil {
+		if err := config.Metrics.ValidateConfiguration(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadState reads the state of the previous syncs, if one is configured
+// When doing a full sync, the previous state is ignored, but the resulting state is still saved
+func (config *Configuration) loadState() (err error) {
+	if config.State == nil {
+		return nil
+	}
+	if config.FullSync {
+		config.state = NewState()
+		return nil
+	}
+	if config.state, err = config.State.Load(); err != nil {
+		return fmt.Errorf("Caught an error while loading the state: %v", err)
+	}
+	return nil
+}
+
 // Sync syncs credentials from the configured sources to the configured targets
-func (config *Configuration) Sync() error {
+// The outcome of the sync of each target is returned, along with the errors of the sync. No results are returned if
+// the sync failed before the targets were initialized
+// When the given context is cancelled, the operations in progress are completed, but no other operation is started
+func (config *Configuration) Sync(ctx context.Context) (results []*TargetResult, err error) {
+	// The credentials register their sensitive values in the set of the sync
+	ctx = logger.WithSensitiveValues(ctx, config.sensitiveValues)
+	ctx, cancel := withTimeout(logger.WithLogger(ctx, config.getLogger()), config.Timeout)
+	defer cancel()
+
 	// Start reading credentials
-	creds, err := config.Sources.Credentials()
+	creds, err := config.fetchCredentials(ctx)
 	if err != nil {
-		return fmt.Errorf("Caught an error while fetching credentials: %v", err)
+		return nil, err
 	}
 
+	if err := config.loadState(); err != nil {
+		return nil, err
+	}
+	defer func() { config.exportMetrics(ctx, creds, results) }()
+
 	// Initialize targets
+	validTargets, errorAccumulator := config.initTargets(ctx, creds)
+	results = []*TargetResult{}
+	for _, initError := range initFailures(errorAccumulator) {
+		results = append(results, &TargetResult{Target: initError.target, Err: initError})
+	}
+	if errorAccumulator != nil && config.StopOnError {
+		return results, errorAccumulator
+	}
+
+	// Sync credentials with as many targets as the config allows
+	syncResults, stopError := config.syncTargets(ctx, validTargets, creds)
+	results = append(results, syncResults...)
+	config.logResults(results)
+	if stopError != nil {
+		// Only the error that stopped the sync is returned, other targets were cancelled
+		return results, stopError
+	}
+	for _, result := range syncResults {
+		if result.Err != nil {
+			errorAccumulator = multierror.Append(errorAccumulator, result.Err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		errorAccumulator = multierror.Append(errorAccumulator, fmt.Errorf("The sync was interrupted before completion: %v", err))
+	}
+
+	// Persist the fingerprints of the credentials that were successfully synced, even if some operations failed
+	if config.state != nil {
+		if err := config.State.Save(config.state); err != nil {
+			return results, multierror.Append(errorAccumulator, fmt.Errorf("Caught an error while saving the state: %v", err))
+		}
+	}
+
+	// This is either a nil, or a collection of past errors which we want to bubble up
+	return results, errorAccumulator
+}
+
+// fetchCredentials fetches the credentials from all sources. Unless configured otherwise, sources use the global retry policy
+func (config *Configuration) fetchCredentials(ctx context.Context) ([]credentials.Credentials, error) {
+	config.Sources.SetDefaultRetryPolicy(config.Retry)
+	counter := &retry.Counter{}
+	creds, err := config.Sources.Credentials(retry.WithCounter(ctx, counter))
+	if retries := counter.Count(); retries > 0 {
+		config.getLogger().Infof("Fetching credentials from the sources required %d retries", retries)
+	}
+	if err != nil {
+		err = fmt.Errorf("Caught an error while fetching credentials: %v", err)
+	}
+	config.emit(Event{Type: EventCredentialsFetched, Err: err})
+	if err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+type targetInitError struct {
+	target string
+	err    error
+}
+
+func (e *targetInitError) Error() string {
+	return fmt.Sprintf("Target `%s` has failed initialization: %v", e.target, e.err)
+}
+
+// initFailures returns the initialization errors contained in the error returned by initTargets
+func initFailures(err error) []*targetInitError {
+	failures := []*targetInitError{}
+	if multiErr, ok := err.(*multierror.Error); ok {
+		for _, err := range multiErr.Errors {
+			failures = append(failures, initFailures(err)...)
+		}
+	} else if initErr, ok := err.(*targetInitError); ok {
+		failures = append(failures, initErr)
+	}
+	return failures
+}
+
+// initTargets initializes all targets in parallel and returns the ones that succeeded, in their configuration order
+// If config.StopOnError is set, the returned error is the first initialization error, otherwise it accumulates all of them
+func (config *Configuration) initTargets(ctx context.Context, creds []credentials.Credentials) ([]targets.Target, error) {
 	validTargets := []targets.Target{}
 	allTargets := config.Targets.AllTargets()
-	initChannel := make(chan interface{})
-	for _, target := range allTargets {
-		go config.initTarget(target, creds, initChannel)
+	initResults := make([]error, len(allTargets))
+	var waitGroup gosync.WaitGroup
+	for i, target := range allTargets {
+		waitGroup.Add(1)
+		go func(i int, target targets.Target) {
+			defer waitGroup.Done()
+			initResults[i] = config.initTarget(ctx, target, creds)
+		}(i, target)
 	}
+	waitGroup.Wait()
+
 	// We will use this to accumulate errors that happen if config.StopOnError is set to false
 	// the multierror.Error implements error so we use the interface to type the accumulator
 	var errorAccumulator error
-	for i := 0; i < len(allTargets); i++ {
-		initTarget := <-initChannel
-		if err, ok := initTarget.(error); ok {
+	for i, err := range initResults {
+		if err != nil {
 			if config.StopOnError {
-				return err
+				return nil, err
 			}
 			errorAccumulator = multierror.Append(errorAccumulator, err)
-			logger.Log.Error(err)
+			config.getLogger().Errorf("%v", err)
 		} else {
-			validTargets = append(validTargets, initTarget.(targets.Target))
+			validTargets = append(validTargets, allTargets[i])
 		}
 	}
+	return validTargets, errorAccumulator
+}
 
-	// Sync credentials with as many targets as the config allows
-	parallelismChannel := make(chan bool, config.TargetParallelism)
-	errorChannel := make(chan error)
-	for _, target := range validTargets {
-		parallelismChannel <- true
-		go config.syncCredentials(target, creds, parallelismChannel, errorChannel)
-
-		// Check for errors. Errors are only passed back if StopOnError is true so this should always return
-		err := <-errorChannel
-		if err != nil {
-			if config.StopOnError {
-				return err
-			}
-			errorAccumulator = multierror.Append(errorAccumulator, err)
-		}
+func (config *Configuration) initTarget(ctx context.Context, target targets.Target, creds []credentials.Credentials) error {
+	target.SetDefaultRetryPolicy(config.Retry)
+	ctx, cancel := withTimeout(ctx, target.GetTimeout())
+	defer cancel()
+	if err := target.Initialize(ctx, creds); err != nil {
+		initErr := &targetInitError{target: target.GetName(), err: err}
+		config.emit(Event{Type: EventTargetInitialized, Target: target.GetName(), Err: initErr})
+		return initErr
 	}
+	config.getLogger().Infof("Connected to %s", target.ToString())
+	config.emit(Event{Type: EventTargetInitialized, Target: target.GetName()})
+	return nil
+}
 
-	// Ensure that the sync method is completely done for all targets
-	for i := 0; i < cap(parallelismChannel); i++ {
-		parallelismChannel <- true
-	}
+// syncTargets syncs credentials to the given targets, running at most config.TargetParallelism syncs at the same time
+// If config.StopOnError is set, the first failure cancels the syncs in progress and prevents the remaining ones from starting.
+// That failure is then returned as the second value
+// The returned results are in the same order as the given targets
+func (config *Configuration) syncTargets(ctx context.Context, validTargets []targets.Target, credentialsList []credentials.Credentials) ([]*TargetResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// This is either a nil, or a collection of past errors which we want to bubble up
-	return errorAccumulator
-}
+	parallelism := config.TargetParallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	semaphore := make(chan struct{}, parallelism)
 
-func (config *Configuration) initTarget(target targets.Target, creds []credentials.Credentials, channel chan interface{}) {
-	var channelValue interface{}
+	var (
+		waitGroup gosync.WaitGroup
+		stopOnce  gosync.Once
+		stopError error
+	)
+	results := make([]*TargetResult, len(validTargets))
+	for i, target := range validTargets {
+		results[i] = &TargetResult{Target: target.GetName(), Initialized: true}
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i].Cancelled = true
+			continue
+		}
 
-	defer func() {
-		channel <- channelValue
-	}()
+		waitGroup.Add(1)
+		go func(target targets.Target, result *TargetResult) {
+			defer func() {
+				<-semaphore
+				waitGroup.Done()
+			}()
+			config.syncCredentials(ctx, target, credentialsList, result)
+			if result.Err != nil && config.StopOnError {
+				stopOnce.Do(func() {
+					stopError = result.Err
+					cancel()
+				})
+			}
+		}(target, results[i])
+	}
+	waitGroup.Wait()
+	return results, stopError
+}
 
-	err := target.Initialize(creds)
-	if err == nil {
-		logger.Log.Infof("Connected to %s", target.ToString())
-		channelValue = target
-	} else {
-		channelValue = fmt.Errorf("Target `%s` has failed initialization: %v", target.GetName(), err)
+// logResults prints out a summary of the outcome of the sync for each target
+func (config *Configuration) logResults(results []*TargetResult) {
+	config.getLogger().Infof("Sync results:")
+	for _, result := range results {
+		config.getLogger().Infof("  %s", result.ToString())
 	}
 }
 
-func (config *Configuration) syncCredentials(target targets.Target, credentialsList []credentials.Credentials, parallelismChannel chan bool, errorChannel chan error) {
-	// We will use this to accumulate errors that happen if config.StopOnError is set to false
-	// the multierror.Error implements error so we use the interface to type the accumulator
-	var errorAccumulator error
+func (config *Configuration) syncCredentials(ctx context.Context, target targets.Target, credentialsList []credentials.Credentials, result *TargetResult) {
+	startTime := time.Now()
+	counter := &retry.Counter{}
 	defer func() {
-		errorChannel <- errorAccumulator
-		<-parallelismChannel
+		result.Duration = time.Since(startTime)
+		result.Retries = counter.Count()
+		config.emit(Event{Type: EventTargetSynced, Target: target.GetName(), Err: result.Err})
 	}()
 
-	filteredCredentials := []credentials.Credentials{}
-	for _, cred := range credentialsList {
-		if cred.ShouldSync(target.GetName(), target.GetTags()) {
-			filteredCredentials = append(filteredCredentials, cred)
-		}
+	ctx, cancel := withTimeout(retry.WithCounter(ctx, counter), target.GetTimeout())
+	defer cancel()
+
+	plan := config.PlanTarget(ctx, target, credentialsList)
+	if err := config.CheckDeletionLimits(target, plan); err != nil {
+		config.getLogger().Errorf("%v", err)
+		result.Err = multierror.Append(nil, err)
+		return
+	}
+	applied, err := config.applyChanges(ctx, target, plan.Changes, result)
+	result.Changes = applied
+	result.Credentials = append(result.Credentials, skippedCredentials(target, credentialsList)...)
+	if err != nil {
+		result.Err = multierror.Append(nil, err)
 	}
+	if applied < len(plan.Changes) && deadlineExceeded(ctx) {
+		err := fmt.Errorf("Timed out while syncing credentials to %s: %d changes were not applied", target.GetName(), len(plan.Changes)-applied)
+		config.getLogger().Errorf("%v", err)
+		result.Err = multierror.Append(result.Err, err)
+	}
+	if applied < len(plan.Changes) && result.Err == nil {
+		result.Cancelled = true
+		config.getLogger().Warningf("Cancelled sync to %s", target.GetName())
+		return
+	}
+	config.getLogger().Infof("Finished sync to %s", target.GetName())
+}
 
-	if err := config.UpdateListOfCredentials(target, filteredCredentials); err != nil {
-		errorAccumulator = multierror.Append(errorAccumulator, err)
-		if config.StopOnError {
-			return
-		}
+// withTimeout returns a copy of the given context that is cancelled after the given timeout. Zero means no timeout
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
 	}
-	if err := config.DeleteListOfCredentials(target); err != nil {
-		errorAccumulator = multierror.Append(errorAccumulator, err)
-		if config.StopOnError {
-			return
-		}
+	return context.WithCancel(ctx)
+}
+
+// operationContext returns the context of a single operation on a target
+// Operations in progress are not interrupted when the given context is cancelled (on shutdown or when another target
+// fails with stop_on_error), so that credentials are never left half-written. They are still bound by its deadline
+func operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	operationCtx := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(operationCtx, deadline)
 	}
-	logger.Log.Infof("Finished sync to %s", target.GetName())
+	return context.WithCancel(operationCtx)
+}
+
+// deadlineExceeded returns true if the deadline of the given context has passed, even if the context is not done yet
+// Operations have their own copy of the deadline, which may expire slightly before the one of the given context
+func deadlineExceeded(ctx context.Context) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
 }
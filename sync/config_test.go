@@ -1,10 +1,15 @@
 package sync
 
 import (
+	"context"
 	"fmt"
+	gosync "sync"
 	"testing"
+	"time"
 
 	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/retry"
+	"github.com/coveooss/credentials-sync/targets"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,10 +26,11 @@ func TestSyncCredentials(t *testing.T) {
 	defer sourceController.Finish()
 
 	// Asserts that UpdateCredentials is called with `test1` and `test2`
-	target.EXPECT().UpdateCredentials(cred1).Times(1)
-	target.EXPECT().UpdateCredentials(cred2).Times(1)
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred1).Times(1)
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred2).Times(1)
 
-	assert.Nil(t, config.Sync())
+	_, err := config.Sync(context.Background())
+	assert.Nil(t, err)
 }
 
 func TestSyncCredentialsAndDeleteUnsynced(t *testing.T) {
@@ -39,13 +45,14 @@ func TestSyncCredentialsAndDeleteUnsynced(t *testing.T) {
 	defer sourceController.Finish()
 
 	// Asserts that UpdateCredentials is called with `test1` and `test2`
-	target.EXPECT().UpdateCredentials(cred1).Times(1)
-	target.EXPECT().UpdateCredentials(cred2).Times(1)
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred1).Times(1)
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred2).Times(1)
 
 	// Asserts that DeleteCredentials is called with `unsynced`
-	target.EXPECT().DeleteCredentials("test3").Times(1)
+	target.EXPECT().DeleteCredentials(gomock.Any(), "test3").Times(1)
 
-	assert.Nil(t, config.Sync())
+	_, err := config.Sync(context.Background())
+	assert.Nil(t, err)
 }
 
 func TestSyncCredentialsAndDeleteUnsyncedWithContinueOnError(t *testing.T) {
@@ -60,22 +67,23 @@ func TestSyncCredentialsAndDeleteUnsyncedWithContinueOnError(t *testing.T) {
 	defer sourceController.Finish()
 
 	// first target returns an error on initialize so it is removed from the pool
-	targets[0].EXPECT().Initialize(gomock.Any()).Return(fmt.Errorf("Dummy error")).AnyTimes()
-	targets[1].EXPECT().Initialize(gomock.Any()).Return(nil).AnyTimes()
+	targets[0].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(fmt.Errorf("Dummy error")).AnyTimes()
+	targets[1].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
 	// Second target fails update on first cred but succeeds on second
-	targets[1].EXPECT().UpdateCredentials(cred1).Return(fmt.Errorf("Dummy error")).Times(1)
-	targets[1].EXPECT().UpdateCredentials(cred2).Times(1)
+	targets[1].EXPECT().UpdateCredentials(gomock.Any(), cred1).Return(fmt.Errorf("Dummy error")).Times(1)
+	targets[1].EXPECT().UpdateCredentials(gomock.Any(), cred2).Times(1)
 
 	// Second target fails delete on first cred but succeeds on second
-	targets[1].EXPECT().DeleteCredentials("test3").Return(fmt.Errorf("Dummy error")).Times(1)
-	targets[1].EXPECT().DeleteCredentials("test4").Times(1)
+	targets[1].EXPECT().DeleteCredentials(gomock.Any(), "test3").Return(fmt.Errorf("Dummy error")).Times(1)
+	targets[1].EXPECT().DeleteCredentials(gomock.Any(), "test4").Times(1)
 
 	// Second target fails deleting the first listed credentials but tries the second
-	targets[1].EXPECT().DeleteCredentials("bad").Return(fmt.Errorf("Dummy error")).Times(2)
-	targets[1].EXPECT().DeleteCredentials("bad2").Times(2)
+	targets[1].EXPECT().DeleteCredentials(gomock.Any(), "bad").Return(fmt.Errorf("Dummy error")).Times(2)
+	targets[1].EXPECT().DeleteCredentials(gomock.Any(), "bad2").Times(2)
 
-	assert.EqualError(t, config.Sync(), "5 errors occurred:\n\t* Target `target-0` has failed initialization: Dummy error\n\t* Failed to send credentials with ID test1 to target-1: Dummy error\n\t* Failed to delete credentials with ID test3 from target-1: Dummy error\n\t* Failed to delete credentials with ID bad from target-1: Dummy error\n\t* Failed to delete credentials with ID bad from target-1: Dummy error\n\n")
+	_, err := config.Sync(context.Background())
+	assert.EqualError(t, err, "5 errors occurred:\n\t* Target `target-0` has failed initialization: Dummy error\n\t* Failed to send credentials with ID test1 to target-1: Dummy error\n\t* Failed to delete credentials with ID test3 from target-1: Dummy error\n\t* Failed to delete credentials with ID bad from target-1: Dummy error\n\t* Failed to delete credentials with ID bad from target-1: Dummy error\n\n")
 }
 
 func TestSyncCredentialsFailOnInitialize(t *testing.T) {
@@ -89,10 +97,11 @@ func TestSyncCredentialsFailOnInitialize(t *testing.T) {
 	defer targetController.Finish()
 	defer sourceController.Finish()
 
-	targets[0].EXPECT().Initialize(gomock.Any()).Return(fmt.Errorf("Dummy error1")).AnyTimes()
-	targets[1].EXPECT().Initialize(gomock.Any()).Return(nil).AnyTimes()
+	targets[0].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(fmt.Errorf("Dummy error1")).AnyTimes()
+	targets[1].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-	assert.EqualError(t, config.Sync(), "Target `target-0` has failed initialization: Dummy error1")
+	_, err := config.Sync(context.Background())
+	assert.EqualError(t, err, "Target `target-0` has failed initialization: Dummy error1")
 }
 
 func TestSyncCredentialsFailOnCredentialsUpdate(t *testing.T) {
@@ -107,14 +116,15 @@ func TestSyncCredentialsFailOnCredentialsUpdate(t *testing.T) {
 	defer sourceController.Finish()
 
 	// Initialize works for both targets
-	targets[0].EXPECT().Initialize(gomock.Any()).Return(nil).AnyTimes()
-	targets[1].EXPECT().Initialize(gomock.Any()).Return(nil).AnyTimes()
+	targets[0].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	targets[1].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
 	// First target succeeds but second target fails
-	targets[0].EXPECT().UpdateCredentials(gomock.Any()).Return(nil).AnyTimes()
-	targets[1].EXPECT().UpdateCredentials(cred1).Return(fmt.Errorf("Dummy error2")).Times(1)
+	targets[0].EXPECT().UpdateCredentials(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	targets[1].EXPECT().UpdateCredentials(gomock.Any(), cred1).Return(fmt.Errorf("Dummy error2")).Times(1)
 
-	assert.EqualError(t, config.Sync(), "1 error occurred:\n\t* Failed to send credentials with ID test1 to target-1: Dummy error2\n\n")
+	_, err := config.Sync(context.Background())
+	assert.EqualError(t, err, "1 error occurred:\n\t* Failed to send credentials with ID test1 to target-1: Dummy error2\n\n")
 }
 
 func TestSyncCredentialsFailOnsDeleteUnsyncedCredentials(t *testing.T) {
@@ -129,18 +139,19 @@ func TestSyncCredentialsFailOnsDeleteUnsyncedCredentials(t *testing.T) {
 	defer sourceController.Finish()
 
 	// Initialize works for both targets
-	targets[0].EXPECT().Initialize(gomock.Any()).Return(nil).AnyTimes()
-	targets[1].EXPECT().Initialize(gomock.Any()).Return(nil).AnyTimes()
+	targets[0].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	targets[1].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
 	// UpdateCredentials works for both targets
-	targets[0].EXPECT().UpdateCredentials(gomock.Any()).Return(nil).AnyTimes()
-	targets[1].EXPECT().UpdateCredentials(gomock.Any()).Return(nil).AnyTimes()
+	targets[0].EXPECT().UpdateCredentials(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	targets[1].EXPECT().UpdateCredentials(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
 	// Delete credentials fails for first target but succeeds for second
-	targets[0].EXPECT().DeleteCredentials("test3").Return(fmt.Errorf("Dummy error3")).Times(1)
-	targets[1].EXPECT().DeleteCredentials(gomock.Any()).Return(nil).AnyTimes()
+	targets[0].EXPECT().DeleteCredentials(gomock.Any(), "test3").Return(fmt.Errorf("Dummy error3")).Times(1)
+	targets[1].EXPECT().DeleteCredentials(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-	assert.EqualError(t, config.Sync(), "1 error occurred:\n\t* Failed to delete credentials with ID test3 from target-0: Dummy error3\n\n")
+	_, err := config.Sync(context.Background())
+	assert.EqualError(t, err, "1 error occurred:\n\t* Failed to delete credentials with ID test3 from target-0: Dummy error3\n\n")
 }
 
 func TestSyncCredentialsFailOnsDeleteListedCredentials(t *testing.T) {
@@ -151,12 +162,272 @@ func TestSyncCredentialsFailOnsDeleteListedCredentials(t *testing.T) {
 	defer sourceController.Finish()
 
 	// Initialize works for both targets
-	targets[0].EXPECT().Initialize(gomock.Any()).Return(nil).AnyTimes()
-	targets[1].EXPECT().Initialize(gomock.Any()).Return(nil).AnyTimes()
+	targets[0].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	targets[1].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
 	// Delete credentials fails for first target but succeeds for second
-	targets[0].EXPECT().DeleteCredentials(gomock.Any()).Return(nil).AnyTimes()
-	targets[1].EXPECT().DeleteCredentials("bad2").Return(fmt.Errorf("Dummy error4")).Times(1)
+	targets[0].EXPECT().DeleteCredentials(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	targets[1].EXPECT().DeleteCredentials(gomock.Any(), "bad2").Return(fmt.Errorf("Dummy error4")).Times(1)
 
-	assert.EqualError(t, config.Sync(), "1 error occurred:\n\t* Failed to delete credentials with ID bad2 from target-1: Dummy error4\n\n")
+	_, err := config.Sync(context.Background())
+	assert.EqualError(t, err, "1 error occurred:\n\t* Failed to delete credentials with ID bad2 from target-1: Dummy error4\n\n")
+}
+
+func TestSyncCredentialsAbortsOverDeletionLimits(t *testing.T) {
+	cred1 := credentials.NewSecretText()
+	cred1.ID = "test1"
+
+	maxDeletions, maxPercentage := 1, 50.0
+	cases := []struct {
+		name          string
+		globalLimits  targets.DeletionLimits
+		targetLimits  targets.DeletionLimits
+		expectedError string
+	}{
+		{
+			name:          "Target limit",
+			targetLimits:  targets.DeletionLimits{MaxDeletions: &maxDeletions},
+			expectedError: "1 error occurred:\n\t* Aborting the sync of target-0: 2 credentials would be deleted, which exceeds the maximum of 1\n\n",
+		},
+		{
+			name:          "Global limit",
+			globalLimits:  targets.DeletionLimits{MaxDeletionsPercentage: &maxPercentage},
+			expectedError: "1 error occurred:\n\t* Aborting the sync of target-0: 2 out of 3 credentials (66.7%) would be deleted, which exceeds the maximum of 50.0%\n\n",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Configuration{StopOnError: false, TargetParallelism: 1, DeletionLimits: tt.globalLimits}
+			options := targetMockOptions{deleteUnsynced: true, deletionLimits: tt.targetLimits}
+			targetController, mockedTargets := setMultipleTargetMockWithOptions(t, config, "target", []string{"test1", "test2", "test3"}, options, 1)
+			sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1})
+			defer targetController.Finish()
+			defer sourceController.Finish()
+
+			// Nothing is modified on the target
+			mockedTargets[0].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			_, err := config.Sync(context.Background())
+			assert.EqualError(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestSyncCredentialsDoesNotDeleteProtectedCredentials(t *testing.T) {
+	cred1 := credentials.NewSecretText()
+	cred1.ID = "test1"
+
+	maxDeletions := 1
+	config := &Configuration{StopOnError: false, TargetParallelism: 1, CredentialsToDelete: []string{"admin"}}
+	options := targetMockOptions{
+		deleteUnsynced:       true,
+		deletionLimits:       targets.DeletionLimits{MaxDeletions: &maxDeletions},
+		protectedCredentials: []string{"admin", "team-*"},
+	}
+	targetController, mockedTargets := setMultipleTargetMockWithOptions(t, config, "target", []string{"admin", "team-a", "team-b", "unsynced"}, options, 1)
+	sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1})
+	defer targetController.Finish()
+	defer sourceController.Finish()
+
+	// Protected credentials are not counted in the deletion limits and are never deleted
+	mockedTargets[0].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockedTargets[0].EXPECT().UpdateCredentials(gomock.Any(), cred1).Times(1)
+	mockedTargets[0].EXPECT().DeleteCredentials(gomock.Any(), "unsynced").Times(1)
+
+	_, err := config.Sync(context.Background())
+	assert.EqualError(t, err, "1 error occurred:\n\t* Refusing to delete credentials with ID admin from target-0: they match the protected credentials pattern `admin`\n\n")
+}
+
+func TestSyncCredentialsInParallel(t *testing.T) {
+	cred1 := credentials.NewSecretText()
+	cred1.ID = "test1"
+
+	config := &Configuration{StopOnError: true, TargetParallelism: 3}
+	targetController, mockedTargets := setMultipleTargetMock(t, config, "target", []string{}, false, 3)
+	sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1})
+	defer targetController.Finish()
+	defer sourceController.Finish()
+
+	// Each target waits for all the others to have started. This deadlocks (and times out) if targets are synced one at a time
+	var started gosync.WaitGroup
+	started.Add(len(mockedTargets))
+	allStarted := make(chan bool)
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+	for _, target := range mockedTargets {
+		target.EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		target.EXPECT().UpdateCredentials(gomock.Any(), cred1).DoAndReturn(func(context.Context, credentials.Credentials) error {
+			started.Done()
+			select {
+			case <-allStarted:
+				return nil
+			case <-time.After(5 * time.Second):
+				return fmt.Errorf("Targets were not synced in parallel")
+			}
+		}).Times(1)
+	}
+
+	results, err := config.Sync(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	for i, result := range results {
+		assert.Equal(t, fmt.Sprintf("target-%d", i), result.Target)
+		assert.Equal(t, 1, result.Changes)
+		assert.NoError(t, result.Err)
+	}
+}
+
+func TestSyncCredentialsStopOnErrorCancelsOtherTargets(t *testing.T) {
+	cred1, cred2 := credentials.NewSecretText(), credentials.NewSecretText()
+	cred1.ID = "test1"
+	cred2.ID = "test2"
+
+	config := &Configuration{StopOnError: true, TargetParallelism: 2}
+	targetController, mockedTargets := setMultipleTargetMock(t, config, "target", []string{}, false, 3)
+	sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1, cred2})
+	defer targetController.Finish()
+	defer sourceController.Finish()
+
+	for _, target := range mockedTargets {
+		target.EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	}
+
+	// The first target fails while the second one is syncing its first credentials
+	secondStarted, failed := make(chan bool), make(chan bool)
+	mockedTargets[0].EXPECT().UpdateCredentials(gomock.Any(), cred1).DoAndReturn(func(context.Context, credentials.Credentials) error {
+		<-secondStarted
+		defer close(failed)
+		return fmt.Errorf("Dummy error")
+	}).Times(1)
+
+	// The second target finishes its in-flight credentials but does not sync the next ones. The third target is never synced
+	mockedTargets[1].EXPECT().UpdateCredentials(gomock.Any(), cred1).DoAndReturn(func(context.Context, credentials.Credentials) error {
+		close(secondStarted)
+		<-failed
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}).Times(1)
+
+	results, err := config.Sync(context.Background())
+	assert.EqualError(t, err, "1 error occurred:\n\t* Failed to send credentials with ID test1 to target-0: Dummy error\n\n")
+	assert.Len(t, results, 3)
+	assert.Error(t, results[0].Err)
+	assert.True(t, results[1].Cancelled)
+	assert.Equal(t, 1, results[1].Changes)
+	assert.True(t, results[2].Cancelled)
+	assert.Equal(t, "target-2: cancelled after 0 changes (0s)", results[2].ToString())
+}
+
+func TestSyncCancelledCompletesOperationInProgress(t *testing.T) {
+	cred1, cred2 := credentials.NewSecretText(), credentials.NewSecretText()
+	cred1.ID = "test1"
+	cred2.ID = "test2"
+
+	config := NewConfiguration()
+	targetController, target := setTargetMock(t, config, "target", []string{}, false)
+	sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1, cred2})
+	defer targetController.Finish()
+	defer sourceController.Finish()
+
+	// The sync is cancelled (on SIGTERM for example) while the first credentials are being synced
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred1).DoAndReturn(func(operationCtx context.Context, _ credentials.Credentials) error {
+		cancel()
+		return operationCtx.Err()
+	}).Times(1)
+
+	results, err := config.Sync(ctx)
+	assert.EqualError(t, err, "1 error occurred:\n\t* The sync was interrupted before completion: context canceled\n\n")
+	assert.Len(t, results, 1)
+	assert.True(t, results[0].Cancelled)
+	assert.Equal(t, 1, results[0].Changes)
+}
+
+func TestSyncTargetTimeout(t *testing.T) {
+	cred1, cred2 := credentials.NewSecretText(), credentials.NewSecretText()
+	cred1.ID = "test1"
+	cred2.ID = "test2"
+
+	config := NewConfiguration()
+	targetController, mockedTargets := setMultipleTargetMockWithOptions(t, config, "target", []string{}, targetMockOptions{timeout: 50 * time.Millisecond}, 2)
+	sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1, cred2})
+	defer targetController.Finish()
+	defer sourceController.Finish()
+
+	for _, target := range mockedTargets {
+		target.EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	}
+
+	// The first target hangs until its timeout is reached. The second one is not affected
+	mockedTargets[0].EXPECT().UpdateCredentials(gomock.Any(), cred1).DoAndReturn(func(operationCtx context.Context, _ credentials.Credentials) error {
+		<-operationCtx.Done()
+		return operationCtx.Err()
+	}).Times(1)
+	mockedTargets[1].EXPECT().UpdateCredentials(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	results, err := config.Sync(context.Background())
+	assert.EqualError(t, err, "2 errors occurred:\n\t* Failed to send credentials with ID test1 to target-0: context deadline exceeded\n\t* Timed out while syncing credentials to target-0: 1 changes were not applied\n\n")
+	assert.Len(t, results, 2)
+	assert.Equal(t, 1, results[0].Changes)
+	assert.False(t, results[0].Cancelled)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, 2, results[1].Changes)
+}
+
+func TestValidateTimeout(t *testing.T) {
+	config := NewConfiguration()
+	config.Timeout = -time.Second
+	assert.EqualError(t, config.ValidateConfiguration(), "The global `timeout` cannot be negative")
+}
+
+func TestSyncResultsCountRetries(t *testing.T) {
+	cred1 := credentials.NewSecretText()
+	cred1.ID = "test1"
+
+	config := NewConfiguration()
+	targetController, target := setTargetMock(t, config, "target", []string{}, false)
+	sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1})
+	defer targetController.Finish()
+	defer sourceController.Finish()
+
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred1).DoAndReturn(func(ctx context.Context, _ credentials.Credentials) error {
+		retry.CountRetry(ctx)
+		retry.CountRetry(ctx)
+		return nil
+	}).Times(1)
+
+	results, err := config.Sync(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, 2, results[0].Retries)
+	assert.Regexp(t, `^target-0: succeeded with 1 changes \(.+, 2 retries\)$`, results[0].ToString())
+}
+
+func TestSyncResultsListCredentials(t *testing.T) {
+	cred1, cred2, cred3 := credentials.NewSecretText(), credentials.NewSecretText(), credentials.NewSecretText()
+	cred1.ID = "test1"
+	cred2.ID = "test2"
+	cred3.ID = "test3"
+	cred3.TargetName = "other-target"
+
+	config := NewConfiguration()
+	targetController, target := setTargetMock(t, config, "target", []string{"test1"}, false)
+	sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1, cred2, cred3})
+	defer targetController.Finish()
+	defer sourceController.Finish()
+
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred1).Times(1)
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred2).Return(fmt.Errorf("Dummy error")).Times(1)
+
+	results, err := config.Sync(context.Background())
+	assert.NotNil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, []*CredentialsResult{
+		{ID: "test1", Action: ActionUpdate},
+		{ID: "test2", Action: ActionCreate, Err: fmt.Errorf("Failed to send credentials with ID test2 to target-0: Dummy error")},
+		{ID: "test3", Action: ActionSkip},
+	}, results[0].Credentials)
 }
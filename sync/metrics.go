@@ -0,0 +1,235 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/hashicorp/go-multierror"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	defaultMetricsJob  = "credentials-sync"
+	metricsPushTimeout = 30 * time.Second
+
+	lastSuccessMetricName = "credentials_sync_target_last_success_timestamp_seconds"
+)
+
+// MetricsConfiguration defines where the Prometheus metrics of each sync are exported
+// They are either pushed to a Pushgateway or written to a file read by the textfile collector of the node exporter
+type MetricsConfiguration struct {
+	PushgatewayURL string `mapstructure:"pushgateway_url"`
+	Job            string `mapstructure:"job"`
+	Textfile       string `mapstructure:"textfile"`
+}
+
+// ValidateConfiguration verifies that the metrics destination is valid
+func (metricsConfig *MetricsConfiguration) ValidateConfiguration() error {
+	if (metricsConfig.PushgatewayURL == "") == (metricsConfig.Textfile == "") {
+		return fmt.Errorf("The metrics must define either a `pushgateway_url` or a `textfile`")
+	}
+	if metricsConfig.PushgatewayURL != "" {
+		if _, err := url.ParseRequestURI(metricsConfig.PushgatewayURL); err != nil {
+			return fmt.Errorf("Invalid metrics `pushgateway_url`: %v", err)
+		}
+	}
+	return nil
+}
+
+func (metricsConfig *MetricsConfiguration) getJob() string {
+	if metricsConfig.Job == "" {
+		return defaultMetricsJob
+	}
+	return metricsConfig.Job
+}
+
+// syncMetrics contains the metrics of a sync. The target metrics have the given target labels, plus their own labels
+type syncMetrics struct {
+	registry          *prometheus.Registry
+	sourceCredentials *prometheus.GaugeVec
+	initFailed        *prometheus.GaugeVec
+	duration          *prometheus.GaugeVec
+	operations        *prometheus.GaugeVec
+	lastSuccess       *prometheus.GaugeVec
+}
+
+func newSyncMetrics(targetLabels ...string) *syncMetrics {
+	metrics := &syncMetrics{
+		registry: prometheus.NewRegistry(),
+		sourceCredentials: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "credentials_sync_source_credentials",
+			Help: "Number of credentials fetched from each type of source during the last sync",
+		}, []string{"source"}),
+		initFailed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "credentials_sync_target_initialization_failed",
+			Help: "1 if the target failed initialization during the last sync, 0 otherwise",
+		}, targetLabels),
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "credentials_sync_target_sync_duration_seconds",
+			Help: "Duration of the last sync of the target",
+		}, targetLabels),
+		operations: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "credentials_sync_target_operations",
+			Help: "Number of operations executed on the target during the last sync, by action and outcome",
+		}, append(append([]string{}, targetLabels...), "action", "outcome")),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: lastSuccessMetricName,
+			Help: "Time of the last successful sync of the target",
+		}, targetLabels),
+	}
+	metrics.registry.MustRegister(metrics.sourceCredentials, metrics.initFailed, metrics.duration, metrics.operations, metrics.lastSuccess)
+	return metrics
+}
+
+func (metrics *syncMetrics) observeSources(credentialsList []credentials.Credentials) {
+	for _, cred := range credentialsList {
+		source := "unknown"
+		if sourced, ok := cred.(interface{ GetSource() string }); ok && sourced.GetSource() != "" {
+			source = sourced.GetSource()
+		}
+		metrics.sourceCredentials.WithLabelValues(source).Inc()
+	}
+}
+
+// observeTarget records the result of a target. The last success time is only set if the sync of the target succeeded
+func (metrics *syncMetrics) observeTarget(result *TargetResult, now time.Time, targetLabelValues ...string) {
+	initFailed := 0.0
+	if !result.Initialized {
+		initFailed = 1
+	}
+	metrics.initFailed.WithLabelValues(targetLabelValues...).Set(initFailed)
+	metrics.duration.WithLabelValues(targetLabelValues...).Set(result.Duration.Seconds())
+	// All operation series are exported, even when they are zero, so that they replace the counts of the previous sync
+	for _, operation := range operationOutcomes() {
+		metrics.operations.WithLabelValues(append(append([]string{}, targetLabelValues...), operation...)...)
+	}
+	for _, credentialsResult := range result.Credentials {
+		labelValues := append(append([]string{}, targetLabelValues...), string(credentialsResult.Action), credentialsResult.status())
+		metrics.operations.WithLabelValues(labelValues...).Inc()
+	}
+	if result.succeeded() {
+		metrics.lastSuccess.WithLabelValues(targetLabelValues...).Set(float64(now.Unix()))
+	}
+}
+
+// operationOutcomes returns the label values (action, outcome) of all the operations that can be executed on a target
+// All planned actions have a symbol, skipped credentials are the only results that are not planned
+func operationOutcomes() [][]string {
+	operations := [][]string{{string(ActionSkip), StatusSkipped}}
+	for action := range actionSymbols {
+		operations = append(operations, []string{string(action), StatusSucceeded}, []string{string(action), StatusFailed})
+	}
+	return operations
+}
+
+// exportMetrics exports the metrics of a sync, given its results, if metrics are configured
+// Failing to export the metrics does not fail the sync, the error is only logged
+func (config *Configuration) exportMetrics(ctx context.Context, credentialsList []credentials.Credentials, results []*TargetResult) {
+	if config.Metrics == nil {
+		return
+	}
+	var err error
+	if config.Metrics.PushgatewayURL != "" {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), metricsPushTimeout)
+		defer cancel()
+		err = config.Metrics.push(ctx, credentialsList, results, time.Now())
+	} else {
+		err = config.Metrics.writeTextfile(credentialsList, results, time.Now())
+	}
+	if err != nil {
+		config.getLogger().Errorf("Caught an error while exporting the metrics: %v", err)
+	}
+}
+
+// newPusher returns a pusher to the group of the job. Metrics are pushed in the text format
+func (metricsConfig *MetricsConfiguration) newPusher() *push.Pusher {
+	return push.New(metricsConfig.PushgatewayURL, metricsConfig.getJob()).Format(expfmt.NewFormat(expfmt.TypeTextPlain))
+}
+
+// push adds the metrics to the Pushgateway. The source metrics are pushed to the group of the job, and the metrics of
+// each target are pushed to a group with a `target` label. Since only the pushed metrics of a group are replaced, the
+// last success time of a target is kept when its sync fails
+// The groups of the targets that are no longer configured are not known, so they are left on the Pushgateway
+func (metricsConfig *MetricsConfiguration) push(ctx context.Context, credentialsList []credentials.Credentials, results []*TargetResult, now time.Time) error {
+	var pushErrors error
+	sourceMetrics := newSyncMetrics()
+	sourceMetrics.observeSources(credentialsList)
+	if err := metricsConfig.newPusher().Collector(sourceMetrics.sourceCredentials).AddContext(ctx); err != nil {
+		pushErrors = multierror.Append(pushErrors, fmt.Errorf("Failed to push the source metrics: %v", err))
+	}
+	for _, result := range results {
+		targetMetrics := newSyncMetrics()
+		targetMetrics.observeTarget(result, now)
+		pusher := metricsConfig.newPusher().
+			Grouping("target", result.Target).
+			Collector(targetMetrics.initFailed).
+			Collector(targetMetrics.duration).
+			Collector(targetMetrics.operations).
+			Collector(targetMetrics.lastSuccess)
+		if err := pusher.AddContext(ctx); err != nil {
+			pushErrors = multierror.Append(pushErrors, fmt.Errorf("Failed to push the metrics of %s: %v", result.Target, err))
+		}
+	}
+	return pushErrors
+}
+
+// writeTextfile writes the metrics to the textfile. The last success times of the targets whose sync failed are
+// read from the previous version of the file, and the targets that are no longer configured are dropped
+func (metricsConfig *MetricsConfiguration) writeTextfile(credentialsList []credentials.Credentials, results []*TargetResult, now time.Time) error {
+	metrics := newSyncMetrics("target")
+	metrics.observeSources(credentialsList)
+	for _, result := range results {
+		metrics.observeTarget(result, now, result.Target)
+	}
+
+	previousLastSuccess, err := readLastSuccess(metricsConfig.Textfile)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if timestamp, ok := previousLastSuccess[result.Target]; ok && !result.succeeded() {
+			metrics.lastSuccess.WithLabelValues(result.Target).Set(timestamp)
+		}
+	}
+
+	if err := prometheus.WriteToTextfile(metricsConfig.Textfile, metrics.registry); err != nil {
+		return fmt.Errorf("Failed to write the metrics textfile: %v", err)
+	}
+	return nil
+}
+
+// readLastSuccess returns the last success time of each target in the given textfile, if it exists
+func readLastSuccess(path string) (map[string]float64, error) {
+	lastSuccess := map[string]float64{}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lastSuccess, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Failed to read the metrics textfile: %v", err)
+	}
+	defer file.Close()
+
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse the metrics textfile: %v", err)
+	}
+	if family, ok := families[lastSuccessMetricName]; ok {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "target" {
+					lastSuccess[label.GetValue()] = metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return lastSuccess, nil
+}
@@ -0,0 +1,139 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/golang/mock/gomock"
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pushgatewayStandIn records the metrics pushed to each group
+type pushgatewayStandIn struct {
+	mutex  gosync.Mutex
+	pushes map[string]string
+}
+
+func newPushgatewayStandIn(t *testing.T) (*pushgatewayStandIn, string) {
+	pushgateway := &pushgatewayStandIn{pushes: map[string]string{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		pushgateway.mutex.Lock()
+		defer pushgateway.mutex.Unlock()
+		pushgateway.pushes[r.Method+" "+r.URL.Path] = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return pushgateway, server.URL
+}
+
+func TestValidateMetricsConfiguration(t *testing.T) {
+	cases := []struct {
+		name          string
+		metrics       *MetricsConfiguration
+		expectedError error
+	}{
+		{
+			name:    "Pushgateway",
+			metrics: &MetricsConfiguration{PushgatewayURL: "http://pushgateway:9091"},
+		},
+		{
+			name:    "Textfile",
+			metrics: &MetricsConfiguration{Textfile: "/var/lib/node_exporter/credentials_sync.prom"},
+		},
+		{
+			name:          "None",
+			metrics:       &MetricsConfiguration{},
+			expectedError: fmt.Errorf("The metrics must define either a `pushgateway_url` or a `textfile`"),
+		},
+		{
+			name:          "Both",
+			metrics:       &MetricsConfiguration{PushgatewayURL: "http://pushgateway:9091", Textfile: "metrics.prom"},
+			expectedError: fmt.Errorf("The metrics must define either a `pushgateway_url` or a `textfile`"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.metrics.ValidateConfiguration())
+		})
+	}
+}
+
+func TestSyncPushesMetrics(t *testing.T) {
+	pushgateway, pushgatewayURL := newPushgatewayStandIn(t)
+
+	cred1, cred2 := credentials.NewSecretText(), credentials.NewSecretText()
+	cred1.ID = "test1"
+	cred2.ID = "test2"
+	cred1.SetSource("local file")
+	cred2.SetSource("local file")
+
+	config := NewConfiguration()
+	config.Metrics = &MetricsConfiguration{PushgatewayURL: pushgatewayURL}
+	targetController, target := setTargetMock(t, config, "target", []string{}, false)
+	sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1, cred2})
+	defer targetController.Finish()
+	defer sourceController.Finish()
+
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred1).Times(1)
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred2).Return(fmt.Errorf("Dummy error")).Times(1)
+
+	_, err := config.Sync(context.Background())
+	assert.NotNil(t, err)
+
+	assert.Len(t, pushgateway.pushes, 2)
+	assert.Contains(t, pushgateway.pushes["POST /metrics/job/credentials-sync"], `credentials_sync_source_credentials{source="local file"} 2`)
+	targetMetrics := pushgateway.pushes["POST /metrics/job/credentials-sync/target/target-0"]
+	assert.Contains(t, targetMetrics, `credentials_sync_target_initialization_failed 0`)
+	assert.Contains(t, targetMetrics, `credentials_sync_target_operations{action="create",outcome="succeeded"} 1`)
+	assert.Contains(t, targetMetrics, `credentials_sync_target_operations{action="create",outcome="failed"} 1`)
+	// The operations that were not executed are pushed as zero, so that they replace the counts of the previous sync
+	assert.Contains(t, targetMetrics, `credentials_sync_target_operations{action="delete_unsynced",outcome="failed"} 0`)
+	assert.Contains(t, targetMetrics, `credentials_sync_target_operations{action="skip",outcome="skipped"} 0`)
+	assert.NotContains(t, targetMetrics, `credentials_sync_target_operations{action="skip",outcome="failed"}`)
+	assert.Contains(t, targetMetrics, `credentials_sync_target_sync_duration_seconds`)
+	// The last success time of the failed target is not pushed, so that the previous one is kept
+	assert.NotContains(t, targetMetrics, lastSuccessMetricName)
+}
+
+func TestWriteMetricsTextfile(t *testing.T) {
+	textfile := filepath.Join(t.TempDir(), "credentials_sync.prom")
+	metricsConfig := &MetricsConfiguration{Textfile: textfile}
+	firstSync := time.Unix(1700000000, 0)
+	cred1 := credentials.NewSecretText()
+	cred1.SetSource("local file")
+
+	require.NoError(t, metricsConfig.writeTextfile([]credentials.Credentials{cred1}, []*TargetResult{
+		{Target: "jenkins", Initialized: true, Changes: 1, Credentials: []*CredentialsResult{{ID: "test1", Action: ActionUpdate}}},
+		{Target: "github", Initialized: true},
+		{Target: "removed", Initialized: true},
+	}, firstSync))
+
+	// On the next sync, jenkins fails, github fails initialization and the removed target is no longer configured
+	require.NoError(t, metricsConfig.writeTextfile([]credentials.Credentials{cred1}, []*TargetResult{
+		{Target: "jenkins", Initialized: true, Err: multierror.Append(nil, fmt.Errorf("Dummy error")), Credentials: []*CredentialsResult{{ID: "test1", Action: ActionUpdate, Err: fmt.Errorf("Dummy error")}}},
+		{Target: "github", Err: fmt.Errorf("Dummy error")},
+	}, firstSync.Add(time.Hour)))
+
+	content, err := os.ReadFile(textfile)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `credentials_sync_source_credentials{source="local file"} 1`)
+	assert.Contains(t, string(content), `credentials_sync_target_initialization_failed{target="github"} 1`)
+	assert.Contains(t, string(content), `credentials_sync_target_operations{action="update",outcome="failed",target="jenkins"} 1`)
+	assert.Contains(t, string(content), `credentials_sync_target_operations{action="update",outcome="succeeded",target="github"} 0`)
+	assert.Contains(t, string(content), `credentials_sync_target_last_success_timestamp_seconds{target="github"} 1.7e+09`)
+	assert.Contains(t, string(content), `credentials_sync_target_last_success_timestamp_seconds{target="jenkins"} 1.7e+09`)
+	assert.NotContains(t, string(content), `target="removed"`)
+}
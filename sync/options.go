@@ -0,0 +1,198 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/logger"
+	"github.com/coveooss/credentials-sync/retry"
+	"github.com/coveooss/credentials-sync/targets"
+)
+
+// EventType identifies the step of the sync that an event reports
+type EventType string
+
+const (
+	// EventCredentialsFetched is emitted once the credentials are fetched from the sources, or failed to be
+	EventCredentialsFetched EventType = "credentials_fetched"
+	// EventTargetInitialized is emitted when the initialization of a target succeeds or fails
+	EventTargetInitialized EventType = "target_initialized"
+	// EventChangeApplied is emitted after each change that is applied on a target, whether it succeeds or not
+	EventChangeApplied EventType = "change_applied"
+	// EventTargetSynced is emitted when the sync of a target is over
+	EventTargetSynced EventType = "target_synced"
+)
+
+// Event reports the progress of a sync. Only the fields that are relevant to its type are set
+type Event struct {
+	Type   EventType
+	Target string
+	ID     string
+	Action Action
+	Err    error
+}
+
+// emit calls the event handler of the sync, if one is set
+func (config *Configuration) emit(event Event) {
+	if config.eventHandler == nil {
+		return
+	}
+	config.eventMutex.Lock()
+	defer config.eventMutex.Unlock()
+	config.eventHandler(event)
+}
+
+// Option configures a sync created with New
+type Option func(*Configuration)
+
+// New creates a sync that is configured only by the given options, for use as a library
+// Unlike a configuration read by the CLI, it logs nothing unless a logger is given with WithLogger
+func New(options ...Option) *Configuration {
+	config := NewConfiguration()
+	config.log = logger.Discard
+	config.Sources = credentials.NewSourcesConfiguration()
+	config.Targets = targets.NewConfiguration()
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+// WithSources adds sources from which the credentials are fetched
+func WithSources(sources ...credentials.Source) Option {
+	return func(config *Configuration) {
+		if sourcesConfiguration, ok := config.Sources.(*credentials.SourcesConfiguration); ok {
+			sourcesConfiguration.AddSources(sources...)
+		} else {
+			config.Sources = credentials.NewSourcesConfiguration(sources...)
+		}
+	}
+}
+
+// WithTargets adds targets to which the credentials are synced
+func WithTargets(targetsToAdd ...targets.Target) Option {
+	return func(config *Configuration) {
+		if targetsConfiguration, ok := config.Targets.(*targets.Configuration); ok {
+			targetsConfiguration.AddTargets(targetsToAdd...)
+		} else {
+			config.Targets = targets.NewConfiguration(targetsToAdd...)
+		}
+	}
+}
+
+// WithLogger sets the logger of the sync. It is also used by the sources and targets for their retries
+// The logger only receives redacted messages, in which the secrets of the credentials are replaced
+func WithLogger(log logger.Logger) Option {
+	return func(config *Configuration) {
+		config.log = log
+	}
+}
+
+// WithEventHandler sets a function that is called on each event of the sync
+// Events are emitted from multiple goroutines, but the calls to the handler are serialized
+func WithEventHandler(handler func(Event)) Option {
+	return func(config *Configuration) {
+		config.eventHandler = handler
+	}
+}
+
+// WithStopOnError stops the sync on the first error, as the `stop_on_error` option does
+func WithStopOnError(stopOnError bool) Option {
+	return func(config *Configuration) {
+		config.StopOnError = stopOnError
+	}
+}
+
+// WithTargetParallelism sets the number of targets that are synced at the same time
+func WithTargetParallelism(parallelism int) Option {
+	return func(config *Configuration) {
+		config.TargetParallelism = parallelism
+	}
+}
+
+// WithTimeout sets the global timeout of the sync. Zero means no timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(config *Configuration) {
+		config.Timeout = timeout
+	}
+}
+
+// WithRetryPolicy sets the default retry policy of the sources and targets
+func WithRetryPolicy(policy retry.Policy) Option {
+	return func(config *Configuration) {
+		config.Retry = policy
+	}
+}
+
+// WithDeletionLimits sets the global limits on the number of credentials deleted from each target
+func WithDeletionLimits(limits targets.DeletionLimits) Option {
+	return func(config *Configuration) {
+		config.DeletionLimits = limits
+	}
+}
+
+// WithCredentialsToDelete sets the IDs of the credentials to delete from all targets
+func WithCredentialsToDelete(ids ...string) Option {
+	return func(config *Configuration) {
+		config.CredentialsToDelete = append(config.CredentialsToDelete, ids...)
+	}
+}
+
+// WithState sets where the fingerprints of the synced credentials are stored between syncs
+func WithState(state *StateConfiguration) Option {
+	return func(config *Configuration) {
+		config.State = state
+	}
+}
+
+// WithMetrics exports the Prometheus metrics of each sync to a Pushgateway or a textfile
+func WithMetrics(metrics *MetricsConfiguration) Option {
+	return func(config *Configuration) {
+		config.Metrics = metrics
+	}
+}
+
+// WithFullSync syncs all credentials, even those that did not change since the last sync
+func WithFullSync(fullSync bool) Option {
+	return func(config *Configuration) {
+		config.FullSync = fullSync
+	}
+}
+
+// Result contains the outcome of a sync for each target, in the order of the targets
+type Result struct {
+	Targets []*TargetResult
+}
+
+// Failed returns true if the sync of any target failed
+func (result *Result) Failed() bool {
+	for _, targetResult := range result.Targets {
+		if targetResult.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Run validates the configuration, then syncs the credentials from the sources to the targets
+// The result is returned even if the sync fails, unless the configuration is invalid or the credentials cannot be fetched
+func (config *Configuration) Run(ctx context.Context) (*Result, error) {
+	if err := config.ValidateConfiguration(); err != nil {
+		return nil, fmt.Errorf("Invalid configuration: %v", err)
+	}
+	if err := config.Sources.ValidateConfiguration(); err != nil {
+		return nil, fmt.Errorf("Invalid sources: %v", err)
+	}
+	if err := config.Targets.ValidateConfiguration(); err != nil {
+		return nil, fmt.Errorf("Invalid targets: %v", err)
+	}
+	// The sensitive values of the credentials are only kept for this run
+	config.sensitiveValues = logger.NewSensitiveValues()
+	results, err := config.Sync(ctx)
+	if results == nil {
+		return nil, config.sensitiveValues.RedactError(err)
+	}
+	return &Result{Targets: results}, config.sensitiveValues.RedactError(err)
+}
@@ -0,0 +1,198 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/logger"
+	"github.com/coveooss/credentials-sync/targets"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAppliesOptions(t *testing.T) {
+	source := &credentials.LocalSource{File: "creds.yaml"}
+	target := &targets.FileTarget{Base: targets.Base{Name: "file"}, Path: "out.json", Format: "json"}
+	config := New(
+		WithSources(source),
+		WithTargets(target),
+		WithStopOnError(true),
+		WithTargetParallelism(2),
+		WithCredentialsToDelete("old"),
+		WithFullSync(true),
+	)
+
+	assert.Equal(t, []credentials.Source{source}, config.Sources.AllSources())
+	assert.Equal(t, []targets.Target{target}, config.Targets.AllTargets())
+	assert.True(t, config.StopOnError)
+	assert.Equal(t, 2, config.TargetParallelism)
+	assert.Equal(t, []string{"old"}, config.CredentialsToDelete)
+	assert.True(t, config.FullSync)
+}
+
+func TestRunSyncsSourcesToTargets(t *testing.T) {
+	dir := t.TempDir()
+	sourceFile := filepath.Join(dir, "creds.yaml")
+	require.NoError(t, os.WriteFile(sourceFile, []byte(`
+first:
+  type: secret
+  secret: first-secret
+second:
+  type: secret
+  secret: second-secret
+`), 0600))
+	targetFile := filepath.Join(dir, "out.json")
+
+	events := []Event{}
+	config := New(
+		WithSources(&credentials.LocalSource{File: sourceFile}),
+		WithTargets(&targets.FileTarget{Base: targets.Base{Name: "file"}, Path: targetFile, Format: "json"}),
+		WithEventHandler(func(event Event) { events = append(events, event) }),
+	)
+
+	result, err := config.Run(context.Background())
+	require.NoError(t, err)
+	assert.False(t, result.Failed())
+	require.Len(t, result.Targets, 1)
+	assert.Equal(t, "file", result.Targets[0].Target)
+	assert.ElementsMatch(t, []*CredentialsResult{
+		{ID: "first", Action: ActionCreate},
+		{ID: "second", Action: ActionCreate},
+	}, result.Targets[0].Credentials)
+	assert.FileExists(t, targetFile)
+
+	require.Len(t, events, 5)
+	assert.Equal(t, Event{Type: EventCredentialsFetched}, events[0])
+	assert.Equal(t, Event{Type: EventTargetInitialized, Target: "file"}, events[1])
+	assert.Equal(t, EventChangeApplied, events[2].Type)
+	assert.Equal(t, EventChangeApplied, events[3].Type)
+	assert.Equal(t, Event{Type: EventTargetSynced, Target: "file"}, events[4])
+}
+
+func TestRunFetchesTheCurrentCredentials(t *testing.T) {
+	dir := t.TempDir()
+	sourceFile := filepath.Join(dir, "creds.yaml")
+	targetFile := filepath.Join(dir, "out.json")
+	config := New(
+		WithSources(&credentials.LocalSource{File: sourceFile}),
+		WithTargets(&targets.FileTarget{Base: targets.Base{Name: "file"}, Path: targetFile, Format: "json"}),
+	)
+
+	for _, secret := range []string{"first-secret", "second-secret"} {
+		require.NoError(t, os.WriteFile(sourceFile, []byte("first:\n  type: secret\n  secret: "+secret+"\n"), 0600))
+		_, err := config.Run(context.Background())
+		require.NoError(t, err)
+		content, err := os.ReadFile(targetFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), secret)
+	}
+}
+
+func TestRunDeletesCredentialsMissingFromTheSources(t *testing.T) {
+	dir := t.TempDir()
+	sourceFile := filepath.Join(dir, "creds.yaml")
+	require.NoError(t, os.WriteFile(sourceFile, []byte("first:\n  type: secret\n  secret: first-secret\n"), 0600))
+	targetFile := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(targetFile, []byte("MY_CRED_USERNAME=user\nMY_CRED_PASSWORD=pass\nOTHER=other\n"), 0600))
+
+	// The variables of `my-cred` are found from its ID, although it is not in the sources anymore
+	config := New(
+		WithSources(&credentials.LocalSource{File: sourceFile}),
+		WithTargets(&targets.FileTarget{Base: targets.Base{Name: "file"}, Path: targetFile, Format: "dotenv"}),
+		WithCredentialsToDelete("my-cred"),
+	)
+	result, err := config.Run(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, result.Targets[0].Credentials, &CredentialsResult{ID: "my-cred", Action: ActionDelete})
+
+	content, err := os.ReadFile(targetFile)
+	require.NoError(t, err)
+	assert.Equal(t, "FIRST=\"first-secret\"\nOTHER=\"other\"\n", string(content))
+}
+
+func TestWithLoggerRedactsMessages(t *testing.T) {
+	var output bytes.Buffer
+	injected := logrus.New()
+	injected.SetOutput(&output)
+
+	config := New(WithLogger(injected))
+	config.sensitiveValues = logger.NewSensitiveValues()
+	config.sensitiveValues.Add("with-logger-secret")
+	ctx := logger.WithSensitiveValues(context.Background(), config.sensitiveValues)
+	config.getLogger().Errorf("Failed to send %s", "with-logger-secret")
+	logger.FromContext(logger.WithLogger(ctx, config.getLogger())).Infof("Retrying with-logger-secret")
+
+	assert.Contains(t, output.String(), "Failed to send ********")
+	assert.Contains(t, output.String(), "Retrying ********")
+	assert.NotContains(t, output.String(), "with-logger-secret")
+}
+
+func TestRunScopesTheSensitiveValues(t *testing.T) {
+	sourceFile := filepath.Join(t.TempDir(), "creds.yaml")
+	require.NoError(t, os.WriteFile(sourceFile, []byte("test1:\n  type: secret\n  secret: run-scoped-secret\n"), 0600))
+	var output bytes.Buffer
+	injected := logrus.New()
+	injected.SetOutput(&output)
+
+	config := New(WithSources(&credentials.LocalSource{File: sourceFile}), WithLogger(injected))
+	targetCtrl, target := setTargetMock(t, config, "target", []string{}, false)
+	defer targetCtrl.Finish()
+	config.Targets.(*targets.MockTargetCollection).EXPECT().ValidateConfiguration().Return(nil)
+	target.EXPECT().UpdateCredentials(gomock.Any(), gomock.Any()).Return(fmt.Errorf("invalid run-scoped-secret"))
+
+	_, err := config.Run(context.Background())
+	assert.ErrorContains(t, err, "invalid ********")
+	assert.Contains(t, output.String(), "invalid ********")
+	assert.NotContains(t, output.String(), "run-scoped-secret")
+
+	// The values of the run are not registered in the default set
+	assert.Equal(t, "run-scoped-secret", logger.Redact("run-scoped-secret"))
+}
+
+func TestRunReportsFailedCredentials(t *testing.T) {
+	cred1 := credentials.NewSecretText()
+	cred1.ID = "test1"
+
+	config := New()
+	sourceCtrl, sourceCollection := setSourceMock(t, config, []credentials.Credentials{cred1})
+	defer sourceCtrl.Finish()
+	sourceCollection.EXPECT().ValidateConfiguration().Return(nil)
+	targetCtrl, target := setTargetMock(t, config, "target", []string{}, false)
+	defer targetCtrl.Finish()
+	config.Targets.(*targets.MockTargetCollection).EXPECT().ValidateConfiguration().Return(nil)
+	target.EXPECT().UpdateCredentials(gomock.Any(), gomock.Any()).Return(fmt.Errorf("update failed"))
+
+	var failedChange Event
+	WithEventHandler(func(event Event) {
+		if event.Type == EventChangeApplied {
+			failedChange = event
+		}
+	})(config)
+
+	result, err := config.Run(context.Background())
+	assert.Error(t, err)
+	require.NotNil(t, result)
+	assert.True(t, result.Failed())
+	require.Len(t, result.Targets[0].Credentials, 1)
+	assert.Equal(t, "test1", result.Targets[0].Credentials[0].ID)
+	assert.Equal(t, ActionCreate, result.Targets[0].Credentials[0].Action)
+	assert.EqualError(t, result.Targets[0].Credentials[0].Err, "Failed to send credentials with ID test1 to target-0: update failed")
+	assert.Equal(t, Event{Type: EventChangeApplied, Target: "target-0", ID: "test1", Action: ActionCreate, Err: result.Targets[0].Credentials[0].Err}, failedChange)
+}
+
+func TestRunValidatesConfiguration(t *testing.T) {
+	config := New(
+		WithTargets(&targets.FileTarget{Base: targets.Base{Name: "file"}, Format: "json"}),
+	)
+
+	result, err := config.Run(context.Background())
+	assert.Nil(t, result)
+	assert.ErrorContains(t, err, "the file target `file` must define a `path`")
+}
@@ -0,0 +1,399 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/logger"
+	"github.com/coveooss/credentials-sync/targets"
+	"github.com/hashicorp/go-multierror"
+)
+
+// Action represents an operation that the sync process executes on a target's credentials
+type Action string
+
+const (
+	// ActionCreate means that the credentials do not exist on the target and will be created
+	ActionCreate Action = "create"
+	// ActionUpdate means that the credentials already exist on the target and will be overwritten
+	ActionUpdate Action = "update"
+	// ActionUnchanged means that the credentials already exist on the target and have not changed since the last sync
+	ActionUnchanged Action = "unchanged"
+	// ActionKeep means that the credentials exist on the target but not in the sources. They are left alone
+	ActionKeep Action = "keep"
+	// ActionTagUnsynced means that the credentials exist on the target but not in the sources and `tag_unsynced` is set
+	// Their description will be tagged with the time at which they were first seen as unsynced
+	ActionTagUnsynced Action = "tag_unsynced"
+	// ActionDelete means that the credentials will be deleted because they are listed in `credentials_to_delete`
+	ActionDelete Action = "delete"
+	// ActionDeleteUnsynced means that the credentials will be deleted because they are not in the sources and `delete_unsynced` is set
+	ActionDeleteUnsynced Action = "delete_unsynced"
+	// ActionSkip means that the credentials are not synced to the target because of their target matching attributes
+	// It is never planned, it only appears in the results of a sync
+	ActionSkip Action = "skip"
+)
+
+var actionSymbols = map[Action]string{
+	ActionCreate:         "+",
+	ActionUpdate:         "~",
+	ActionUnchanged:      " ",
+	ActionKeep:           "=",
+	ActionTagUnsynced:    "*",
+	ActionDelete:         "-",
+	ActionDeleteUnsynced: "-",
+}
+
+// Change represents a single operation planned on a target
+type Change struct {
+	Action      Action                  `json:"action"`
+	ID          string                  `json:"id"`
+	Credentials credentials.Credentials `json:"-"`
+	Fingerprint string                  `json:"-"`
+}
+
+// TargetPlan lists all the changes that a sync would execute on a target
+type TargetPlan struct {
+	Target  string   `json:"target"`
+	Error   string   `json:"error,omitempty"`
+	Changes []Change `json:"changes"`
+}
+
+// Count returns the number of planned changes with the given action
+func (plan *TargetPlan) Count(action Action) int {
+	count := 0
+	for _, change := range plan.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// ToString prints out a human readable description of the plan
+func (plan *TargetPlan) ToString() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s:\n", plan.Target)
+	if plan.Error != "" {
+		fmt.Fprintf(&builder, "  ! %s\n", plan.Error)
+		if len(plan.Changes) == 0 {
+			return builder.String()
+		}
+	}
+	if len(plan.Changes) == 0 {
+		builder.WriteString("  No credentials\n")
+	}
+	for _, change := range plan.Changes {
+		fmt.Fprintf(&builder, "  %s %-16s %s\n", actionSymbols[change.Action], change.Action, change.ID)
+	}
+	fmt.Fprintf(&builder, "  %d to create, %d to update, %d unchanged, %d to keep, %d to tag, %d to delete\n",
+		plan.Count(ActionCreate),
+		plan.Count(ActionUpdate),
+		plan.Count(ActionUnchanged),
+		plan.Count(ActionKeep),
+		plan.Count(ActionTagUnsynced),
+		plan.Count(ActionDelete)+plan.Count(ActionDeleteUnsynced),
+	)
+	return builder.String()
+}
+
+// Plan computes the changes that a sync would execute on all targets without modifying them
+func (config *Configuration) Plan(ctx context.Context) ([]*TargetPlan, error) {
+	ctx, cancel := withTimeout(logger.WithLogger(ctx, config.getLogger()), config.Timeout)
+	defer cancel()
+
+	creds, err := config.fetchCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.loadState(); err != nil {
+		return nil, err
+	}
+
+	plans := []*TargetPlan{}
+	validTargets, errorAccumulator := config.initTargets(ctx, creds)
+	if errorAccumulator != nil && config.StopOnError {
+		return nil, errorAccumulator
+	}
+	for _, initError := range initFailures(errorAccumulator) {
+		plans = append(plans, &TargetPlan{Target: initError.target, Error: initError.Error()})
+	}
+	for _, target := range validTargets {
+		plan := config.PlanTarget(ctx, target, creds)
+		if err := config.CheckDeletionLimits(target, plan); err != nil {
+			if config.StopOnError {
+				return nil, err
+			}
+			errorAccumulator = multierror.Append(errorAccumulator, err)
+			plan.Error = err.Error()
+		}
+		plans = append(plans, plan)
+	}
+	return plans, errorAccumulator
+}
+
+// PlanTarget computes the changes that a sync would execute on the given target
+func (config *Configuration) PlanTarget(ctx context.Context, target targets.Target, credentialsList []credentials.Credentials) *TargetPlan {
+	plan := &TargetPlan{Target: target.GetName()}
+	plan.Changes = append(plan.Changes, config.PlanUpdates(ctx, target, filterCredentials(target, credentialsList))...)
+	plan.Changes = append(plan.Changes, config.PlanDeletions(target)...)
+	return plan
+}
+
+// PlanUpdates computes the changes needed to sync the given list of credentials to the given target
+// This includes the handling of credentials that exist on the target but are not in the given list
+// If a state is loaded, credentials whose fingerprint did not change since the last sync are left unchanged
+func (config *Configuration) PlanUpdates(ctx context.Context, target targets.Target, listOfCredentials []credentials.Credentials) []Change {
+	isSynced := func(id string) bool {
+		for _, credentials := range listOfCredentials {
+			if credentials.GetTargetID() == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	changes := []Change{}
+	for _, credentials := range listOfCredentials {
+		change := Change{Action: ActionCreate, ID: credentials.GetTargetID(), Credentials: credentials}
+		if config.state != nil {
+			fingerprint, err := targets.Fingerprint(target, credentials)
+			if err != nil {
+				config.getLogger().Warningf("[%s] Unable to compute the fingerprint of %s: %v", target.GetName(), change.ID, err)
+			}
+			change.Fingerprint = fingerprint
+		}
+		if targets.HasCredential(target, change.ID) {
+			change.Action = ActionUpdate
+			if change.Fingerprint != "" && change.Fingerprint == config.state.GetFingerprint(target.GetName(), change.ID) {
+				change.Action = ActionUnchanged
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	for _, existingID := range target.GetExistingCredentials() {
+		if !isSynced(existingID) {
+			action := ActionKeep
+			_, isProtected := targets.GetProtectingPattern(target, existingID)
+			if target.ShouldDeleteUnsynced() && !isProtected && config.isOwned(ctx, target, existingID) {
+				action = ActionDeleteUnsynced
+			} else if target.ShouldTagUnsynced() {
+				action = ActionTagUnsynced
+			}
+			changes = append(changes, Change{Action: action, ID: existingID})
+		}
+	}
+	return changes
+}
+
+// PlanDeletions computes the changes needed to remove the configured list of credentials from the given target
+func (config *Configuration) PlanDeletions(target targets.Target) []Change {
+	changes := []Change{}
+	for _, id := range config.CredentialsToDelete {
+		if targets.HasCredential(target, id) {
+			changes = append(changes, Change{Action: ActionDelete, ID: id})
+		}
+	}
+	return changes
+}
+
+// CheckDeletionLimits returns an error if the given plan deletes more credentials than the target or global limits allow
+// Protected credentials are not counted, since they are never deleted
+func (config *Configuration) CheckDeletionLimits(target targets.Target, plan *TargetPlan) error {
+	deletedIDs := map[string]bool{}
+	for _, change := range plan.Changes {
+		if change.Action != ActionDelete && change.Action != ActionDeleteUnsynced {
+			continue
+		}
+		if _, isProtected := targets.GetProtectingPattern(target, change.ID); !isProtected {
+			deletedIDs[change.ID] = true
+		}
+	}
+	existing := len(target.GetExistingCredentials())
+	for _, limits := range []targets.DeletionLimits{target.GetDeletionLimits(), config.DeletionLimits} {
+		if err := limits.Check(len(deletedIDs), existing); err != nil {
+			return fmt.Errorf("Aborting the sync of %s: %v", target.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// ApplyChanges executes the given changes on the given target, until the given context is cancelled
+func (config *Configuration) ApplyChanges(ctx context.Context, target targets.Target, changes []Change) error {
+	_, err := config.applyChanges(ctx, target, changes, nil)
+	return err
+}
+
+// applyChanges executes the given changes on the given target, until the given context is cancelled
+// The change in progress is completed even if the context is cancelled, unless its deadline is reached
+// The outcome of each change is added to the given result, if it is set. It returns the number of changes that were processed
+// If the target buffers its changes, they are flushed once all the changes are processed
+func (config *Configuration) applyChanges(ctx context.Context, target targets.Target, changes []Change, result *TargetResult) (int, error) {
+	processed, err := config.applyEachChange(ctx, target, changes, result)
+	if buffer, ok := target.(targets.ChangesBuffer); ok {
+		if flushErr := config.flushChanges(ctx, target, buffer, changes[:processed], result); flushErr != nil {
+			if !config.StopOnError {
+				err = multierror.Append(err, flushErr)
+			} else if err == nil {
+				err = flushErr
+			}
+		}
+	}
+	return processed, err
+}
+
+// flushChanges applies the changes buffered by the given target
+// If it fails, the given changes are reported as failed and the fingerprints of the synced credentials are forgotten
+func (config *Configuration) flushChanges(ctx context.Context, target targets.Target, buffer targets.ChangesBuffer, changes []Change, result *TargetResult) error {
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	err := buffer.FlushChanges(ctx)
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("Failed to write the changes to %s: %v", target.GetName(), err)
+	config.getLogger().Errorf("%v", err)
+	for i, change := range changes {
+		if change.Action == ActionUnchanged || change.Action == ActionKeep {
+			continue
+		}
+		if change.Action == ActionCreate || change.Action == ActionUpdate {
+			config.forgetFingerprint(target, change.ID)
+		}
+		if result != nil && result.Credentials[i].Err == nil {
+			result.Credentials[i].Err = err
+		}
+	}
+	return err
+}
+
+// applyEachChange executes the given changes one by one on the given target (see applyChanges)
+func (config *Configuration) applyEachChange(ctx context.Context, target targets.Target, changes []Change, result *TargetResult) (int, error) {
+	// We will use this to accumulate errors that happen if config.StopOnError is set to false
+	// the multierror.Error implements error so we use the interface to type the accumulator
+	var errorAccumulator error
+	loggedUnsyncedDeletion := false
+	for i, change := range changes {
+		if ctx.Err() != nil || deadlineExceeded(ctx) {
+			return i, errorAccumulator
+		}
+		if change.Action == ActionDeleteUnsynced && !loggedUnsyncedDeletion {
+			config.getLogger().Debugf("Deleting unsynced credentials from %v", target.GetName())
+			loggedUnsyncedDeletion = true
+		}
+		err := config.applyChange(ctx, target, change)
+		if result != nil {
+			result.Credentials = append(result.Credentials, &CredentialsResult{ID: change.ID, Action: change.Action, Err: err})
+		}
+		config.emit(Event{Type: EventChangeApplied, Target: target.GetName(), ID: change.ID, Action: change.Action, Err: err})
+		if err != nil {
+			if config.StopOnError {
+				return i + 1, err
+			}
+			errorAccumulator = multierror.Append(errorAccumulator, err)
+			config.getLogger().Errorf("%v", err)
+		}
+	}
+	return len(changes), errorAccumulator
+}
+
+// applyChange executes a single change on the given target
+func (config *Configuration) applyChange(ctx context.Context, target targets.Target, change Change) (err error) {
+	ctx, cancel := operationContext(ctx)
+	defer cancel()
+
+	switch change.Action {
+	case ActionCreate, ActionUpdate:
+		config.getLogger().Infof("[%s] Syncing %s", target.GetName(), change.ID)
+		if err = target.UpdateCredentials(ctx, change.Credentials); err != nil {
+			err = fmt.Errorf("Failed to send credentials with ID %s to %s: %v", change.ID, target.GetName(), err)
+		} else if config.state != nil && change.Fingerprint != "" {
+			config.state.SetFingerprint(target.GetName(), change.ID, change.Fingerprint)
+		}
+	case ActionUnchanged:
+		config.getLogger().Debugf("[%s] %s is unchanged since the last sync. Skipping it", target.GetName(), change.ID)
+	case ActionKeep:
+		config.getLogger().Infof("[%s] %s is unsynced. Not modifying it", target.GetName(), change.ID)
+		config.forgetFingerprint(target, change.ID)
+	case ActionTagUnsynced:
+		config.forgetFingerprint(target, change.ID)
+		if err = config.tagUnsynced(ctx, target, change.ID); err != nil {
+			err = fmt.Errorf("Failed to tag credentials with ID %s on %s as unsynced: %v", change.ID, target.GetName(), err)
+		}
+	case ActionDelete, ActionDeleteUnsynced:
+		if pattern, isProtected := targets.GetProtectingPattern(target, change.ID); isProtected {
+			return fmt.Errorf("Refusing to delete credentials with ID %s from %s: they match the protected credentials pattern `%s`", change.ID, target.GetName(), pattern)
+		}
+		config.getLogger().Infof("[%s] Deleting %s", target.GetName(), change.ID)
+		if err = target.DeleteCredentials(ctx, change.ID); err != nil {
+			err = fmt.Errorf("Failed to delete credentials with ID %s from %s: %v", change.ID, target.GetName(), err)
+		} else {
+			config.forgetFingerprint(target, change.ID)
+		}
+	}
+	return err
+}
+
+// isOwned returns true if the given credentials can be deleted from the target when they are unsynced
+// If the target only deletes marked credentials, the description of the credentials must contain the ownership marker
+func (config *Configuration) isOwned(ctx context.Context, target targets.Target, id string) bool {
+	if !target.ShouldDeleteOnlyMarked() {
+		return true
+	}
+	description, err := target.GetCredentialsDescription(ctx, id)
+	if err != nil {
+		config.getLogger().Warningf("[%s] Unable to verify the ownership of %s, it will not be deleted: %v", target.GetName(), id, err)
+		return false
+	}
+	return targets.IsMarked(target, description)
+}
+
+// tagUnsynced tags the description of the given credentials with the time at which they were first seen as unsynced
+// The tag is removed when the credentials are synced again, since their description is then overwritten
+func (config *Configuration) tagUnsynced(ctx context.Context, target targets.Target, id string) error {
+	description, err := target.GetCredentialsDescription(ctx, id)
+	if err != nil {
+		return err
+	}
+	taggedDescription := targets.TagUnsynced(description, time.Now())
+	if taggedDescription == description {
+		config.getLogger().Debugf("[%s] %s is already tagged as unsynced", target.GetName(), id)
+		return nil
+	}
+	config.getLogger().Infof("[%s] Tagging %s as unsynced", target.GetName(), id)
+	return target.SetCredentialsDescription(ctx, id, taggedDescription)
+}
+
+// forgetFingerprint removes credentials that are no longer managed from the state
+// This ensures that they are fully synced if they ever become managed again
+func (config *Configuration) forgetFingerprint(target targets.Target, id string) {
+	if config.state != nil {
+		config.state.DeleteFingerprint(target.GetName(), id)
+	}
+}
+
+// skippedCredentials returns the results of the credentials that are not synced to the given target (see filterCredentials)
+func skippedCredentials(target targets.Target, credentialsList []credentials.Credentials) []*CredentialsResult {
+	skipped := []*CredentialsResult{}
+	for _, cred := range credentialsList {
+		if !cred.ShouldSync(target.GetName(), target.GetTags()) {
+			skipped = append(skipped, &CredentialsResult{ID: cred.GetTargetID(), Action: ActionSkip})
+		}
+	}
+	return skipped
+}
+
+func filterCredentials(target targets.Target, credentialsList []credentials.Credentials) []credentials.Credentials {
+	filteredCredentials := []credentials.Credentials{}
+	for _, cred := range credentialsList {
+		if cred.ShouldSync(target.GetName(), target.GetTags()) {
+			filteredCredentials = append(filteredCredentials, cred)
+		}
+	}
+	return filteredCredentials
+}
@@ -0,0 +1,109 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanTarget(t *testing.T) {
+	cred1, cred2 := credentials.NewSecretText(), credentials.NewSecretText()
+	cred1.ID = "test1"
+	cred2.ID = "test2"
+
+	cases := []struct {
+		name                 string
+		existingCredentials  []string
+		shouldDeleteUnsynced bool
+		credentialsToDelete  []string
+		expected             []Change
+	}{
+		{
+			name:                "Create and update",
+			existingCredentials: []string{"test1"},
+			expected: []Change{
+				{Action: ActionUpdate, ID: "test1", Credentials: cred1},
+				{Action: ActionCreate, ID: "test2", Credentials: cred2},
+			},
+		},
+		{
+			name:                "Keep unsynced",
+			existingCredentials: []string{"test3"},
+			expected: []Change{
+				{Action: ActionCreate, ID: "test1", Credentials: cred1},
+				{Action: ActionCreate, ID: "test2", Credentials: cred2},
+				{Action: ActionKeep, ID: "test3"},
+			},
+		},
+		{
+			name:                 "Delete unsynced",
+			existingCredentials:  []string{"test1", "test3"},
+			shouldDeleteUnsynced: true,
+			expected: []Change{
+				{Action: ActionUpdate, ID: "test1", Credentials: cred1},
+				{Action: ActionCreate, ID: "test2", Credentials: cred2},
+				{Action: ActionDeleteUnsynced, ID: "test3"},
+			},
+		},
+		{
+			name:                "Delete configured list",
+			existingCredentials: []string{"test3"},
+			credentialsToDelete: []string{"test3", "test-not-exist"},
+			expected: []Change{
+				{Action: ActionCreate, ID: "test1", Credentials: cred1},
+				{Action: ActionCreate, ID: "test2", Credentials: cred2},
+				{Action: ActionKeep, ID: "test3"},
+				{Action: ActionDelete, ID: "test3"},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Configuration{CredentialsToDelete: tt.credentialsToDelete}
+			targetController, target := setTargetMock(t, config, "target", tt.existingCredentials, tt.shouldDeleteUnsynced)
+			defer targetController.Finish()
+
+			// No calls to UpdateCredentials or DeleteCredentials are expected
+			plan := config.PlanTarget(context.Background(), target, []credentials.Credentials{cred1, cred2})
+			assert.Equal(t, "target-0", plan.Target)
+			assert.Equal(t, tt.expected, plan.Changes)
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	cred1 := credentials.NewSecretText()
+	cred1.ID = "test1"
+
+	config := &Configuration{StopOnError: false}
+	targetController, targets := setMultipleTargetMock(t, config, "target", []string{"test1", "test2"}, true, 2)
+	sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1})
+	defer targetController.Finish()
+	defer sourceController.Finish()
+
+	targets[0].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(fmt.Errorf("Dummy error")).AnyTimes()
+	targets[1].EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	plans, err := config.Plan(context.Background())
+	assert.EqualError(t, err, "1 error occurred:\n\t* Target `target-0` has failed initialization: Dummy error\n\n")
+	assert.Equal(t, []*TargetPlan{
+		{Target: "target-0", Error: "Target `target-0` has failed initialization: Dummy error"},
+		{Target: "target-1", Changes: []Change{
+			{Action: ActionUpdate, ID: "test1", Credentials: cred1},
+			{Action: ActionDeleteUnsynced, ID: "test2"},
+		}},
+	}, plans)
+
+	assert.Equal(t, "target-1:\n  ~ update           test1\n  - delete_unsynced  test2\n  0 to create, 1 to update, 0 unchanged, 0 to keep, 0 to tag, 1 to delete\n", plans[1].ToString())
+	assert.Equal(t, "target-0:\n  ! Target `target-0` has failed initialization: Dummy error\n", plans[0].ToString())
+
+	jsonPlan, err := json.Marshal(plans[1])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"target": "target-1", "changes": [{"action": "update", "id": "test1"}, {"action": "delete_unsynced", "id": "test2"}]}`, string(jsonPlan))
+}
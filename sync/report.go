@@ -0,0 +1,210 @@
+package sync
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/coveooss/credentials-sync/logger"
+)
+
+// Statuses of the targets and credentials in a report
+const (
+	StatusSucceeded            = "succeeded"
+	StatusFailed               = "failed"
+	StatusCancelled            = "cancelled"
+	StatusFailedInitialization = "failed_initialization"
+	StatusSkipped              = "skipped"
+)
+
+// Report is a machine-readable summary of a sync, built from the results of its targets
+// The errors it contains are redacted, so that it can be published as a build artifact
+type Report struct {
+	Status  string          `json:"status"`
+	Error   string          `json:"error,omitempty"`
+	Targets []*TargetReport `json:"targets"`
+}
+
+// TargetReport contains the outcome of the sync of a single target
+type TargetReport struct {
+	Target          string               `json:"target"`
+	Status          string               `json:"status"`
+	Initialized     bool                 `json:"initialized"`
+	Error           string               `json:"error,omitempty"`
+	Changes         int                  `json:"changes"`
+	Retries         int                  `json:"retries"`
+	DurationSeconds float64              `json:"duration_seconds"`
+	Credentials     []*CredentialsReport `json:"credentials"`
+}
+
+// CredentialsReport contains the outcome of a single change on the credentials of a target
+type CredentialsReport struct {
+	ID     string `json:"id"`
+	Action Action `json:"action"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NewReport creates the report of a sync from the results of its targets and the error it returned
+func NewReport(results []*TargetResult, err error) *Report {
+	report := &Report{Status: StatusSucceeded, Targets: []*TargetReport{}}
+	if err != nil {
+		report.Status = StatusFailed
+		report.Error = logger.Redact(err.Error())
+	}
+	for _, result := range results {
+		report.Targets = append(report.Targets, newTargetReport(result))
+	}
+	return report
+}
+
+func newTargetReport(result *TargetResult) *TargetReport {
+	targetReport := &TargetReport{
+		Target:          result.Target,
+		Status:          StatusSucceeded,
+		Initialized:     result.Initialized,
+		Changes:         result.Changes,
+		Retries:         result.Retries,
+		DurationSeconds: result.Duration.Seconds(),
+		Credentials:     []*CredentialsReport{},
+	}
+	switch {
+	case !result.Initialized:
+		targetReport.Status = StatusFailedInitialization
+	case result.Cancelled:
+		targetReport.Status = StatusCancelled
+	case result.Err != nil:
+		targetReport.Status = StatusFailed
+	}
+	if result.Err != nil {
+		targetReport.Error = logger.Redact(result.Err.Error())
+	}
+	for _, credentialsResult := range result.Credentials {
+		credentialsReport := &CredentialsReport{ID: credentialsResult.ID, Action: credentialsResult.Action, Status: credentialsResult.status()}
+		if credentialsResult.Err != nil {
+			credentialsReport.Error = logger.Redact(credentialsResult.Err.Error())
+		}
+		targetReport.Credentials = append(targetReport.Credentials, credentialsReport)
+	}
+	return targetReport
+}
+
+// status returns the status of a change on the credentials of a target: succeeded, failed or skipped
+func (result *CredentialsResult) status() string {
+	switch {
+	case result.Err != nil:
+		return StatusFailed
+	case result.Action == ActionSkip:
+		return StatusSkipped
+	}
+	return StatusSucceeded
+}
+
+// WriteJSON writes the report in JSON
+func (report *Report) WriteJSON(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name          `xml:"testsuites"`
+	Tests      int               `xml:"tests,attr"`
+	Failures   int               `xml:"failures,attr"`
+	Skipped    int               `xml:"skipped,attr"`
+	TestSuites []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteJUnit writes the report in the JUnit XML format, with a test suite per target
+// Each target has an `initialize` test case, then a test case per credentials. Errors of the target that are not caused
+// by a credentials (deletion limits, timeouts, etc.) are reported by a `sync` test case
+func (report *Report) WriteJUnit(writer io.Writer) error {
+	suites := &junitTestSuites{}
+	for _, target := range report.Targets {
+		suite := &junitTestSuite{Name: target.Target, Time: fmt.Sprintf("%.3f", target.DurationSeconds)}
+		addCase := func(testCase *junitTestCase) {
+			testCase.ClassName = target.Target
+			suite.Tests++
+			if testCase.Failure != nil {
+				suite.Failures++
+			}
+			if testCase.Skipped != nil {
+				suite.Skipped++
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+
+		initialize := &junitTestCase{Name: "initialize"}
+		if !target.Initialized {
+			initialize.Failure = &junitFailure{Message: "failed initialization", Text: target.Error}
+		}
+		addCase(initialize)
+
+		failedCredentials := 0
+		for _, creds := range target.Credentials {
+			testCase := &junitTestCase{Name: fmt.Sprintf("%s (%s)", creds.ID, creds.Action)}
+			switch creds.Status {
+			case StatusFailed:
+				failedCredentials++
+				testCase.Failure = &junitFailure{Message: fmt.Sprintf("failed to %s", creds.Action), Text: creds.Error}
+			case StatusSkipped:
+				testCase.Skipped = &junitSkipped{Message: "not synced to this target"}
+			}
+			addCase(testCase)
+		}
+
+		if target.Initialized && (target.Status == StatusFailed || target.Status == StatusCancelled) && failedCredentials == 0 {
+			addCase(&junitTestCase{Name: "sync", Failure: &junitFailure{Message: target.Status, Text: target.Error}})
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.TestSuites = append(suites.TestSuites, suite)
+	}
+
+	// When no target was synced (ex: the credentials could not be fetched), the error of the sync is its only failure
+	if len(report.Targets) == 0 && report.Error != "" {
+		suites.Tests, suites.Failures = 1, 1
+		suites.TestSuites = append(suites.TestSuites, &junitTestSuite{Name: "credentials-sync", Tests: 1, Failures: 1, Time: "0.000", TestCases: []*junitTestCase{
+			{Name: "sync", ClassName: "credentials-sync", Failure: &junitFailure{Message: StatusFailed, Text: report.Error}},
+		}})
+	}
+
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
@@ -0,0 +1,130 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testResults() []*TargetResult {
+	updateErr := fmt.Errorf("Failed to send credentials with ID test2 to jenkins: 500")
+	return []*TargetResult{
+		{Target: "broken", Err: &targetInitError{target: "broken", err: fmt.Errorf("connection refused")}},
+		{
+			Target:      "jenkins",
+			Initialized: true,
+			Changes:     3,
+			Retries:     1,
+			Duration:    1500 * time.Millisecond,
+			Err:         multierror.Append(nil, updateErr),
+			Credentials: []*CredentialsResult{
+				{ID: "test1", Action: ActionCreate},
+				{ID: "test2", Action: ActionUpdate, Err: updateErr},
+				{ID: "test3", Action: ActionUnchanged},
+				{ID: "test4", Action: ActionSkip},
+			},
+		},
+		{
+			Target:      "github",
+			Initialized: true,
+			Err:         multierror.Append(nil, fmt.Errorf("Aborting the sync of github: too many deletions")),
+		},
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	report := NewReport(testResults(), fmt.Errorf("the sync failed"))
+
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, "the sync failed", report.Error)
+	require.Len(t, report.Targets, 3)
+	assert.Equal(t, &TargetReport{
+		Target:      "broken",
+		Status:      StatusFailedInitialization,
+		Error:       "Target `broken` has failed initialization: connection refused",
+		Credentials: []*CredentialsReport{},
+	}, report.Targets[0])
+	assert.Equal(t, StatusFailed, report.Targets[1].Status)
+	assert.Equal(t, 1.5, report.Targets[1].DurationSeconds)
+	assert.Equal(t, []*CredentialsReport{
+		{ID: "test1", Action: ActionCreate, Status: StatusSucceeded},
+		{ID: "test2", Action: ActionUpdate, Status: StatusFailed, Error: "Failed to send credentials with ID test2 to jenkins: 500"},
+		{ID: "test3", Action: ActionUnchanged, Status: StatusSucceeded},
+		{ID: "test4", Action: ActionSkip, Status: StatusSkipped},
+	}, report.Targets[1].Credentials)
+	assert.Equal(t, StatusFailed, report.Targets[2].Status)
+}
+
+func TestNewReportSucceeded(t *testing.T) {
+	report := NewReport([]*TargetResult{{Target: "jenkins", Initialized: true}}, nil)
+
+	assert.Equal(t, StatusSucceeded, report.Status)
+	assert.Equal(t, StatusSucceeded, report.Targets[0].Status)
+}
+
+func TestReportWriteJSON(t *testing.T) {
+	report := NewReport([]*TargetResult{{
+		Target:      "jenkins",
+		Initialized: true,
+		Changes:     1,
+		Credentials: []*CredentialsResult{{ID: "test1", Action: ActionCreate}},
+	}}, nil)
+
+	var output bytes.Buffer
+	require.NoError(t, report.WriteJSON(&output))
+	assert.JSONEq(t, `{
+		"status": "succeeded",
+		"targets": [{
+			"target": "jenkins",
+			"status": "succeeded",
+			"initialized": true,
+			"changes": 1,
+			"retries": 0,
+			"duration_seconds": 0,
+			"credentials": [{"id": "test1", "action": "create", "status": "succeeded"}]
+		}]
+	}`, output.String())
+}
+
+func TestReportWriteJUnit(t *testing.T) {
+	var output bytes.Buffer
+	require.NoError(t, NewReport(testResults(), fmt.Errorf("the sync failed")).WriteJUnit(&output))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="8" failures="3" skipped="1">
+  <testsuite name="broken" tests="1" failures="1" skipped="0" time="0.000">
+    <testcase name="initialize" classname="broken">
+      <failure message="failed initialization">Target `+"`broken`"+` has failed initialization: connection refused</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="jenkins" tests="5" failures="1" skipped="1" time="1.500">
+    <testcase name="initialize" classname="jenkins"></testcase>
+    <testcase name="test1 (create)" classname="jenkins"></testcase>
+    <testcase name="test2 (update)" classname="jenkins">
+      <failure message="failed to update">Failed to send credentials with ID test2 to jenkins: 500</failure>
+    </testcase>
+    <testcase name="test3 (unchanged)" classname="jenkins"></testcase>
+    <testcase name="test4 (skip)" classname="jenkins">
+      <skipped message="not synced to this target"></skipped>
+    </testcase>
+  </testsuite>
+  <testsuite name="github" tests="2" failures="1" skipped="0" time="0.000">
+    <testcase name="initialize" classname="github"></testcase>
+    <testcase name="sync" classname="github">
+      <failure message="failed">1 error occurred:&#xA;&#x9;* Aborting the sync of github: too many deletions&#xA;&#xA;</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, output.String())
+}
+
+func TestReportWriteJUnitWithoutTargets(t *testing.T) {
+	var output bytes.Buffer
+	require.NoError(t, NewReport(nil, fmt.Errorf("Caught an error while fetching credentials")).WriteJUnit(&output))
+	assert.Contains(t, output.String(), `<testsuites tests="1" failures="1" skipped="0">`)
+	assert.Contains(t, output.String(), `<failure message="failed">Caught an error while fetching credentials</failure>`)
+}
@@ -0,0 +1,148 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	gosync "sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// StateConfiguration defines where the state of incremental syncs is persisted
+// The state is either stored in a local file or in a S3 object
+type StateConfiguration struct {
+	File   string
+	Bucket string
+	Key    string
+
+	client s3iface.S3API
+}
+
+// State contains the fingerprints of the credentials that were last synced to each target
+type State struct {
+	// Target name -> Credentials target ID -> Fingerprint
+	Targets map[string]map[string]string `json:"targets"`
+
+	mutex gosync.Mutex
+}
+
+// NewState creates an empty state
+func NewState() *State {
+	return &State{Targets: map[string]map[string]string{}}
+}
+
+// GetFingerprint returns the fingerprint of the credentials last synced to the given target
+func (state *State) GetFingerprint(targetName string, id string) string {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return state.Targets[targetName][id]
+}
+
+// SetFingerprint records the fingerprint of the credentials synced to the given target
+func (state *State) SetFingerprint(targetName string, id string, fingerprint string) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if _, ok := state.Targets[targetName]; !ok {
+		state.Targets[targetName] = map[string]string{}
+	}
+	state.Targets[targetName][id] = fingerprint
+}
+
+// DeleteFingerprint removes the fingerprint of the given credentials on the given target
+func (state *State) DeleteFingerprint(targetName string, id string) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	delete(state.Targets[targetName], id)
+}
+
+func (stateConfig *StateConfiguration) getClient() s3iface.S3API {
+	if stateConfig.client == nil {
+		sess := session.Must(session.NewSessionWithOptions(session.Options{
+			SharedConfigState:       session.SharedConfigEnable,
+			AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
+		}))
+		stateConfig.client = s3.New(sess)
+	}
+	return stateConfig.client
+}
+
+// Load reads the state from its configured location. If the state does not exist yet, an empty state is returned
+func (stateConfig *StateConfiguration) Load() (*State, error) {
+	var content []byte
+	if stateConfig.File != "" {
+		fileContent, err := os.ReadFile(stateConfig.File)
+		if errors.Is(err, os.ErrNotExist) {
+			return NewState(), nil
+		} else if err != nil {
+			return nil, fmt.Errorf("Failed to read the state file: %v", err)
+		}
+		content = fileContent
+	} else {
+		response, err := stateConfig.getClient().GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(stateConfig.Bucket),
+			Key:    aws.String(stateConfig.Key),
+		})
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return NewState(), nil
+		} else if err != nil {
+			return nil, fmt.Errorf("Failed to download the state file from S3: %v", err)
+		}
+		if content, err = io.ReadAll(response.Body); err != nil {
+			return nil, fmt.Errorf("Failed to read the state file from S3: %v", err)
+		}
+	}
+
+	state := NewState()
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("Failed to parse the state file: %v", err)
+	}
+	if state.Targets == nil {
+		state.Targets = map[string]map[string]string{}
+	}
+	return state, nil
+}
+
+// Save writes the given state to its configured location
+func (stateConfig *StateConfiguration) Save(state *State) error {
+	state.mutex.Lock()
+	content, err := json.MarshalIndent(state, "", "  ")
+	state.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if stateConfig.File != "" {
+		if err := os.WriteFile(stateConfig.File, content, 0600); err != nil {
+			return fmt.Errorf("Failed to write the state file: %v", err)
+		}
+		return nil
+	}
+	if _, err := stateConfig.getClient().PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(stateConfig.Bucket),
+		Key:    aws.String(stateConfig.Key),
+		Body:   bytes.NewReader(content),
+	}); err != nil {
+		return fmt.Errorf("Failed to upload the state file to S3: %v", err)
+	}
+	return nil
+}
+
+// ValidateConfiguration verifies that the state location is valid
+func (stateConfig *StateConfiguration) ValidateConfiguration() error {
+	if stateConfig.File == "" && (stateConfig.Bucket == "" || stateConfig.Key == "") {
+		return fmt.Errorf("The state must either define a `file` or a `bucket` and a `key`")
+	}
+	if stateConfig.File != "" && (stateConfig.Bucket != "" || stateConfig.Key != "") {
+		return fmt.Errorf("The state cannot define both a `file` and a S3 location")
+	}
+	return nil
+}
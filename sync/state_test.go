@@ -0,0 +1,139 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/targets"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateValidateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		state       *StateConfiguration
+		expectError bool
+	}{
+		{name: "Empty", state: &StateConfiguration{}, expectError: true},
+		{name: "File", state: &StateConfiguration{File: "state.json"}, expectError: false},
+		{name: "S3", state: &StateConfiguration{Bucket: "bucket", Key: "key"}, expectError: false},
+		{name: "S3 without key", state: &StateConfiguration{Bucket: "bucket"}, expectError: true},
+		{name: "File and S3", state: &StateConfiguration{File: "state.json", Bucket: "bucket", Key: "key"}, expectError: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectError, tt.state.ValidateConfiguration() != nil)
+		})
+	}
+}
+
+func TestLocalState(t *testing.T) {
+	stateConfig := &StateConfiguration{File: path.Join(t.TempDir(), "state.json")}
+
+	// A missing state file results in an empty state
+	state, err := stateConfig.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "", state.GetFingerprint("target", "test1"))
+
+	state.SetFingerprint("target", "test1", "abc")
+	state.SetFingerprint("target", "test2", "def")
+	state.DeleteFingerprint("target", "test2")
+	assert.NoError(t, stateConfig.Save(state))
+
+	fileInfo, err := os.Stat(stateConfig.File)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+
+	loadedState, err := stateConfig.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"target": {"test1": "abc"}}, loadedState.Targets)
+}
+
+type mockS3Client struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+func (m *mockS3Client) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	content, ok := m.objects[*input.Bucket+"/"+*input.Key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "not found", nil)
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(content))}, nil
+}
+
+func (m *mockS3Client) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	content, _ := io.ReadAll(input.Body)
+	m.objects[*input.Bucket+"/"+*input.Key] = content
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3State(t *testing.T) {
+	client := &mockS3Client{objects: map[string][]byte{}}
+	stateConfig := &StateConfiguration{Bucket: "bucket", Key: "state.json", client: client}
+
+	state, err := stateConfig.Load()
+	assert.NoError(t, err)
+	state.SetFingerprint("target", "test1", "abc")
+	assert.NoError(t, stateConfig.Save(state))
+	assert.Contains(t, string(client.objects["bucket/state.json"]), `"test1": "abc"`)
+
+	loadedState, err := stateConfig.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", loadedState.GetFingerprint("target", "test1"))
+
+	client.objects["bucket/state.json"] = []byte("not json")
+	_, err = stateConfig.Load()
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "Failed to parse the state file"))
+}
+
+func TestIncrementalSync(t *testing.T) {
+	cred1, cred2 := credentials.NewSecretText(), credentials.NewSecretText()
+	cred1.ID = "test1"
+	cred1.Secret = "value1"
+	cred2.ID = "test2"
+	cred2.Secret = "value2"
+
+	config := &Configuration{StopOnError: true, TargetParallelism: 1, State: &StateConfiguration{File: path.Join(t.TempDir(), "state.json")}}
+	targetController, target := setTargetMock(t, config, "target", []string{"test1", "test2", "test3"}, false)
+	sourceController, _ := setSourceMock(t, config, []credentials.Credentials{cred1, cred2})
+	defer targetController.Finish()
+	defer sourceController.Finish()
+
+	fingerprint1, _ := targets.Fingerprint(target, cred1)
+	state := NewState()
+	state.SetFingerprint("target-0", "test1", fingerprint1)
+	state.SetFingerprint("target-0", "test2", "outdated")
+	state.SetFingerprint("target-0", "test3", "unsynced")
+	assert.NoError(t, config.State.Save(state))
+
+	// Only the modified credentials is sent
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred2).Times(1)
+	_, err := config.Sync(context.Background())
+	assert.Nil(t, err)
+
+	fingerprint2, _ := targets.Fingerprint(target, cred2)
+	savedState, err := config.State.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"target-0": {"test1": fingerprint1, "test2": fingerprint2}}, savedState.Targets)
+
+	// A full sync sends everything
+	config.FullSync = true
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred1).Times(1)
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred2).Times(1)
+	_, err = config.Sync(context.Background())
+	assert.Nil(t, err)
+}
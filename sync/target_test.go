@@ -1,10 +1,14 @@
 package sync
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/targets"
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDeleteListOfCredentials(t *testing.T) {
@@ -15,9 +19,9 @@ func TestDeleteListOfCredentials(t *testing.T) {
 	defer targetController.Finish()
 
 	// Asserts that DeleteCredentials is called with `test1`
-	target.EXPECT().DeleteCredentials("test1").Return(nil)
+	target.EXPECT().DeleteCredentials(gomock.Any(), "test1").Return(nil)
 
-	config.DeleteListOfCredentials(target)
+	config.DeleteListOfCredentials(context.Background(), target)
 }
 
 func TestUpdateListOfCredentials(t *testing.T) {
@@ -30,10 +34,10 @@ func TestUpdateListOfCredentials(t *testing.T) {
 	cred2.ID = "test2"
 
 	// Asserts that UpdateCredentials is called with `test1` and `test2`
-	target.EXPECT().UpdateCredentials(cred1).Times(1)
-	target.EXPECT().UpdateCredentials(cred2).Times(1)
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred1).Times(1)
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred2).Times(1)
 
-	config.UpdateListOfCredentials(target, []credentials.Credentials{cred1, cred2})
+	config.UpdateListOfCredentials(context.Background(), target, []credentials.Credentials{cred1, cred2})
 }
 
 func TestDeleteUnsyncedCredentials(t *testing.T) {
@@ -46,10 +50,99 @@ func TestDeleteUnsyncedCredentials(t *testing.T) {
 	cred2.ID = "test2"
 
 	// Do not verify this call
-	target.EXPECT().UpdateCredentials(gomock.Any()).AnyTimes()
+	target.EXPECT().UpdateCredentials(gomock.Any(), gomock.Any()).AnyTimes()
 
 	// Asserts that DeleteCredentials is called with `unsynced`
-	target.EXPECT().DeleteCredentials("unsynced").Times(1)
+	target.EXPECT().DeleteCredentials(gomock.Any(), "unsynced").Times(1)
 
-	config.UpdateListOfCredentials(target, []credentials.Credentials{cred1, cred2})
+	config.UpdateListOfCredentials(context.Background(), target, []credentials.Credentials{cred1, cred2})
+}
+
+func TestTagUnsyncedCredentials(t *testing.T) {
+	config := NewConfiguration()
+	targetController, targets := setMultipleTargetMockWithOptions(t, config, "", []string{"test1", "unsynced", "already-tagged"}, targetMockOptions{tagUnsynced: true}, 1)
+	target := targets[0]
+	defer targetController.Finish()
+
+	cred1 := credentials.NewSecretText()
+	cred1.ID = "test1"
+
+	target.EXPECT().UpdateCredentials(gomock.Any(), cred1).Times(1)
+
+	// Only the credentials that are not already tagged are modified
+	target.EXPECT().GetCredentialsDescription(gomock.Any(), "unsynced").Return("a description", nil)
+	target.EXPECT().GetCredentialsDescription(gomock.Any(), "already-tagged").Return("[unsynced since 2020-01-01T00:00:00Z] a description", nil)
+	target.EXPECT().SetCredentialsDescription(gomock.Any(), "unsynced", gomock.Any()).DoAndReturn(func(ctx context.Context, id string, description string) error {
+		assert.Regexp(t, `^\[unsynced since \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z\] a description$`, description)
+		return nil
+	})
+
+	assert.Nil(t, config.UpdateListOfCredentials(context.Background(), target, []credentials.Credentials{cred1}))
+}
+
+func TestDeleteOnlyMarkedUnsyncedCredentials(t *testing.T) {
+	config := NewConfiguration()
+	options := targetMockOptions{deleteUnsynced: true, deleteOnlyMarked: true, ownershipMarker: "[managed]"}
+	targetController, targets := setMultipleTargetMockWithOptions(t, config, "", []string{"marked", "unmarked", "unknown"}, options, 1)
+	target := targets[0]
+	defer targetController.Finish()
+
+	target.EXPECT().GetCredentialsDescription(gomock.Any(), "marked").Return("a description [managed]", nil)
+	target.EXPECT().GetCredentialsDescription(gomock.Any(), "unmarked").Return("created by hand", nil)
+	target.EXPECT().GetCredentialsDescription(gomock.Any(), "unknown").Return("", fmt.Errorf("Dummy error"))
+
+	// Only the marked credentials are deleted
+	target.EXPECT().DeleteCredentials(gomock.Any(), "marked").Times(1)
+
+	assert.Nil(t, config.UpdateListOfCredentials(context.Background(), target, []credentials.Credentials{}))
+}
+
+type bufferingTargetMock struct {
+	*targets.MockTarget
+	flushes  int
+	flushErr error
+}
+
+func (target *bufferingTargetMock) FlushChanges(ctx context.Context) error {
+	target.flushes++
+	return target.flushErr
+}
+
+func TestApplyChangesFlushesBufferedChanges(t *testing.T) {
+	config := NewConfiguration()
+	config.state = NewState()
+	targetController, mockTarget := setTargetMock(t, config, "target", []string{"test1", "unsynced"}, true)
+	defer targetController.Finish()
+	target := &bufferingTargetMock{MockTarget: mockTarget}
+
+	cred1, cred2 := credentials.NewSecretText(), credentials.NewSecretText()
+	cred1.ID = "test1"
+	cred2.ID = "test2"
+	changes := []Change{
+		{Action: ActionUpdate, ID: "test1", Credentials: cred1, Fingerprint: "fingerprint1"},
+		{Action: ActionCreate, ID: "test2", Credentials: cred2, Fingerprint: "fingerprint2"},
+		{Action: ActionDeleteUnsynced, ID: "unsynced"},
+	}
+	target.EXPECT().UpdateCredentials(gomock.Any(), gomock.Any()).Times(4)
+	target.EXPECT().DeleteCredentials(gomock.Any(), "unsynced").Times(2)
+
+	// The changes are flushed once, after they are all applied
+	result := &TargetResult{}
+	applied, err := config.applyChanges(context.Background(), target, changes, result)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, applied)
+	assert.Equal(t, 1, target.flushes)
+	assert.Equal(t, "fingerprint1", config.state.GetFingerprint("target-0", "test1"))
+
+	// If the flush fails, all the changes are reported as failed and the synced credentials are forgotten
+	target.flushErr = fmt.Errorf("disk full")
+	result = &TargetResult{}
+	_, err = config.applyChanges(context.Background(), target, changes, result)
+	assert.EqualError(t, err, "1 error occurred:\n\t* Failed to write the changes to target-0: disk full\n\n")
+	assert.Equal(t, 2, target.flushes)
+	for _, credentialsResult := range result.Credentials {
+		assert.EqualError(t, credentialsResult.Err, "Failed to write the changes to target-0: disk full")
+	}
+	assert.Equal(t, "", config.state.GetFingerprint("target-0", "test1"))
+	assert.Equal(t, "", config.state.GetFingerprint("target-0", "test2"))
 }
@@ -3,6 +3,7 @@ package sync
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/coveooss/credentials-sync/credentials"
 	"github.com/coveooss/credentials-sync/targets"
@@ -14,9 +15,10 @@ func setSourceMock(t *testing.T, config *Configuration, creds []credentials.Cred
 	source := credentials.NewMockSource(ctrl)
 	sourceCollection := credentials.NewMockSourceCollection(ctrl)
 	sourceCollection.EXPECT().AllSources().Return([]credentials.Source{source}).AnyTimes()
+	sourceCollection.EXPECT().SetDefaultRetryPolicy(gomock.Any()).AnyTimes()
 
 	if creds != nil {
-		sourceCollection.EXPECT().Credentials().Return(creds, nil).AnyTimes()
+		sourceCollection.EXPECT().Credentials(gomock.Any()).Return(creds, nil).AnyTimes()
 	}
 
 	config.SetSources(sourceCollection)
@@ -24,6 +26,21 @@ func setSourceMock(t *testing.T, config *Configuration, creds []credentials.Cred
 }
 
 func setMultipleTargetMock(t *testing.T, config *Configuration, name string, credentials []string, shouldDeleteUnsynced bool, targetNumber int) (*gomock.Controller, []*targets.MockTarget) {
+	return setMultipleTargetMockWithOptions(t, config, name, credentials, targetMockOptions{deleteUnsynced: shouldDeleteUnsynced}, targetNumber)
+}
+
+// targetMockOptions defines the values returned by the base methods of mocked targets
+type targetMockOptions struct {
+	deleteUnsynced       bool
+	deleteOnlyMarked     bool
+	deletionLimits       targets.DeletionLimits
+	ownershipMarker      string
+	protectedCredentials []string
+	tagUnsynced          bool
+	timeout              time.Duration
+}
+
+func setMultipleTargetMockWithOptions(t *testing.T, config *Configuration, name string, credentials []string, options targetMockOptions, targetNumber int) (*gomock.Controller, []*targets.MockTarget) {
 	ctrl := gomock.NewController(t)
 	targetCollection := targets.NewMockTargetCollection(ctrl)
 
@@ -35,7 +52,14 @@ func setMultipleTargetMock(t *testing.T, config *Configuration, name string, cre
 		target.EXPECT().GetName().Return(fmt.Sprintf("%s-%v", name, i)).AnyTimes()
 		target.EXPECT().GetTags().Return(map[string]string{}).AnyTimes()
 		target.EXPECT().ToString().Return(fmt.Sprintf("%s-%v", name, i)).AnyTimes()
-		target.EXPECT().ShouldDeleteUnsynced().Return(shouldDeleteUnsynced).AnyTimes()
+		target.EXPECT().ShouldDeleteUnsynced().Return(options.deleteUnsynced).AnyTimes()
+		target.EXPECT().ShouldDeleteOnlyMarked().Return(options.deleteOnlyMarked).AnyTimes()
+		target.EXPECT().GetOwnershipMarker().Return(options.ownershipMarker).AnyTimes()
+		target.EXPECT().GetDeletionLimits().Return(options.deletionLimits).AnyTimes()
+		target.EXPECT().GetProtectedCredentials().Return(options.protectedCredentials).AnyTimes()
+		target.EXPECT().ShouldTagUnsynced().Return(options.tagUnsynced).AnyTimes()
+		target.EXPECT().GetTimeout().Return(options.timeout).AnyTimes()
+		target.EXPECT().SetDefaultRetryPolicy(gomock.Any()).AnyTimes()
 		targetsToReturn = append(targetsToReturn, target)
 		targetsToReturnInterface = append(targetsToReturnInterface, target)
 	}
@@ -47,6 +71,6 @@ func setMultipleTargetMock(t *testing.T, config *Configuration, name string, cre
 
 func setTargetMock(t *testing.T, config *Configuration, name string, credentials []string, shouldDeleteUnsynced bool) (*gomock.Controller, *targets.MockTarget) {
 	ctrl, targetsToReturn := setMultipleTargetMock(t, config, name, credentials, shouldDeleteUnsynced, 1)
-	targetsToReturn[0].EXPECT().Initialize(gomock.Any()).AnyTimes()
+	targetsToReturn[0].EXPECT().Initialize(gomock.Any(), gomock.Any()).AnyTimes()
 	return ctrl, targetsToReturn[0]
 }
@@ -0,0 +1,70 @@
+package targets
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/retry"
+)
+
+// AWSBase contains the attributes common to the targets that store credentials in an AWS account
+type AWSBase struct {
+	// Prefix of the names of the synced credentials. Only the credentials under the prefix are considered as existing
+	Prefix string `mapstructure:"prefix"`
+	Region string `mapstructure:"region"`
+	// Role assumed to access the account. The default credentials are used if it is not set
+	RoleARN  string `mapstructure:"role_arn"`
+	KMSKeyID string `mapstructure:"kms_key_id"`
+}
+
+// newSession creates an AWS session for the target, assuming its role if it defines one
+func (awsBase *AWSBase) newSession(policy retry.Policy, name string) *session.Session {
+	config := request.WithRetryer(aws.NewConfig(), &retry.AWSRetryer{Policy: policy, Name: name})
+	if awsBase.Region != "" {
+		config = config.WithRegion(awsBase.Region)
+	}
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		Config:                  *config,
+		SharedConfigState:       session.SharedConfigEnable,
+		AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
+	}))
+	if awsBase.RoleARN != "" {
+		sess = sess.Copy(&aws.Config{Credentials: stscreds.NewCredentials(sess, awsBase.RoleARN)})
+	}
+	return sess
+}
+
+// validateAWSBase verifies that the AWS attributes of the given target are valid
+func (awsBase *AWSBase) validateAWSBase(targetType string, targetName string) error {
+	if awsBase.Prefix == "" {
+		return fmt.Errorf("the %s target `%s` must define a `prefix`", targetType, targetName)
+	}
+	return nil
+}
+
+// sortedTagKeys returns the keys of the given tags in a stable order, so that the requests sent to AWS are deterministic
+func sortedTagKeys(tags map[string]string) []string {
+	keys := []string{}
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toAWSSecretValue serializes the given credentials to the JSON document stored in AWS
+// The document is a single credentials in the format of the sources, so it can be read back by an AWS source
+func toAWSSecretValue(creds credentials.Credentials, description string) (string, error) {
+	document, err := toCredentialsDocument(creds, description)
+	if err != nil {
+		return "", err
+	}
+	serializedValue, err := json.Marshal(document)
+	return string(serializedValue), err
+}
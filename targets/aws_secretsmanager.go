@@ -0,0 +1,181 @@
+package targets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/coveooss/credentials-sync/credentials"
+)
+
+// AWSSecretsManagerTarget represents the secrets of an AWS account, under a name prefix
+// Each credentials is synced to a secret named after the prefix and the credentials' target ID
+type AWSSecretsManagerTarget struct {
+	Base    `mapstructure:",squash"`
+	AWSBase `mapstructure:",squash"`
+
+	// Number of days during which deleted secrets can be restored. Secrets are deleted without recovery if it is 0
+	RecoveryWindowDays *int `mapstructure:"recovery_window_days"`
+
+	client              secretsmanageriface.SecretsManagerAPI
+	existingCredentials []string
+	// Credentials target ID -> Description of the secret
+	descriptions map[string]string
+}
+
+func (target *AWSSecretsManagerTarget) secretName(id string) string {
+	return target.Prefix + id
+}
+
+// Initialize executes all necessary operations to prepare the Secrets Manager target for sync
+func (target *AWSSecretsManagerTarget) Initialize(ctx context.Context, allCredentials []credentials.Credentials) error {
+	if target.client == nil {
+		target.client = secretsmanager.New(target.newSession(target.Retry, target.Name))
+	}
+
+	target.existingCredentials = []string{}
+	target.descriptions = map[string]string{}
+	input := &secretsmanager.ListSecretsInput{
+		Filters: []*secretsmanager.Filter{{Key: aws.String(secretsmanager.FilterNameStringTypeName), Values: []*string{aws.String(target.Prefix)}}},
+	}
+	return target.client.ListSecretsPagesWithContext(ctx, input, func(output *secretsmanager.ListSecretsOutput, lastPage bool) bool {
+		for _, secret := range output.SecretList {
+			// The name filter also matches words in the middle of names
+			if id, found := strings.CutPrefix(aws.StringValue(secret.Name), target.Prefix); found {
+				target.existingCredentials = append(target.existingCredentials, id)
+				target.descriptions[id] = aws.StringValue(secret.Description)
+			}
+		}
+		return !lastPage
+	})
+}
+
+// ToString prints out a description of the Secrets Manager target
+func (target *AWSSecretsManagerTarget) ToString() string {
+	return fmt.Sprintf("%s (Amazon SecretsManager) - %s", target.BaseToString(), target.Prefix)
+}
+
+// GetExistingCredentials returns a list of all credential IDs on the target
+func (target *AWSSecretsManagerTarget) GetExistingCredentials() []string {
+	return target.existingCredentials
+}
+
+// DeleteCredentials deletes the secret of the credentials with the given ID
+func (target *AWSSecretsManagerTarget) DeleteCredentials(ctx context.Context, id string) error {
+	input := &secretsmanager.DeleteSecretInput{SecretId: aws.String(target.secretName(id))}
+	if target.RecoveryWindowDays != nil && *target.RecoveryWindowDays == 0 {
+		input.ForceDeleteWithoutRecovery = aws.Bool(true)
+	} else if target.RecoveryWindowDays != nil {
+		input.RecoveryWindowInDays = aws.Int64(int64(*target.RecoveryWindowDays))
+	}
+	_, err := target.client.DeleteSecretWithContext(ctx, input)
+	return err
+}
+
+// GetCredentialsDescription returns the description of the secret of the credentials with the given ID
+func (target *AWSSecretsManagerTarget) GetCredentialsDescription(ctx context.Context, id string) (string, error) {
+	description, ok := target.descriptions[id]
+	if !ok {
+		return "", fmt.Errorf("the secret %s does not exist", target.secretName(id))
+	}
+	return description, nil
+}
+
+// SetCredentialsDescription modifies the description of the secret of the credentials with the given ID
+func (target *AWSSecretsManagerTarget) SetCredentialsDescription(ctx context.Context, id string, description string) error {
+	if _, err := target.client.UpdateSecretWithContext(ctx, &secretsmanager.UpdateSecretInput{
+		SecretId:    aws.String(target.secretName(id)),
+		Description: aws.String(description),
+	}); err != nil {
+		return err
+	}
+	target.descriptions[id] = description
+	return nil
+}
+
+// tags returns the tags of the secrets, which are the target's tags
+func (target *AWSSecretsManagerTarget) tags() []*secretsmanager.Tag {
+	var tags []*secretsmanager.Tag
+	for _, key := range sortedTagKeys(target.Tags) {
+		tags = append(tags, &secretsmanager.Tag{Key: aws.String(key), Value: aws.String(target.Tags[key])})
+	}
+	return tags
+}
+
+// RenderCredentials returns the value of the secret of the given credentials
+func (target *AWSSecretsManagerTarget) RenderCredentials(cred credentials.Credentials) ([]byte, error) {
+	value, err := toAWSSecretValue(cred, target.markedDescription(cred))
+	return []byte(value), err
+}
+
+// UpdateCredentials syncs the given credentials to Secrets Manager
+// A secret that is scheduled for deletion is restored before being updated
+func (target *AWSSecretsManagerTarget) UpdateCredentials(ctx context.Context, cred credentials.Credentials) error {
+	description := target.markedDescription(cred)
+	value, err := toAWSSecretValue(cred, description)
+	if err != nil {
+		return err
+	}
+	name := target.secretName(cred.GetTargetID())
+
+	if !HasCredential(target, cred.GetTargetID()) {
+		input := &secretsmanager.CreateSecretInput{
+			Name:         aws.String(name),
+			Description:  aws.String(description),
+			SecretString: aws.String(value),
+		}
+		if target.KMSKeyID != "" {
+			input.KmsKeyId = aws.String(target.KMSKeyID)
+		}
+		input.Tags = target.tags()
+		_, err := target.client.CreateSecretWithContext(ctx, input)
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != secretsmanager.ErrCodeInvalidRequestException {
+			if err == nil {
+				target.descriptions[cred.GetTargetID()] = description
+			}
+			return err
+		}
+		// Invalid requests are also caused by a secret with the same name that is scheduled for deletion
+		secret, describeErr := target.client.DescribeSecretWithContext(ctx, &secretsmanager.DescribeSecretInput{SecretId: aws.String(name)})
+		if describeErr != nil || secret.DeletedDate == nil {
+			return err
+		}
+		if _, err := target.client.RestoreSecretWithContext(ctx, &secretsmanager.RestoreSecretInput{SecretId: aws.String(name)}); err != nil {
+			return fmt.Errorf("unable to restore the secret %s, which is scheduled for deletion: %v", name, err)
+		}
+	}
+
+	input := &secretsmanager.UpdateSecretInput{
+		SecretId:     aws.String(name),
+		Description:  aws.String(description),
+		SecretString: aws.String(value),
+	}
+	if target.KMSKeyID != "" {
+		input.KmsKeyId = aws.String(target.KMSKeyID)
+	}
+	if _, err := target.client.UpdateSecretWithContext(ctx, input); err != nil {
+		return err
+	}
+	if len(target.Tags) > 0 {
+		if _, err := target.client.TagResourceWithContext(ctx, &secretsmanager.TagResourceInput{SecretId: aws.String(name), Tags: target.tags()}); err != nil {
+			return err
+		}
+	}
+	target.descriptions[cred.GetTargetID()] = description
+	return nil
+}
+
+// ValidateConfiguration verifies that Secrets Manager configuration is valid
+func (target *AWSSecretsManagerTarget) ValidateConfiguration() error {
+	if err := target.validateAWSBase("Secrets Manager", target.Name); err != nil {
+		return err
+	}
+	if target.RecoveryWindowDays != nil && *target.RecoveryWindowDays != 0 && (*target.RecoveryWindowDays < 7 || *target.RecoveryWindowDays > 30) {
+		return fmt.Errorf("the `recovery_window_days` of the Secrets Manager target `%s` must be 0 or between 7 and 30", target.Name)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package targets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSecretsManagerSecret struct {
+	value              string
+	description        string
+	kmsKeyID           string
+	tags               map[string]string
+	scheduledForDelete bool
+}
+
+type mockSecretsManagerTargetClient struct {
+	secretsmanageriface.SecretsManagerAPI
+	secrets map[string]*mockSecretsManagerSecret
+	// Returned by CreateSecret, if it is set
+	createError error
+}
+
+func (m *mockSecretsManagerTargetClient) ListSecretsPagesWithContext(ctx aws.Context, input *secretsmanager.ListSecretsInput, fn func(*secretsmanager.ListSecretsOutput, bool) bool, options ...request.Option) error {
+	names := []string{}
+	for name, secret := range m.secrets {
+		if strings.Contains(name, *input.Filters[0].Values[0]) && !secret.scheduledForDelete {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		output := &secretsmanager.ListSecretsOutput{SecretList: []*secretsmanager.SecretListEntry{{Name: aws.String(name), Description: aws.String(m.secrets[name].description)}}}
+		if !fn(output, i == len(names)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockSecretsManagerTargetClient) CreateSecretWithContext(ctx aws.Context, input *secretsmanager.CreateSecretInput, options ...request.Option) (*secretsmanager.CreateSecretOutput, error) {
+	if m.createError != nil {
+		return nil, m.createError
+	}
+	if _, ok := m.secrets[*input.Name]; ok {
+		return nil, awserr.New(secretsmanager.ErrCodeInvalidRequestException, "You can't create this secret because a secret with this name is already scheduled for deletion.", nil)
+	}
+	secret := &mockSecretsManagerSecret{value: *input.SecretString, description: *input.Description, tags: map[string]string{}}
+	secret.kmsKeyID = aws.StringValue(input.KmsKeyId)
+	for _, tag := range input.Tags {
+		secret.tags[*tag.Key] = *tag.Value
+	}
+	m.secrets[*input.Name] = secret
+	return &secretsmanager.CreateSecretOutput{}, nil
+}
+
+func (m *mockSecretsManagerTargetClient) DescribeSecretWithContext(ctx aws.Context, input *secretsmanager.DescribeSecretInput, options ...request.Option) (*secretsmanager.DescribeSecretOutput, error) {
+	secret, ok := m.secrets[*input.SecretId]
+	if !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secrets Manager can't find the specified secret.", nil)
+	}
+	output := &secretsmanager.DescribeSecretOutput{Name: input.SecretId}
+	if secret.scheduledForDelete {
+		output.DeletedDate = aws.Time(time.Now())
+	}
+	return output, nil
+}
+
+func (m *mockSecretsManagerTargetClient) RestoreSecretWithContext(ctx aws.Context, input *secretsmanager.RestoreSecretInput, options ...request.Option) (*secretsmanager.RestoreSecretOutput, error) {
+	m.secrets[*input.SecretId].scheduledForDelete = false
+	return &secretsmanager.RestoreSecretOutput{}, nil
+}
+
+func (m *mockSecretsManagerTargetClient) UpdateSecretWithContext(ctx aws.Context, input *secretsmanager.UpdateSecretInput, options ...request.Option) (*secretsmanager.UpdateSecretOutput, error) {
+	secret, ok := m.secrets[*input.SecretId]
+	if !ok || secret.scheduledForDelete {
+		return nil, fmt.Errorf("ResourceNotFoundException")
+	}
+	secret.description = aws.StringValue(input.Description)
+	if input.SecretString != nil {
+		secret.value = *input.SecretString
+	}
+	if input.KmsKeyId != nil {
+		secret.kmsKeyID = *input.KmsKeyId
+	}
+	return &secretsmanager.UpdateSecretOutput{}, nil
+}
+
+func (m *mockSecretsManagerTargetClient) TagResourceWithContext(ctx aws.Context, input *secretsmanager.TagResourceInput, options ...request.Option) (*secretsmanager.TagResourceOutput, error) {
+	for _, tag := range input.Tags {
+		m.secrets[*input.SecretId].tags[*tag.Key] = *tag.Value
+	}
+	return &secretsmanager.TagResourceOutput{}, nil
+}
+
+func (m *mockSecretsManagerTargetClient) DeleteSecretWithContext(ctx aws.Context, input *secretsmanager.DeleteSecretInput, options ...request.Option) (*secretsmanager.DeleteSecretOutput, error) {
+	if aws.BoolValue(input.ForceDeleteWithoutRecovery) {
+		delete(m.secrets, *input.SecretId)
+	} else {
+		m.secrets[*input.SecretId].scheduledForDelete = true
+	}
+	return &secretsmanager.DeleteSecretOutput{}, nil
+}
+
+func TestAWSSecretsManagerTargetValidateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	zero, seven, forty := 0, 7, 40
+	cases := []struct {
+		name          string
+		target        *AWSSecretsManagerTarget
+		expectedError error
+	}{
+		{
+			name:          "Valid",
+			target:        &AWSSecretsManagerTarget{Base: Base{Name: "test"}, AWSBase: AWSBase{Prefix: "ci/"}, RecoveryWindowDays: &seven},
+			expectedError: nil,
+		},
+		{
+			name:          "Valid without recovery",
+			target:        &AWSSecretsManagerTarget{Base: Base{Name: "test"}, AWSBase: AWSBase{Prefix: "ci/"}, RecoveryWindowDays: &zero},
+			expectedError: nil,
+		},
+		{
+			name:          "Missing prefix",
+			target:        &AWSSecretsManagerTarget{Base: Base{Name: "test"}},
+			expectedError: fmt.Errorf("the Secrets Manager target `test` must define a `prefix`"),
+		},
+		{
+			name:          "Invalid recovery window",
+			target:        &AWSSecretsManagerTarget{Base: Base{Name: "test"}, AWSBase: AWSBase{Prefix: "ci/"}, RecoveryWindowDays: &forty},
+			expectedError: fmt.Errorf("the `recovery_window_days` of the Secrets Manager target `test` must be 0 or between 7 and 30"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.target.ValidateConfiguration())
+		})
+	}
+}
+
+func TestAWSSecretsManagerTargetSync(t *testing.T) {
+	t.Parallel()
+
+	client := &mockSecretsManagerTargetClient{secrets: map[string]*mockSecretsManagerSecret{
+		"ci/my-cred":           {value: "old", description: "Old description", tags: map[string]string{}},
+		"ci/other":             {value: "other", tags: map[string]string{}},
+		"ci/deleted":           {value: "deleted", tags: map[string]string{}, scheduledForDelete: true},
+		"other-prefix/ci/cred": {value: "not under the prefix", tags: map[string]string{}},
+		"not-under-the-prefix": {value: "not under the prefix", tags: map[string]string{}},
+	}}
+	target := &AWSSecretsManagerTarget{
+		Base:    Base{Name: "test", Tags: map[string]string{"team": "ci"}},
+		AWSBase: AWSBase{Prefix: "ci/", KMSKeyID: "my-key"},
+		client:  client,
+	}
+	assert.Equal(t, "test [Tags: team=ci] (Amazon SecretsManager) - ci/", target.ToString())
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.Username = "user"
+	userPass.Password = "pass"
+	secret := credentials.NewSecretText()
+	secret.ID = "slack"
+	secret.Secret = "xoxb"
+	deleted := credentials.NewSecretText()
+	deleted.ID = "deleted"
+	deleted.Secret = "restored"
+
+	assert.NoError(t, target.Initialize(context.Background(), nil))
+	assert.Equal(t, []string{"my-cred", "other"}, target.GetExistingCredentials())
+
+	description, err := target.GetCredentialsDescription(context.Background(), "my-cred")
+	assert.NoError(t, err)
+	assert.Equal(t, "Old description", description)
+	assert.NoError(t, target.SetCredentialsDescription(context.Background(), "other", "[unsynced] other"))
+	assert.Equal(t, "[unsynced] other", client.secrets["ci/other"].description)
+
+	for _, cred := range []credentials.Credentials{userPass, secret, deleted} {
+		assert.NoError(t, target.UpdateCredentials(context.Background(), cred))
+		expectedValue, _ := target.RenderCredentials(cred)
+		synced := client.secrets["ci/"+cred.GetID()]
+		assert.Equal(t, string(expectedValue), synced.value)
+		assert.Equal(t, cred.GetID(), synced.description)
+		assert.Equal(t, "my-key", synced.kmsKeyID)
+		assert.Equal(t, map[string]string{"team": "ci"}, synced.tags)
+		assert.False(t, synced.scheduledForDelete)
+	}
+
+	assert.NoError(t, target.DeleteCredentials(context.Background(), "other"))
+	assert.True(t, client.secrets["ci/other"].scheduledForDelete)
+	zero := 0
+	target.RecoveryWindowDays = &zero
+	assert.NoError(t, target.DeleteCredentials(context.Background(), "slack"))
+	assert.NotContains(t, client.secrets, "ci/slack")
+}
+
+func TestAWSSecretsManagerTargetCreateInvalidRequest(t *testing.T) {
+	t.Parallel()
+
+	createError := awserr.New(secretsmanager.ErrCodeInvalidRequestException, "The KMS key is disabled.", nil)
+	client := &mockSecretsManagerTargetClient{secrets: map[string]*mockSecretsManagerSecret{}, createError: createError}
+	target := &AWSSecretsManagerTarget{Base: Base{Name: "test"}, AWSBase: AWSBase{Prefix: "ci/"}, client: client}
+	assert.NoError(t, target.Initialize(context.Background(), nil))
+
+	secret := credentials.NewSecretText()
+	secret.ID = "slack"
+	secret.Secret = "xoxb"
+
+	// The secret is not scheduled for deletion, so the original error is returned instead of trying to restore it
+	assert.Equal(t, createError, target.UpdateCredentials(context.Background(), secret))
+}
@@ -0,0 +1,178 @@
+package targets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/coveooss/credentials-sync/credentials"
+)
+
+// AWSSSMTarget represents the SecureString parameters of an AWS account, under a path prefix
+// Each credentials is synced to a parameter named after the prefix and the credentials' target ID
+type AWSSSMTarget struct {
+	Base    `mapstructure:",squash"`
+	AWSBase `mapstructure:",squash"`
+
+	client              ssmiface.SSMAPI
+	existingCredentials []string
+	// Credentials target ID -> Description of the parameter
+	descriptions map[string]string
+	// Credentials target ID -> Metadata of the parameter, as listed on initialization
+	metadata map[string]*ssm.ParameterMetadata
+}
+
+func (target *AWSSSMTarget) parameterName(id string) string {
+	return target.Prefix + id
+}
+
+// Initialize executes all necessary operations to prepare the SSM target for sync
+func (target *AWSSSMTarget) Initialize(ctx context.Context, allCredentials []credentials.Credentials) error {
+	if target.client == nil {
+		target.client = ssm.New(target.newSession(target.Retry, target.Name))
+	}
+
+	target.existingCredentials = []string{}
+	target.descriptions = map[string]string{}
+	target.metadata = map[string]*ssm.ParameterMetadata{}
+	input := &ssm.DescribeParametersInput{
+		ParameterFilters: []*ssm.ParameterStringFilter{{Key: aws.String("Name"), Option: aws.String("BeginsWith"), Values: []*string{aws.String(target.Prefix)}}},
+	}
+	return target.client.DescribeParametersPagesWithContext(ctx, input, func(output *ssm.DescribeParametersOutput, lastPage bool) bool {
+		for _, parameter := range output.Parameters {
+			if id, found := strings.CutPrefix(aws.StringValue(parameter.Name), target.Prefix); found {
+				target.existingCredentials = append(target.existingCredentials, id)
+				target.descriptions[id] = aws.StringValue(parameter.Description)
+				target.metadata[id] = parameter
+			}
+		}
+		return !lastPage
+	})
+}
+
+// ToString prints out a description of the SSM target
+func (target *AWSSSMTarget) ToString() string {
+	return fmt.Sprintf("%s (Amazon SSM Parameter Store) - %s", target.BaseToString(), target.Prefix)
+}
+
+// GetExistingCredentials returns a list of all credential IDs on the target
+func (target *AWSSSMTarget) GetExistingCredentials() []string {
+	return target.existingCredentials
+}
+
+// DeleteCredentials deletes the parameter of the credentials with the given ID
+func (target *AWSSSMTarget) DeleteCredentials(ctx context.Context, id string) error {
+	_, err := target.client.DeleteParameterWithContext(ctx, &ssm.DeleteParameterInput{Name: aws.String(target.parameterName(id))})
+	return err
+}
+
+// GetCredentialsDescription returns the description of the parameter of the credentials with the given ID
+func (target *AWSSSMTarget) GetCredentialsDescription(ctx context.Context, id string) (string, error) {
+	description, ok := target.descriptions[id]
+	if !ok {
+		return "", fmt.Errorf("the parameter %s does not exist", target.parameterName(id))
+	}
+	return description, nil
+}
+
+// SetCredentialsDescription modifies the description of the parameter of the credentials with the given ID
+// Since the description can only be changed along with the value, the current value is sent back as is, with the
+// same KMS key and tier
+func (target *AWSSSMTarget) SetCredentialsDescription(ctx context.Context, id string, description string) error {
+	name := target.parameterName(id)
+	output, err := target.client.GetParameterWithContext(ctx, &ssm.GetParameterInput{Name: aws.String(name), WithDecryption: aws.Bool(true)})
+	if err != nil {
+		return err
+	}
+	input := &ssm.PutParameterInput{
+		Name:        aws.String(name),
+		Description: aws.String(description),
+		Value:       output.Parameter.Value,
+		Type:        output.Parameter.Type,
+		Overwrite:   aws.Bool(true),
+	}
+	if metadata, ok := target.metadata[id]; ok {
+		input.KeyId = metadata.KeyId
+		input.Tier = metadata.Tier
+	}
+	if input.KeyId == nil && aws.StringValue(input.Type) == ssm.ParameterTypeSecureString && target.KMSKeyID != "" {
+		input.KeyId = aws.String(target.KMSKeyID)
+	}
+	if _, err := target.client.PutParameterWithContext(ctx, input); err != nil {
+		return err
+	}
+	target.descriptions[id] = description
+	return nil
+}
+
+// RenderCredentials returns the value of the parameter of the given credentials
+func (target *AWSSSMTarget) RenderCredentials(cred credentials.Credentials) ([]byte, error) {
+	value, err := toAWSSecretValue(cred, target.markedDescription(cred))
+	return []byte(value), err
+}
+
+// UpdateCredentials syncs the given credentials to the Parameter Store
+// Tags can only be set when a parameter is created, so they are added separately when it is overwritten
+func (target *AWSSSMTarget) UpdateCredentials(ctx context.Context, cred credentials.Credentials) error {
+	description := target.markedDescription(cred)
+	value, err := toAWSSecretValue(cred, description)
+	if err != nil {
+		return err
+	}
+	name := target.parameterName(cred.GetTargetID())
+	exists := HasCredential(target, cred.GetTargetID())
+
+	input := &ssm.PutParameterInput{
+		Name:        aws.String(name),
+		Description: aws.String(description),
+		Value:       aws.String(value),
+		Type:        aws.String(ssm.ParameterTypeSecureString),
+		// Parameters larger than 4 KB, such as some private keys, require the advanced tier
+		Tier: aws.String(ssm.ParameterTierIntelligentTiering),
+	}
+	if target.KMSKeyID != "" {
+		input.KeyId = aws.String(target.KMSKeyID)
+	}
+	if exists {
+		input.Overwrite = aws.Bool(true)
+	} else {
+		input.Tags = target.tags()
+	}
+	if _, err := target.client.PutParameterWithContext(ctx, input); err != nil {
+		return err
+	}
+	if exists && len(target.Tags) > 0 {
+		if _, err := target.client.AddTagsToResourceWithContext(ctx, &ssm.AddTagsToResourceInput{
+			ResourceId:   aws.String(name),
+			ResourceType: aws.String(ssm.ResourceTypeForTaggingParameter),
+			Tags:         target.tags(),
+		}); err != nil {
+			return err
+		}
+	}
+	target.descriptions[cred.GetTargetID()] = description
+	return nil
+}
+
+// tags returns the tags of the parameters, which are the target's tags
+func (target *AWSSSMTarget) tags() []*ssm.Tag {
+	var tags []*ssm.Tag
+	for _, key := range sortedTagKeys(target.Tags) {
+		tags = append(tags, &ssm.Tag{Key: aws.String(key), Value: aws.String(target.Tags[key])})
+	}
+	return tags
+}
+
+// ValidateConfiguration verifies that SSM configuration is valid
+func (target *AWSSSMTarget) ValidateConfiguration() error {
+	if err := target.validateAWSBase("SSM", target.Name); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(target.Prefix, "/") {
+		return fmt.Errorf("the `prefix` of the SSM target `%s` must start with a `/`", target.Name)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package targets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSSMTargetClient struct {
+	ssmiface.SSMAPI
+	t          *testing.T
+	parameters map[string]*ssm.PutParameterInput
+	tags       map[string]map[string]string
+}
+
+func (m *mockSSMTargetClient) DescribeParametersPagesWithContext(ctx aws.Context, input *ssm.DescribeParametersInput, fn func(*ssm.DescribeParametersOutput, bool) bool, options ...request.Option) error {
+	assert.Equal(m.t, "BeginsWith", *input.ParameterFilters[0].Option)
+	names := []string{}
+	for name := range m.parameters {
+		if strings.HasPrefix(name, *input.ParameterFilters[0].Values[0]) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		output := &ssm.DescribeParametersOutput{Parameters: []*ssm.ParameterMetadata{{Name: aws.String(name), Description: m.parameters[name].Description, KeyId: m.parameters[name].KeyId, Tier: m.parameters[name].Tier}}}
+		if !fn(output, i == len(names)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockSSMTargetClient) GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, options ...request.Option) (*ssm.GetParameterOutput, error) {
+	parameter, ok := m.parameters[*input.Name]
+	if !ok {
+		return nil, fmt.Errorf("ParameterNotFound")
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Name: input.Name, Value: parameter.Value, Type: parameter.Type}}, nil
+}
+
+func (m *mockSSMTargetClient) PutParameterWithContext(ctx aws.Context, input *ssm.PutParameterInput, options ...request.Option) (*ssm.PutParameterOutput, error) {
+	_, exists := m.parameters[*input.Name]
+	if exists && !aws.BoolValue(input.Overwrite) {
+		return nil, fmt.Errorf("ParameterAlreadyExists")
+	}
+	if aws.BoolValue(input.Overwrite) && input.Tags != nil {
+		return nil, fmt.Errorf("ValidationException: tags and overwrite can't be used together")
+	}
+	m.parameters[*input.Name] = input
+	if !exists {
+		m.tags[*input.Name] = map[string]string{}
+	}
+	for _, tag := range input.Tags {
+		m.tags[*input.Name][*tag.Key] = *tag.Value
+	}
+	return &ssm.PutParameterOutput{}, nil
+}
+
+func (m *mockSSMTargetClient) AddTagsToResourceWithContext(ctx aws.Context, input *ssm.AddTagsToResourceInput, options ...request.Option) (*ssm.AddTagsToResourceOutput, error) {
+	for _, tag := range input.Tags {
+		m.tags[*input.ResourceId][*tag.Key] = *tag.Value
+	}
+	return &ssm.AddTagsToResourceOutput{}, nil
+}
+
+func (m *mockSSMTargetClient) DeleteParameterWithContext(ctx aws.Context, input *ssm.DeleteParameterInput, options ...request.Option) (*ssm.DeleteParameterOutput, error) {
+	delete(m.parameters, *input.Name)
+	return &ssm.DeleteParameterOutput{}, nil
+}
+
+func TestAWSSSMTargetValidateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		target        *AWSSSMTarget
+		expectedError error
+	}{
+		{
+			name:          "Valid",
+			target:        &AWSSSMTarget{Base: Base{Name: "test"}, AWSBase: AWSBase{Prefix: "/ci/"}},
+			expectedError: nil,
+		},
+		{
+			name:          "Missing prefix",
+			target:        &AWSSSMTarget{Base: Base{Name: "test"}},
+			expectedError: fmt.Errorf("the SSM target `test` must define a `prefix`"),
+		},
+		{
+			name:          "Relative prefix",
+			target:        &AWSSSMTarget{Base: Base{Name: "test"}, AWSBase: AWSBase{Prefix: "ci/"}},
+			expectedError: fmt.Errorf("the `prefix` of the SSM target `test` must start with a `/`"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.target.ValidateConfiguration())
+		})
+	}
+}
+
+func TestAWSSSMTargetSync(t *testing.T) {
+	t.Parallel()
+
+	client := &mockSSMTargetClient{
+		t: t,
+		parameters: map[string]*ssm.PutParameterInput{
+			"/ci/my-cred": {Name: aws.String("/ci/my-cred"), Value: aws.String("old"), Description: aws.String("Old description"), Type: aws.String(ssm.ParameterTypeSecureString)},
+			"/ci/other":   {Name: aws.String("/ci/other"), Value: aws.String("other"), Type: aws.String(ssm.ParameterTypeSecureString), KeyId: aws.String("other-key"), Tier: aws.String(ssm.ParameterTierAdvanced)},
+			"/other":      {Name: aws.String("/other"), Value: aws.String("not under the prefix"), Type: aws.String(ssm.ParameterTypeString)},
+		},
+		tags: map[string]map[string]string{"/ci/my-cred": {}, "/ci/other": {}, "/other": {}},
+	}
+	target := &AWSSSMTarget{
+		Base:    Base{Name: "test", Tags: map[string]string{"team": "ci"}},
+		AWSBase: AWSBase{Prefix: "/ci/", KMSKeyID: "my-key"},
+		client:  client,
+	}
+	assert.Equal(t, "test [Tags: team=ci] (Amazon SSM Parameter Store) - /ci/", target.ToString())
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.Username = "user"
+	userPass.Password = "pass"
+	secret := credentials.NewSecretText()
+	secret.ID = "slack"
+	secret.Secret = "xoxb"
+
+	assert.NoError(t, target.Initialize(context.Background(), nil))
+	assert.Equal(t, []string{"my-cred", "other"}, target.GetExistingCredentials())
+
+	description, err := target.GetCredentialsDescription(context.Background(), "my-cred")
+	assert.NoError(t, err)
+	assert.Equal(t, "Old description", description)
+	assert.NoError(t, target.SetCredentialsDescription(context.Background(), "other", "[unsynced] other"))
+	assert.Equal(t, "[unsynced] other", *client.parameters["/ci/other"].Description)
+	assert.Equal(t, "other", *client.parameters["/ci/other"].Value)
+	// The parameter is not re-encrypted with another key, nor moved to another tier
+	assert.Equal(t, "other-key", *client.parameters["/ci/other"].KeyId)
+	assert.Equal(t, ssm.ParameterTierAdvanced, *client.parameters["/ci/other"].Tier)
+
+	for _, cred := range []credentials.Credentials{userPass, secret} {
+		assert.NoError(t, target.UpdateCredentials(context.Background(), cred))
+		expectedValue, _ := target.RenderCredentials(cred)
+		synced := client.parameters["/ci/"+cred.GetID()]
+		assert.Equal(t, string(expectedValue), *synced.Value)
+		assert.Equal(t, cred.GetID(), *synced.Description)
+		assert.Equal(t, ssm.ParameterTypeSecureString, *synced.Type)
+		assert.Equal(t, "my-key", *synced.KeyId)
+		assert.Equal(t, map[string]string{"team": "ci"}, client.tags["/ci/"+cred.GetID()])
+	}
+
+	assert.NoError(t, target.DeleteCredentials(context.Background(), "other"))
+	assert.NotContains(t, client.parameters, "/ci/other")
+}
@@ -0,0 +1,64 @@
+package targets
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToAWSSecretValue(t *testing.T) {
+	t.Parallel()
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.TargetID = "target-id"
+	userPass.Username = "user"
+	userPass.Password = "pass"
+	value, err := toAWSSecretValue(userPass, "A description")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": "target-id", "type": "usernamepassword", "description": "A description", "username": "user", "password": "pass"}`, value)
+
+	aws := credentials.NewAmazonWebServicesCredentials()
+	aws.ID = "aws"
+	aws.AccessKey = "AKIA"
+	aws.SecretKey = "secret"
+	value, err = toAWSSecretValue(aws, "aws")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": "aws", "type": "aws", "description": "aws", "access_key": "AKIA", "secret_key": "secret"}`, value)
+
+	app := credentials.NewGithubAppCredentials()
+	app.ID = "app"
+	app.AppID = 1234
+	app.PrivateKey = "key"
+	value, err = toAWSSecretValue(app, "app")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": "app", "type": "github_app", "description": "app", "app_id": 1234, "private_key": "key"}`, value)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	unknownCredentials := credentials.NewMockCredentials(ctrl)
+	unknownCredentials.EXPECT().GetID().Return("unknown")
+	_, err = toAWSSecretValue(unknownCredentials, "")
+	assert.EqualError(t, err, "unable to serialize the credentials with ID unknown")
+}
+
+func TestAWSSecretValueCanBeReadAsCredentials(t *testing.T) {
+	t.Parallel()
+
+	ssh := credentials.NewSSHCredentials()
+	ssh.ID = "ssh"
+	ssh.Description = "A description"
+	ssh.Username = "git"
+	ssh.PrivateKey = "key"
+	value, err := toAWSSecretValue(ssh, ssh.Description)
+	assert.NoError(t, err)
+
+	credentialsMap := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(value), &credentialsMap))
+	parsedCredentials, err := credentials.ParseSingleCredentials(credentialsMap)
+	assert.NoError(t, err)
+	assert.Equal(t, ssh, parsedCredentials)
+}
@@ -0,0 +1,371 @@
+package targets
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeyaml "sigs.k8s.io/yaml"
+)
+
+const (
+	fileFormatDotenv     = "dotenv"
+	fileFormatJSON       = "json"
+	fileFormatYAML       = "yaml"
+	fileFormatKubernetes = "kubernetes"
+)
+
+// FileTarget represents a file on disk to which credentials are rendered
+// In the `json` and `yaml` formats, the file is a map of credentials documents (in the format of the sources)
+// In the `dotenv` and `kubernetes` formats, each credentials is rendered to environment variables (in a `.env` file or
+// in the data of a Secret manifest)
+// The changes are buffered, the whole file is rewritten atomically once they are all applied (see FlushChanges)
+// Entries that are not synced are kept unless they are deleted
+type FileTarget struct {
+	Base `mapstructure:",squash"`
+
+	Path   string `mapstructure:"path"`
+	Format string `mapstructure:"format"`
+	// Name and namespace of the secret, in the `kubernetes` format
+	SecretName string `mapstructure:"secret_name"`
+	Namespace  string `mapstructure:"namespace"`
+
+	existingCredentials []string
+	// Credentials target ID -> Credentials document, in the `json` and `yaml` formats
+	documents map[string]map[string]interface{}
+	// Variable name -> Value, in the `dotenv` and `kubernetes` formats
+	variables          map[string]string
+	credentialsEntries map[string][]string
+	// True if entries were modified since the file was last written
+	modified bool
+}
+
+func (file *FileTarget) hasDocuments() bool {
+	return file.Format == fileFormatJSON || file.Format == fileFormatYAML
+}
+
+// Initialize reads the existing entries of the file, if it exists
+func (file *FileTarget) Initialize(ctx context.Context, allCredentials []credentials.Credentials) error {
+	content, err := os.ReadFile(file.Path)
+	if os.IsNotExist(err) {
+		content = nil
+	} else if err != nil {
+		return fmt.Errorf("unable to read %s: %v", file.Path, err)
+	}
+
+	if file.hasDocuments() {
+		file.documents = map[string]map[string]interface{}{}
+		if err := yaml.Unmarshal(content, &file.documents); err != nil {
+			return fmt.Errorf("unable to parse %s: %v", file.Path, err)
+		}
+		if file.documents == nil {
+			file.documents = map[string]map[string]interface{}{}
+		}
+		file.existingCredentials = []string{}
+		for id := range file.documents {
+			file.existingCredentials = append(file.existingCredentials, id)
+		}
+		sort.Strings(file.existingCredentials)
+		return nil
+	}
+
+	if file.Format == fileFormatKubernetes {
+		file.variables, err = parseSecretManifest(content)
+	} else {
+		file.variables, err = parseDotenv(content)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %v", file.Path, err)
+	}
+	existingEntries := map[string]bool{}
+	for name := range file.variables {
+		existingEntries[name] = true
+	}
+	file.existingCredentials, file.credentialsEntries = groupExistingCredentials(syncedCredentials(file, allCredentials), existingEntries, func(creds credentials.Credentials) []string {
+		// Credentials that cannot be converted have no entries, the error is returned when they are synced
+		variables, err := toEnvironmentVariables(creds)
+		if err != nil {
+			return nil
+		}
+		names := []string{}
+		for name := range variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	})
+	return nil
+}
+
+// ToString prints out a description of the file target
+func (file *FileTarget) ToString() string {
+	return fmt.Sprintf("%s (File) - %s (%s)", file.BaseToString(), file.Path, file.Format)
+}
+
+// GetExistingCredentials returns a list of all credential IDs on the target
+func (file *FileTarget) GetExistingCredentials() []string {
+	return file.existingCredentials
+}
+
+// DeleteCredentials removes the entries of the credentials with the given ID from the file
+func (file *FileTarget) DeleteCredentials(ctx context.Context, id string) error {
+	file.modified = true
+	if file.hasDocuments() {
+		delete(file.documents, id)
+		return nil
+	}
+	for _, name := range file.entriesOf(id) {
+		delete(file.variables, name)
+	}
+	return nil
+}
+
+// entriesOf returns the existing environment variables of the credentials with the given ID, in the `dotenv` and
+// `kubernetes` formats. Credentials are stored as a single document in the other formats
+func (file *FileTarget) entriesOf(id string) []string {
+	if file.hasDocuments() {
+		return nil
+	}
+	return groupedEntriesOf(id, file.credentialsEntries, func(name string) bool {
+		_, ok := file.variables[name]
+		return ok
+	})
+}
+
+// GetCredentialsDescription returns the description of the credentials with the given ID, in the `json` and `yaml` formats
+func (file *FileTarget) GetCredentialsDescription(ctx context.Context, id string) (string, error) {
+	if !file.hasDocuments() {
+		return file.Base.GetCredentialsDescription(ctx, id)
+	}
+	document, ok := file.documents[id]
+	if !ok {
+		return "", fmt.Errorf("the credentials with ID %s are not in %s", id, file.Path)
+	}
+	description, _ := document["description"].(string)
+	return description, nil
+}
+
+// SetCredentialsDescription modifies the description of the credentials with the given ID, in the `json` and `yaml` formats
+func (file *FileTarget) SetCredentialsDescription(ctx context.Context, id string, description string) error {
+	if !file.hasDocuments() {
+		return file.Base.SetCredentialsDescription(ctx, id, description)
+	}
+	document, ok := file.documents[id]
+	if !ok {
+		return fmt.Errorf("the credentials with ID %s are not in %s", id, file.Path)
+	}
+	document["description"] = description
+	file.modified = true
+	return nil
+}
+
+// RenderCredentials returns the entries of the file that are written for the given credentials
+func (file *FileTarget) RenderCredentials(cred credentials.Credentials) ([]byte, error) {
+	if file.hasDocuments() {
+		document, err := file.toDocument(cred)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(document)
+	}
+	variables, err := toEnvironmentVariables(cred)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create environment variables from %s: %v", cred.GetID(), err)
+	}
+	return json.Marshal(variables)
+}
+
+// toDocument returns the credentials document of the given credentials. The ID is the key of the document in the file
+func (file *FileTarget) toDocument(cred credentials.Credentials) (map[string]interface{}, error) {
+	document, err := toCredentialsDocument(cred, file.markedDescription(cred))
+	if err != nil {
+		return nil, err
+	}
+	delete(document, "id")
+	return document, nil
+}
+
+// UpdateCredentials writes the given credentials to the file
+func (file *FileTarget) UpdateCredentials(ctx context.Context, cred credentials.Credentials) error {
+	if file.hasDocuments() {
+		document, err := file.toDocument(cred)
+		if err != nil {
+			return err
+		}
+		file.documents[cred.GetTargetID()] = document
+		file.modified = true
+		return nil
+	}
+
+	variables, err := toEnvironmentVariables(cred)
+	if err != nil {
+		return fmt.Errorf("unable to create environment variables from %s: %v", cred.GetID(), err)
+	}
+	for name, value := range variables {
+		if value == "" {
+			delete(file.variables, name)
+		} else {
+			file.variables[name] = value
+		}
+	}
+	file.modified = true
+	return nil
+}
+
+// FlushChanges writes the file if its entries were modified since it was last written
+func (file *FileTarget) FlushChanges(ctx context.Context) error {
+	if !file.modified {
+		return nil
+	}
+	if err := file.write(); err != nil {
+		return err
+	}
+	file.modified = false
+	return nil
+}
+
+// write renders all entries and atomically replaces the file, which is only readable by its owner
+func (file *FileTarget) write() error {
+	var (
+		content []byte
+		err     error
+	)
+	switch file.Format {
+	case fileFormatJSON:
+		content, err = json.MarshalIndent(file.documents, "", "  ")
+		content = append(content, '\n')
+	case fileFormatYAML:
+		content, err = yaml.Marshal(file.documents)
+	case fileFormatKubernetes:
+		content, err = file.renderSecretManifest()
+	default:
+		content = renderDotenv(file.variables)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to render %s: %v", file.Path, err)
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(file.Path), "."+filepath.Base(file.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	if err := tempFile.Chmod(0600); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if _, err := tempFile.Write(content); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), file.Path)
+}
+
+func (file *FileTarget) renderSecretManifest() ([]byte, error) {
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      file.SecretName,
+			Namespace: file.Namespace,
+			Labels:    map[string]string{kubernetesManagedByLabel: kubernetesManagedByValue},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{},
+	}
+	for name, value := range file.variables {
+		secret.Data[name] = []byte(value)
+	}
+	return kubeyaml.Marshal(secret)
+}
+
+// parseSecretManifest returns the data of the given Secret manifest
+func parseSecretManifest(content []byte) (map[string]string, error) {
+	secret := &corev1.Secret{}
+	if err := kubeyaml.Unmarshal(content, secret); err != nil {
+		return nil, err
+	}
+	variables := map[string]string{}
+	for name, value := range secret.Data {
+		variables[name] = string(value)
+	}
+	for name, value := range secret.StringData {
+		variables[name] = value
+	}
+	return variables, nil
+}
+
+var dotenvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+var dotenvUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r")
+
+// renderDotenv renders the given variables as sorted `NAME="value"` lines
+func renderDotenv(variables map[string]string) []byte {
+	names := []string{}
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&builder, "%s=\"%s\"\n", name, dotenvEscaper.Replace(variables[name]))
+	}
+	return []byte(builder.String())
+}
+
+// parseDotenv returns the variables of the given `.env` file. Blank lines and comments are ignored
+// Values can be unquoted, single-quoted (taken literally) or double-quoted (with escape sequences)
+func parseDotenv(content []byte) (map[string]string, error) {
+	variables := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !found {
+			return nil, fmt.Errorf("line %d is not a variable assignment", lineNumber)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = dotenvUnescaper.Replace(value[1 : len(value)-1])
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		}
+		variables[strings.TrimSpace(name)] = value
+	}
+	return variables, scanner.Err()
+}
+
+// ValidateConfiguration verifies that the file target's configuration is valid
+func (file *FileTarget) ValidateConfiguration() error {
+	if file.Path == "" {
+		return fmt.Errorf("the file target `%s` must define a `path`", file.Name)
+	}
+	switch file.Format {
+	case fileFormatJSON, fileFormatYAML:
+	case fileFormatDotenv, fileFormatKubernetes:
+		if file.TagUnsynced || file.DeleteOnlyMarked {
+			return fmt.Errorf("the file target `%s` does not support `tag_unsynced` and `delete_only_marked` in the %s format, since variables have no description", file.Name, file.Format)
+		}
+	default:
+		return fmt.Errorf("the `format` of the file target `%s` must be one of `dotenv`, `json`, `yaml` or `kubernetes`", file.Name)
+	}
+	if file.Format == fileFormatKubernetes && file.SecretName == "" {
+		return fmt.Errorf("the file target `%s` must define a `secret_name` in the kubernetes format", file.Name)
+	}
+	return nil
+}
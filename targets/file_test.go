@@ -0,0 +1,238 @@
+package targets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileTargetValidateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		target        *FileTarget
+		expectedError error
+	}{
+		{
+			name:          "Valid",
+			target:        &FileTarget{Base: Base{Name: "test"}, Path: "creds.json", Format: "json"},
+			expectedError: nil,
+		},
+		{
+			name:          "Missing path",
+			target:        &FileTarget{Base: Base{Name: "test"}, Format: "json"},
+			expectedError: fmt.Errorf("the file target `test` must define a `path`"),
+		},
+		{
+			name:          "Unknown format",
+			target:        &FileTarget{Base: Base{Name: "test"}, Path: "creds.toml", Format: "toml"},
+			expectedError: fmt.Errorf("the `format` of the file target `test` must be one of `dotenv`, `json`, `yaml` or `kubernetes`"),
+		},
+		{
+			name:          "Tag unsynced in dotenv",
+			target:        &FileTarget{Base: Base{Name: "test", TagUnsynced: true}, Path: ".env", Format: "dotenv"},
+			expectedError: fmt.Errorf("the file target `test` does not support `tag_unsynced` and `delete_only_marked` in the dotenv format, since variables have no description"),
+		},
+		{
+			name:          "Tag unsynced in yaml",
+			target:        &FileTarget{Base: Base{Name: "test", TagUnsynced: true}, Path: "creds.yaml", Format: "yaml"},
+			expectedError: nil,
+		},
+		{
+			name:          "Missing secret name",
+			target:        &FileTarget{Base: Base{Name: "test"}, Path: "secret.yaml", Format: "kubernetes"},
+			expectedError: fmt.Errorf("the file target `test` must define a `secret_name` in the kubernetes format"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.target.ValidateConfiguration())
+		})
+	}
+}
+
+func TestFileTargetSync(t *testing.T) {
+	t.Parallel()
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.Username = "user"
+	userPass.Password = "pa\"ss"
+	secret := credentials.NewSecretText()
+	secret.ID = "slack"
+	secret.TargetID = "slack-token"
+	secret.Secret = "line1\nline2"
+
+	cases := []struct {
+		format           string
+		existingContent  string
+		expectedExisting []string
+		expectedContent  string
+	}{
+		{
+			format:           "json",
+			existingContent:  `{"other": {"type": "secret", "secret": "other"}, "slack-token": {"type": "secret", "secret": "old"}}`,
+			expectedExisting: []string{"other", "slack-token"},
+			expectedContent: `{
+  "my-cred": {
+    "description": "my-cred",
+    "password": "pa\"ss",
+    "type": "usernamepassword",
+    "username": "user"
+  },
+  "other": {
+    "secret": "other",
+    "type": "secret"
+  },
+  "slack-token": {
+    "description": "slack",
+    "secret": "line1\nline2",
+    "type": "secret"
+  }
+}
+`,
+		},
+		{
+			format:           "yaml",
+			existingContent:  "other:\n  type: secret\n  secret: other\n",
+			expectedExisting: []string{"other"},
+			expectedContent: `my-cred:
+    description: my-cred
+    password: pa"ss
+    type: usernamepassword
+    username: user
+other:
+    secret: other
+    type: secret
+slack-token:
+    description: slack
+    secret: |-
+        line1
+        line2
+    type: secret
+`,
+		},
+		{
+			format:           "dotenv",
+			existingContent:  "# Comment\nexport OTHER='other'\nMY_CRED_USERNAME=old\n",
+			expectedExisting: []string{"OTHER", "my-cred"},
+			expectedContent: `MY_CRED_PASSWORD="pa\"ss"
+MY_CRED_USERNAME="user"
+OTHER="other"
+SLACK_TOKEN="line1\nline2"
+`,
+		},
+		{
+			format:           "kubernetes",
+			existingContent:  "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\nstringData:\n  OTHER: other\n",
+			expectedExisting: []string{"OTHER"},
+			expectedContent: `apiVersion: v1
+data:
+  MY_CRED_PASSWORD: cGEic3M=
+  MY_CRED_USERNAME: dXNlcg==
+  OTHER: b3RoZXI=
+  SLACK_TOKEN: bGluZTEKbGluZTI=
+kind: Secret
+metadata:
+  labels:
+    app.kubernetes.io/managed-by: credentials-sync
+  name: creds
+  namespace: ci
+type: Opaque
+`,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "credentials")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.existingContent), 0644))
+			target := &FileTarget{Base: Base{Name: "test"}, Path: path, Format: tt.format, SecretName: "creds", Namespace: "ci"}
+			allCredentials := []credentials.Credentials{userPass, secret}
+
+			assert.NoError(t, target.Initialize(context.Background(), allCredentials))
+			assert.Equal(t, tt.expectedExisting, target.GetExistingCredentials())
+			for _, cred := range allCredentials {
+				assert.NoError(t, target.UpdateCredentials(context.Background(), cred))
+			}
+
+			// The changes are only written when they are flushed
+			content, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.existingContent, string(content))
+			assert.NoError(t, target.FlushChanges(context.Background()))
+			content, err = os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedContent, string(content))
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+			// The written file is read back as is
+			readTarget := &FileTarget{Base: Base{Name: "test"}, Path: path, Format: tt.format, SecretName: "creds", Namespace: "ci"}
+			assert.NoError(t, readTarget.Initialize(context.Background(), allCredentials))
+			assert.Len(t, readTarget.GetExistingCredentials(), 3)
+			for _, cred := range allCredentials {
+				assert.NoError(t, readTarget.DeleteCredentials(context.Background(), cred.GetTargetID()))
+			}
+			assert.NoError(t, readTarget.FlushChanges(context.Background()))
+			assert.NoError(t, readTarget.Initialize(context.Background(), allCredentials))
+			assert.Equal(t, tt.expectedExisting[:1], readTarget.GetExistingCredentials())
+		})
+	}
+}
+
+func TestFileTargetSyncFilesAndCertificates(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".env")
+	target := &FileTarget{Base: Base{Name: "test"}, Path: path, Format: "dotenv"}
+	secretFile, cert, keystore := newTestFileCredentials(t)
+
+	assert.NoError(t, target.Initialize(context.Background(), []credentials.Credentials{secretFile, cert}))
+	assert.NoError(t, target.UpdateCredentials(context.Background(), secretFile))
+	assert.NoError(t, target.UpdateCredentials(context.Background(), cert))
+	assert.NoError(t, target.FlushChanges(context.Background()))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("CLIENT_CERT_KEYSTORE=%q\nCLIENT_CERT_PASSWORD=\"hunter42\"\nKUBECONFIG=\"apiVersion: v1\\nkind: Config\"\n", keystore), string(content))
+}
+
+func TestFileTargetDescriptions(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	target := &FileTarget{Base: Base{Name: "test"}, Path: path, Format: "yaml"}
+	assert.NoError(t, target.Initialize(context.Background(), nil))
+	assert.Empty(t, target.GetExistingCredentials())
+
+	secret := credentials.NewSecretText()
+	secret.ID = "slack"
+	secret.Description = "Slack token"
+	secret.Secret = "xoxb"
+	assert.NoError(t, target.UpdateCredentials(context.Background(), secret))
+
+	description, err := target.GetCredentialsDescription(context.Background(), "slack")
+	assert.NoError(t, err)
+	assert.Equal(t, "Slack token", description)
+	assert.NoError(t, target.SetCredentialsDescription(context.Background(), "slack", "[unsynced] Slack token"))
+	assert.NoError(t, target.FlushChanges(context.Background()))
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "slack:\n    description: '[unsynced] Slack token'\n    secret: xoxb\n    type: secret\n", string(content))
+
+	_, err = target.GetCredentialsDescription(context.Background(), "unknown")
+	assert.EqualError(t, err, fmt.Sprintf("the credentials with ID unknown are not in %s", path))
+
+	dotenvTarget := &FileTarget{Base: Base{Name: "test"}, Path: filepath.Join(t.TempDir(), ".env"), Format: "dotenv"}
+	_, err = dotenvTarget.GetCredentialsDescription(context.Background(), "slack")
+	assert.EqualError(t, err, "the target `test` does not support credentials descriptions")
+}
@@ -0,0 +1,355 @@
+package targets
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/retry"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// defaultGithubAPIURL is the API of github.com, used by the GitHub target and by the GitHub App credentials synced to Jenkins
+const defaultGithubAPIURL = "https://api.github.com"
+
+// GithubTarget represents the GitHub Actions secrets of a repository, of an environment of a repository or of an organization
+// Each credentials is synced as one or more secrets, named after the credentials' target ID
+type GithubTarget struct {
+	Base `mapstructure:",squash"`
+
+	// ID of the `github_app` or `secret` (token) credentials used to authenticate
+	CredentialsID string `mapstructure:"credentials_id"`
+	APIURL        string `mapstructure:"api_url"`
+	Owner         string `mapstructure:"owner"`
+	// Organization secrets are synced if no repository is set
+	Repository  string `mapstructure:"repository"`
+	Environment string `mapstructure:"environment"`
+	// Visibility of organization secrets: `all` or `private` (the default)
+	Visibility string `mapstructure:"visibility"`
+
+	client              *http.Client
+	token               string
+	publicKey           *githubPublicKey
+	existingCredentials []string
+	existingSecrets     map[string]bool
+	// Credentials target ID -> Names of all the secrets that the credentials may be synced to
+	credentialsSecrets map[string][]string
+}
+
+type githubPublicKey struct {
+	KeyID string `json:"key_id"`
+	Key   string `json:"key"`
+}
+
+func (github *GithubTarget) getAPIURL() string {
+	if github.APIURL == "" {
+		return defaultGithubAPIURL
+	}
+	return strings.TrimSuffix(github.APIURL, "/")
+}
+
+// secretsPath returns the path of the API endpoints managing the target's secrets
+func (github *GithubTarget) secretsPath() string {
+	switch {
+	case github.Repository == "":
+		return fmt.Sprintf("/orgs/%s/actions/secrets", url.PathEscape(github.Owner))
+	case github.Environment != "":
+		return fmt.Sprintf("/repos/%s/%s/environments/%s/secrets", url.PathEscape(github.Owner), url.PathEscape(github.Repository), url.PathEscape(github.Environment))
+	default:
+		return fmt.Sprintf("/repos/%s/%s/actions/secrets", url.PathEscape(github.Owner), url.PathEscape(github.Repository))
+	}
+}
+
+// request sends a request to the GitHub API and decodes the JSON response into the given result, if it is not nil
+func (github *GithubTarget) request(ctx context.Context, method string, path string, authorization string, body interface{}, result interface{}) error {
+	var bodyReader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		bodyReader = bytes.NewReader(payload)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, github.getAPIURL()+path, bodyReader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("Authorization", authorization)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := github.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		responseBody, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("%s %s: invalid response code %d: %s", method, path, response.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	if result != nil {
+		return json.NewDecoder(response.Body).Decode(result)
+	}
+	return nil
+}
+
+func (github *GithubTarget) apiRequest(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	return github.request(ctx, method, path, "Bearer "+github.token, body, result)
+}
+
+// Initialize executes all necessary operations to prepare the GitHub target for sync
+func (github *GithubTarget) Initialize(ctx context.Context, allCredentials []credentials.Credentials) error {
+	github.client = &http.Client{Transport: &retry.Transport{Base: http.DefaultTransport, Policy: github.Retry, Name: github.Name}}
+
+	var loginCredentials credentials.Credentials
+	for _, creds := range allCredentials {
+		if creds.GetID() == github.CredentialsID {
+			loginCredentials = creds
+		}
+	}
+	if err := github.login(ctx, loginCredentials); err != nil {
+		return err
+	}
+
+	github.publicKey = &githubPublicKey{}
+	if err := github.apiRequest(ctx, http.MethodGet, github.secretsPath()+"/public-key", nil, github.publicKey); err != nil {
+		return fmt.Errorf("unable to fetch the public key of the secrets: %v", err)
+	}
+
+	github.existingSecrets = map[string]bool{}
+	for page := 1; ; page++ {
+		var result struct {
+			TotalCount int `json:"total_count"`
+			Secrets    []struct {
+				Name string `json:"name"`
+			} `json:"secrets"`
+		}
+		if err := github.apiRequest(ctx, http.MethodGet, fmt.Sprintf("%s?per_page=100&page=%d", github.secretsPath(), page), nil, &result); err != nil {
+			return fmt.Errorf("unable to list the secrets: %v", err)
+		}
+		for _, secret := range result.Secrets {
+			github.existingSecrets[secret.Name] = true
+		}
+		if len(result.Secrets) == 0 || len(github.existingSecrets) >= result.TotalCount {
+			break
+		}
+	}
+
+	// Secrets are reported as the credentials they were synced from, so that they can be compared with the source credentials
+	github.existingCredentials, github.credentialsSecrets = groupExistingCredentials(syncedCredentials(github, allCredentials), github.existingSecrets, func(creds credentials.Credentials) []string {
+		// Credentials that cannot be converted have no secrets, the error is returned when they are synced
+		secrets, err := toEnvironmentVariables(creds)
+		if err != nil {
+			return nil
+		}
+		names := []string{}
+		for name := range secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	})
+
+	return nil
+}
+
+// login obtains the token used to call the API from the given credentials
+// A GitHub App is authenticated with an installation token of the target's owner
+func (github *GithubTarget) login(ctx context.Context, loginCredentials credentials.Credentials) error {
+	switch castCreds := loginCredentials.(type) {
+	case *credentials.SecretTextCredentials:
+		github.token = castCreds.Secret
+		return nil
+	case *credentials.GithubAppCredentials:
+		jwt, err := githubAppJWT(castCreds, time.Now())
+		if err != nil {
+			return fmt.Errorf("unable to create a token for the GitHub App %d: %v", castCreds.AppID, err)
+		}
+		installationPath := fmt.Sprintf("/orgs/%s/installation", url.PathEscape(github.Owner))
+		if github.Repository != "" {
+			installationPath = fmt.Sprintf("/repos/%s/%s/installation", url.PathEscape(github.Owner), url.PathEscape(github.Repository))
+		}
+		var installation struct {
+			ID int64 `json:"id"`
+		}
+		if err := github.request(ctx, http.MethodGet, installationPath, "Bearer "+jwt, nil, &installation); err != nil {
+			return fmt.Errorf("unable to find the installation of the GitHub App %d: %v", castCreds.AppID, err)
+		}
+		var accessToken struct {
+			Token string `json:"token"`
+		}
+		if err := github.request(ctx, http.MethodPost, fmt.Sprintf("/app/installations/%d/access_tokens", installation.ID), "Bearer "+jwt, nil, &accessToken); err != nil {
+			return fmt.Errorf("unable to create an installation token for the GitHub App %d: %v", castCreds.AppID, err)
+		}
+		github.token = accessToken.Token
+		return nil
+	case nil:
+		return fmt.Errorf("the credentials with ID %s, used to log in to GitHub, were not found", github.CredentialsID)
+	default:
+		return fmt.Errorf("the credentials with ID %s, used to log in to GitHub, must be of the `github_app` or `secret` type", github.CredentialsID)
+	}
+}
+
+// githubAppJWT returns a JSON Web Token authenticating as the given GitHub App
+func githubAppJWT(app *credentials.GithubAppCredentials, now time.Time) (string, error) {
+	privateKey, err := app.GetRSAPrivateKey()
+	if err != nil {
+		return "", fmt.Errorf("unable to parse the private key: %v", err)
+	}
+
+	encode := func(value interface{}) string {
+		payload, _ := json.Marshal(value)
+		return base64.RawURLEncoding.EncodeToString(payload)
+	}
+	// The token is issued in the past to allow for clock drift, and expires before the maximum of 10 minutes
+	unsignedToken := encode(map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + encode(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.Itoa(app.AppID),
+	})
+	hash := sha256.Sum256([]byte(unsignedToken))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsignedToken + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ToString prints out a description of the GitHub target
+func (github *GithubTarget) ToString() string {
+	scope := github.Owner
+	if github.Repository != "" {
+		scope += "/" + github.Repository
+	}
+	if github.Environment != "" {
+		scope += " (" + github.Environment + ")"
+	}
+	return fmt.Sprintf("%s (GitHub) - %s", github.BaseToString(), scope)
+}
+
+// GetExistingCredentials returns a list of all credential IDs on the target
+func (github *GithubTarget) GetExistingCredentials() []string {
+	return github.existingCredentials
+}
+
+// DeleteCredentials deletes the secrets of the credentials with the given ID on the target
+// If the ID does not match any credentials, it is the name of a secret
+func (github *GithubTarget) DeleteCredentials(ctx context.Context, id string) error {
+	for _, name := range github.entriesOf(id) {
+		if err := github.apiRequest(ctx, http.MethodDelete, github.secretsPath()+"/"+url.PathEscape(name), nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entriesOf returns the existing secrets of the credentials with the given ID
+func (github *GithubTarget) entriesOf(id string) []string {
+	return groupedEntriesOf(id, github.credentialsSecrets, func(name string) bool { return github.existingSecrets[name] })
+}
+
+// RenderCredentials returns the secrets that are sent to GitHub for the given credentials
+func (github *GithubTarget) RenderCredentials(cred credentials.Credentials) ([]byte, error) {
+	secrets, err := toEnvironmentVariables(cred)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create github secrets from %s: %v", cred.GetID(), err)
+	}
+	return json.Marshal(secrets)
+}
+
+// UpdateCredentials syncs the given credentials to GitHub
+// Secrets of optional fields that are no longer set are deleted
+func (github *GithubTarget) UpdateCredentials(ctx context.Context, cred credentials.Credentials) error {
+	secrets, err := toEnvironmentVariables(cred)
+	if err != nil {
+		return fmt.Errorf("unable to create github secrets from %s: %v", cred.GetID(), err)
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(github.publicKey.Key)
+	if err != nil || len(publicKey) != 32 {
+		return fmt.Errorf("invalid public key: %s", github.publicKey.Key)
+	}
+	var recipient [32]byte
+	copy(recipient[:], publicKey)
+
+	names := []string{}
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := secrets[name]
+		secretPath := github.secretsPath() + "/" + url.PathEscape(name)
+		if value == "" {
+			if github.existingSecrets[name] {
+				if err := github.apiRequest(ctx, http.MethodDelete, secretPath, nil, nil); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		encryptedValue, err := box.SealAnonymous(nil, []byte(value), &recipient, rand.Reader)
+		if err != nil {
+			return fmt.Errorf("unable to encrypt the %s secret: %v", name, err)
+		}
+		body := map[string]string{
+			"encrypted_value": base64.StdEncoding.EncodeToString(encryptedValue),
+			"key_id":          github.publicKey.KeyID,
+		}
+		if github.Repository == "" {
+			body["visibility"] = github.getVisibility()
+		}
+		if err := github.apiRequest(ctx, http.MethodPut, secretPath, body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (github *GithubTarget) getVisibility() string {
+	if github.Visibility == "" {
+		return "private"
+	}
+	return github.Visibility
+}
+
+// ValidateConfiguration verifies that GitHub configuration is valid
+func (github *GithubTarget) ValidateConfiguration() error {
+	if github.CredentialsID == "" {
+		return fmt.Errorf("the GitHub target `%s` must define `credentials_id`", github.Name)
+	}
+	if github.Owner == "" {
+		return fmt.Errorf("the GitHub target `%s` must define an `owner`", github.Name)
+	}
+	if github.Environment != "" && github.Repository == "" {
+		return fmt.Errorf("the GitHub target `%s` must define a `repository` to sync environment secrets", github.Name)
+	}
+	if github.Visibility != "" && (github.Repository != "" || (github.Visibility != "all" && github.Visibility != "private")) {
+		return fmt.Errorf("the `visibility` of the GitHub target `%s` must be `all` or `private`, and is only valid for organization secrets", github.Name)
+	}
+	if github.TagUnsynced || github.DeleteOnlyMarked {
+		return fmt.Errorf("the GitHub target `%s` does not support `tag_unsynced` and `delete_only_marked`, since secrets have no description", github.Name)
+	}
+	if github.APIURL != "" {
+		if _, err := url.ParseRequestURI(github.APIURL); err != nil {
+			return fmt.Errorf("the GitHub target `%s` has an invalid API URL: %s", github.Name, github.APIURL)
+		}
+	}
+	return nil
+}
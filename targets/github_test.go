@@ -0,0 +1,368 @@
+package targets
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// fakeGithub is a GitHub API serving the secrets of a single scope (organization, repository or environment)
+type fakeGithub struct {
+	*httptest.Server
+
+	t           *testing.T
+	secretsPath string
+	token       string
+	publicKey   *[32]byte
+	privateKey  *[32]byte
+	appKey      *rsa.PrivateKey
+
+	mutex   gosync.Mutex
+	secrets map[string]string
+	bodies  map[string]map[string]string
+}
+
+func newFakeGithub(t *testing.T, secretsPath string, existingSecrets ...string) *fakeGithub {
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	appKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	server := &fakeGithub{
+		t:           t,
+		secretsPath: secretsPath,
+		token:       "the-token",
+		publicKey:   publicKey,
+		privateKey:  privateKey,
+		appKey:      appKey,
+		secrets:     map[string]string{},
+		bodies:      map[string]map[string]string{},
+	}
+	for _, name := range existingSecrets {
+		server.secrets[name] = "existing"
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (server *fakeGithub) appPrivateKey() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(server.appKey)}))
+}
+
+func (server *fakeGithub) verifyJWT(authorization string) bool {
+	parts := strings.Split(strings.TrimPrefix(authorization, "Bearer "), ".")
+	if len(parts) != 3 {
+		return false
+	}
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if rsa.VerifyPKCS1v15(&server.appKey.PublicKey, crypto.SHA256, hash[:], signature) != nil {
+		return false
+	}
+	claims := map[string]interface{}{}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	json.Unmarshal(payload, &claims)
+	return claims["iss"] == "1234"
+}
+
+func (server *fakeGithub) handle(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/installation") && r.Method == http.MethodGet:
+		if !server.verifyJWT(r.Header.Get("Authorization")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 42})
+		return
+	case r.URL.Path == "/app/installations/42/access_tokens" && r.Method == http.MethodPost:
+		if !server.verifyJWT(r.Header.Get("Authorization")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"token": server.token})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+server.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	name, found := strings.CutPrefix(r.URL.Path, server.secretsPath)
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	name = strings.TrimPrefix(name, "/")
+
+	switch {
+	case name == "public-key":
+		json.NewEncoder(w).Encode(map[string]string{"key_id": "the-key-id", "key": base64.StdEncoding.EncodeToString(server.publicKey[:])})
+	case name == "" && r.Method == http.MethodGet:
+		// Pages of 2 secrets, whatever the requested page size
+		names := []string{}
+		for name := range server.secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		page := 1
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		secrets := []map[string]string{}
+		for i := (page - 1) * 2; i < len(names) && i < page*2; i++ {
+			secrets = append(secrets, map[string]string{"name": names[i]})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total_count": len(names), "secrets": secrets})
+	case r.Method == http.MethodPut:
+		body := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(server.t, "the-key-id", body["key_id"])
+		encryptedValue, _ := base64.StdEncoding.DecodeString(body["encrypted_value"])
+		value, ok := box.OpenAnonymous(nil, encryptedValue, server.publicKey, server.privateKey)
+		assert.True(server.t, ok, "The secret %s could not be decrypted", name)
+		server.secrets[name] = string(value)
+		server.bodies[name] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		delete(server.secrets, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newGithubLoginToken() *credentials.SecretTextCredentials {
+	token := credentials.NewSecretText()
+	token.ID = "github-token"
+	token.Secret = "the-token"
+	return token
+}
+
+func TestGithubToString(t *testing.T) {
+	t.Parallel()
+
+	github := &GithubTarget{
+		Base:        Base{Name: "targetName", Tags: map[string]string{"my_tag": "tag_value"}},
+		Owner:       "coveooss",
+		Repository:  "credentials-sync",
+		Environment: "prod",
+	}
+	assert.Equal(t, "targetName [Tags: my_tag=tag_value] (GitHub) - coveooss/credentials-sync (prod)", github.ToString())
+}
+
+func TestGithubValidateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		target        *GithubTarget
+		expectedError error
+	}{
+		{
+			name:          "Valid repository",
+			target:        &GithubTarget{Base: Base{Name: "test"}, CredentialsID: "token", Owner: "org", Repository: "repo"},
+			expectedError: nil,
+		},
+		{
+			name:          "Valid organization",
+			target:        &GithubTarget{Base: Base{Name: "test"}, CredentialsID: "token", Owner: "org", Visibility: "all"},
+			expectedError: nil,
+		},
+		{
+			name:          "Missing credentials",
+			target:        &GithubTarget{Base: Base{Name: "test"}, Owner: "org"},
+			expectedError: fmt.Errorf("the GitHub target `test` must define `credentials_id`"),
+		},
+		{
+			name:          "Missing owner",
+			target:        &GithubTarget{Base: Base{Name: "test"}, CredentialsID: "token"},
+			expectedError: fmt.Errorf("the GitHub target `test` must define an `owner`"),
+		},
+		{
+			name:          "Environment without repository",
+			target:        &GithubTarget{Base: Base{Name: "test"}, CredentialsID: "token", Owner: "org", Environment: "prod"},
+			expectedError: fmt.Errorf("the GitHub target `test` must define a `repository` to sync environment secrets"),
+		},
+		{
+			name:          "Visibility on repository",
+			target:        &GithubTarget{Base: Base{Name: "test"}, CredentialsID: "token", Owner: "org", Repository: "repo", Visibility: "all"},
+			expectedError: fmt.Errorf("the `visibility` of the GitHub target `test` must be `all` or `private`, and is only valid for organization secrets"),
+		},
+		{
+			name:          "Tag unsynced",
+			target:        &GithubTarget{Base: Base{Name: "test", TagUnsynced: true}, CredentialsID: "token", Owner: "org"},
+			expectedError: fmt.Errorf("the GitHub target `test` does not support `tag_unsynced` and `delete_only_marked`, since secrets have no description"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.target.ValidateConfiguration())
+		})
+	}
+}
+
+func TestGithubSync(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeGithub(t, "/repos/coveooss/credentials-sync/actions/secrets", "MY_CRED_USERNAME", "AWS_ROLE_ARN", "OTHER", "UNMANAGED")
+	github := &GithubTarget{Base: Base{Name: "test"}, CredentialsID: "github-token", APIURL: server.URL, Owner: "coveooss", Repository: "credentials-sync"}
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.Username = "user"
+	userPass.Password = "pass"
+	aws := credentials.NewAmazonWebServicesCredentials()
+	aws.ID = "aws"
+	aws.AccessKey = "AKIA"
+	aws.SecretKey = "secret"
+
+	assert.NoError(t, github.Initialize(context.Background(), []credentials.Credentials{newGithubLoginToken(), userPass, aws}))
+	// The secrets are reported as the credentials they belong to, all pages are listed
+	assert.Equal(t, []string{"OTHER", "UNMANAGED", "aws", "my-cred"}, github.GetExistingCredentials())
+
+	assert.NoError(t, github.UpdateCredentials(context.Background(), userPass))
+	assert.NoError(t, github.UpdateCredentials(context.Background(), aws))
+	assert.NoError(t, github.DeleteCredentials(context.Background(), "OTHER"))
+	assert.Equal(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIA",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"MY_CRED_PASSWORD":      "pass",
+		"MY_CRED_USERNAME":      "user",
+		"UNMANAGED":             "existing",
+	}, server.secrets)
+	assert.NotContains(t, server.bodies["MY_CRED_USERNAME"], "visibility")
+
+	assert.NoError(t, github.DeleteCredentials(context.Background(), "my-cred"))
+	assert.NotContains(t, server.secrets, "MY_CRED_USERNAME")
+}
+
+func TestGithubDeleteCredentialsMissingFromTheSources(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeGithub(t, "/repos/coveooss/credentials-sync/actions/secrets", "MY_CRED_USERNAME", "MY_CRED_PASSWORD", "OTHER")
+	github := &GithubTarget{Base: Base{Name: "test"}, CredentialsID: "github-token", APIURL: server.URL, Owner: "coveooss", Repository: "credentials-sync"}
+	// The `MY_CRED_USERNAME` secret belongs to credentials of the sources
+	secret := credentials.NewSecretText()
+	secret.ID = "my_cred_username"
+	secret.Secret = "a-secret"
+
+	assert.NoError(t, github.Initialize(context.Background(), []credentials.Credentials{newGithubLoginToken(), secret}))
+	assert.True(t, HasCredential(github, "my-cred"))
+	assert.False(t, HasCredential(github, "unknown"))
+
+	assert.NoError(t, github.DeleteCredentials(context.Background(), "my-cred"))
+	assert.Equal(t, map[string]string{"MY_CRED_USERNAME": "existing", "OTHER": "existing"}, server.secrets)
+}
+
+func TestGithubSyncFilesAndCertificates(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeGithub(t, "/repos/coveooss/credentials-sync/actions/secrets")
+	github := &GithubTarget{Base: Base{Name: "test"}, CredentialsID: "github-token", APIURL: server.URL, Owner: "coveooss", Repository: "credentials-sync"}
+	secretFile, cert, keystore := newTestFileCredentials(t)
+
+	assert.NoError(t, github.Initialize(context.Background(), []credentials.Credentials{newGithubLoginToken(), secretFile, cert}))
+	assert.NoError(t, github.UpdateCredentials(context.Background(), secretFile))
+	assert.NoError(t, github.UpdateCredentials(context.Background(), cert))
+	assert.Equal(t, map[string]string{
+		"CLIENT_CERT_KEYSTORE": keystore,
+		"CLIENT_CERT_PASSWORD": "hunter42",
+		"KUBECONFIG":           "apiVersion: v1\nkind: Config",
+	}, server.secrets)
+}
+
+func TestGithubSyncOrganizationWithApp(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeGithub(t, "/orgs/coveooss/actions/secrets")
+	github := &GithubTarget{Base: Base{Name: "test"}, CredentialsID: "github-app", APIURL: server.URL, Owner: "coveooss", Visibility: "all"}
+
+	app := credentials.NewGithubAppCredentials()
+	app.ID = "github-app"
+	app.AppID = 1234
+	app.PrivateKey = server.appPrivateKey()
+	secret := credentials.NewSecretText()
+	secret.ID = "slack"
+	secret.Secret = "xoxb"
+
+	assert.NoError(t, github.Initialize(context.Background(), []credentials.Credentials{app, secret}))
+	assert.Empty(t, github.GetExistingCredentials())
+	assert.NoError(t, github.UpdateCredentials(context.Background(), secret))
+	assert.Equal(t, map[string]string{"SLACK": "xoxb"}, server.secrets)
+	assert.Equal(t, "all", server.bodies["SLACK"]["visibility"])
+}
+
+func TestGithubInitializeErrors(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeGithub(t, "/repos/coveooss/credentials-sync/environments/prod/secrets")
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "userpass"
+	invalidToken := credentials.NewSecretText()
+	invalidToken.ID = "invalid-token"
+	invalidToken.Secret = "invalid"
+	allCredentials := []credentials.Credentials{newGithubLoginToken(), userPass, invalidToken}
+
+	github := &GithubTarget{Base: Base{Name: "test"}, CredentialsID: "github-token", APIURL: server.URL, Owner: "coveooss", Repository: "credentials-sync", Environment: "prod"}
+	assert.NoError(t, github.Initialize(context.Background(), allCredentials))
+
+	github.CredentialsID = "missing"
+	assert.EqualError(t, github.Initialize(context.Background(), allCredentials), "the credentials with ID missing, used to log in to GitHub, were not found")
+
+	github.CredentialsID = "userpass"
+	assert.EqualError(t, github.Initialize(context.Background(), allCredentials), "the credentials with ID userpass, used to log in to GitHub, must be of the `github_app` or `secret` type")
+
+	github.CredentialsID = "invalid-token"
+	err := github.Initialize(context.Background(), allCredentials)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unable to fetch the public key of the secrets")
+		assert.Contains(t, err.Error(), "invalid response code 401")
+	}
+}
+
+func TestGithubAppJWT(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeGithub(t, "/orgs/coveooss/actions/secrets")
+	app := credentials.NewGithubAppCredentials()
+	app.AppID = 1234
+
+	// PKCS#1 and PKCS#8 keys are supported
+	app.PrivateKey = server.appPrivateKey()
+	jwt, err := githubAppJWT(app, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, server.verifyJWT("Bearer "+jwt))
+
+	pkcs8Key, err := x509.MarshalPKCS8PrivateKey(server.appKey)
+	assert.NoError(t, err)
+	app.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Key}))
+	jwt, err = githubAppJWT(app, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, server.verifyJWT("Bearer "+jwt))
+
+	app.PrivateKey = "not a key"
+	_, err = githubAppJWT(app, time.Now())
+	assert.EqualError(t, err, "unable to parse the private key: no PEM private key found")
+}
@@ -0,0 +1,360 @@
+package targets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/retry"
+)
+
+const (
+	defaultGitlabURL              = "https://gitlab.com"
+	defaultGitlabEnvironmentScope = "*"
+)
+
+// GitlabTarget represents the CI/CD variables of a GitLab project or group
+// Each credentials is synced as one or more variables, named after the credentials' target ID
+type GitlabTarget struct {
+	Base `mapstructure:",squash"`
+
+	// ID of the `secret` credentials containing the access token used to authenticate
+	CredentialsID string `mapstructure:"credentials_id"`
+	URL           string `mapstructure:"url"`
+	// ID or full path of the project or group
+	Project string `mapstructure:"project"`
+	Group   string `mapstructure:"group"`
+
+	// Flags of the variables, unless they are set on the credentials
+	Masked           bool   `mapstructure:"masked"`
+	Protected        bool   `mapstructure:"protected"`
+	EnvironmentScope string `mapstructure:"environment_scope"`
+
+	client              *http.Client
+	token               string
+	existingCredentials []string
+	// Variable key -> Variables with that key (one per environment scope)
+	existingVariables map[string][]*gitlabVariable
+	// Credentials target ID -> Keys of all the variables that the credentials may be synced to
+	credentialsVariables map[string][]string
+}
+
+// gitlabVariable is a CI/CD variable, as sent to and returned by the GitLab API
+type gitlabVariable struct {
+	Key              string `json:"key"`
+	Value            string `json:"value"`
+	VariableType     string `json:"variable_type"`
+	Protected        bool   `json:"protected"`
+	Masked           bool   `json:"masked"`
+	EnvironmentScope string `json:"environment_scope"`
+	Description      string `json:"description"`
+}
+
+func (gitlab *GitlabTarget) getURL() string {
+	if gitlab.URL == "" {
+		return defaultGitlabURL
+	}
+	return strings.TrimSuffix(gitlab.URL, "/")
+}
+
+func (gitlab *GitlabTarget) getEnvironmentScope() string {
+	if gitlab.EnvironmentScope == "" {
+		return defaultGitlabEnvironmentScope
+	}
+	return gitlab.EnvironmentScope
+}
+
+// variablesPath returns the path of the API endpoints managing the target's variables
+func (gitlab *GitlabTarget) variablesPath() string {
+	if gitlab.Group != "" {
+		return fmt.Sprintf("/api/v4/groups/%s/variables", url.PathEscape(gitlab.Group))
+	}
+	return fmt.Sprintf("/api/v4/projects/%s/variables", url.PathEscape(gitlab.Project))
+}
+
+func (gitlab *GitlabTarget) variablePath(variable *gitlabVariable) string {
+	return fmt.Sprintf("%s/%s?filter[environment_scope]=%s", gitlab.variablesPath(), url.PathEscape(variable.Key), url.QueryEscape(variable.EnvironmentScope))
+}
+
+// request sends a request to the GitLab API and decodes the JSON response into the given result, if it is not nil
+func (gitlab *GitlabTarget) request(ctx context.Context, method string, path string, body interface{}, result interface{}) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader = bytes.NewReader(payload)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, gitlab.getURL()+path, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("PRIVATE-TOKEN", gitlab.token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := gitlab.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		responseBody, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("%s %s: invalid response code %d: %s", method, path, response.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	if result != nil {
+		return response, json.NewDecoder(response.Body).Decode(result)
+	}
+	return response, nil
+}
+
+// Initialize executes all necessary operations to prepare the GitLab target for sync
+func (gitlab *GitlabTarget) Initialize(ctx context.Context, allCredentials []credentials.Credentials) error {
+	gitlab.client = &http.Client{Transport: &retry.Transport{Base: http.DefaultTransport, Policy: gitlab.Retry, Name: gitlab.Name}}
+
+	var loginCredentials credentials.Credentials
+	for _, creds := range allCredentials {
+		if creds.GetID() == gitlab.CredentialsID {
+			loginCredentials = creds
+		}
+	}
+	switch castCreds := loginCredentials.(type) {
+	case *credentials.SecretTextCredentials:
+		gitlab.token = castCreds.Secret
+	case nil:
+		return fmt.Errorf("the credentials with ID %s, used to log in to GitLab, were not found", gitlab.CredentialsID)
+	default:
+		return fmt.Errorf("the credentials with ID %s, used to log in to GitLab, must be of the `secret` type", gitlab.CredentialsID)
+	}
+
+	gitlab.existingVariables = map[string][]*gitlabVariable{}
+	for page := "1"; page != ""; {
+		variables := []*gitlabVariable{}
+		response, err := gitlab.request(ctx, http.MethodGet, fmt.Sprintf("%s?per_page=100&page=%s", gitlab.variablesPath(), page), nil, &variables)
+		if err != nil {
+			return fmt.Errorf("unable to list the variables: %v", err)
+		}
+		for _, variable := range variables {
+			gitlab.existingVariables[variable.Key] = append(gitlab.existingVariables[variable.Key], variable)
+		}
+		page = response.Header.Get("X-Next-Page")
+	}
+
+	// Variables are reported as the credentials they were synced from, so that they can be compared with the source credentials
+	existingKeys := map[string]bool{}
+	for key := range gitlab.existingVariables {
+		existingKeys[key] = true
+	}
+	gitlab.existingCredentials, gitlab.credentialsVariables = groupExistingCredentials(syncedCredentials(gitlab, allCredentials), existingKeys, func(creds credentials.Credentials) []string {
+		// Credentials that cannot be converted have no variables, the error is returned when they are synced
+		variables, err := gitlab.toGitlabVariables(creds)
+		if err != nil {
+			return nil
+		}
+		keys := []string{}
+		for _, variable := range variables {
+			keys = append(keys, variable.Key)
+		}
+		return keys
+	})
+
+	return nil
+}
+
+// ToString prints out a description of the GitLab target
+func (gitlab *GitlabTarget) ToString() string {
+	scope := "project " + gitlab.Project
+	if gitlab.Group != "" {
+		scope = "group " + gitlab.Group
+	}
+	return fmt.Sprintf("%s (GitLab) - %s on %s", gitlab.BaseToString(), scope, gitlab.getURL())
+}
+
+// GetExistingCredentials returns a list of all credential IDs on the target
+func (gitlab *GitlabTarget) GetExistingCredentials() []string {
+	return gitlab.existingCredentials
+}
+
+// getVariableKeys returns the keys of the variables of the credentials with the given ID
+// If the ID does not match any credentials, it is the key of a variable
+func (gitlab *GitlabTarget) getVariableKeys(id string) []string {
+	return groupedEntriesOf(id, gitlab.credentialsVariables, func(key string) bool { return len(gitlab.existingVariables[key]) > 0 })
+}
+
+// entriesOf returns the keys of the existing variables of the credentials with the given ID
+func (gitlab *GitlabTarget) entriesOf(id string) []string {
+	return gitlab.getVariableKeys(id)
+}
+
+// deleteVariable deletes the given variable and removes it from the existing variables
+func (gitlab *GitlabTarget) deleteVariable(ctx context.Context, variable *gitlabVariable) error {
+	if _, err := gitlab.request(ctx, http.MethodDelete, gitlab.variablePath(variable), nil, nil); err != nil {
+		return err
+	}
+	remainingVariables := []*gitlabVariable{}
+	for _, existingVariable := range gitlab.existingVariables[variable.Key] {
+		if existingVariable.EnvironmentScope != variable.EnvironmentScope {
+			remainingVariables = append(remainingVariables, existingVariable)
+		}
+	}
+	gitlab.existingVariables[variable.Key] = remainingVariables
+	return nil
+}
+
+// DeleteCredentials deletes the variables of the credentials with the given ID on the target, in all environment scopes
+func (gitlab *GitlabTarget) DeleteCredentials(ctx context.Context, id string) error {
+	for _, key := range gitlab.getVariableKeys(id) {
+		for _, variable := range gitlab.existingVariables[key] {
+			if err := gitlab.deleteVariable(ctx, variable); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetCredentialsDescription returns the description of the variables of the credentials with the given ID
+func (gitlab *GitlabTarget) GetCredentialsDescription(ctx context.Context, id string) (string, error) {
+	for _, key := range gitlab.getVariableKeys(id) {
+		for _, variable := range gitlab.existingVariables[key] {
+			return variable.Description, nil
+		}
+	}
+	return "", fmt.Errorf("no variables found for the credentials with ID %s", id)
+}
+
+// SetCredentialsDescription modifies the description of the variables of the credentials with the given ID
+// The other attributes of the variables are sent back as they were listed
+func (gitlab *GitlabTarget) SetCredentialsDescription(ctx context.Context, id string, description string) error {
+	for _, key := range gitlab.getVariableKeys(id) {
+		for _, variable := range gitlab.existingVariables[key] {
+			updatedVariable := *variable
+			updatedVariable.Description = description
+			if _, err := gitlab.request(ctx, http.MethodPut, gitlab.variablePath(variable), &updatedVariable, nil); err != nil {
+				return err
+			}
+			*variable = updatedVariable
+		}
+	}
+	return nil
+}
+
+// RenderCredentials returns the variables that are sent to GitLab for the given credentials
+func (gitlab *GitlabTarget) RenderCredentials(cred credentials.Credentials) ([]byte, error) {
+	variables, err := gitlab.toGitlabVariables(cred)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create gitlab variables from %s: %v", cred.GetID(), err)
+	}
+	return json.Marshal(variables)
+}
+
+// UpdateCredentials syncs the given credentials to GitLab
+// Variables of optional fields that are no longer set, and variables of the credentials in other environment scopes, are deleted
+func (gitlab *GitlabTarget) UpdateCredentials(ctx context.Context, cred credentials.Credentials) error {
+	variables, err := gitlab.toGitlabVariables(cred)
+	if err != nil {
+		return fmt.Errorf("unable to create gitlab variables from %s: %v", cred.GetID(), err)
+	}
+	for _, variable := range variables {
+		updated := false
+		for _, existingVariable := range gitlab.existingVariables[variable.Key] {
+			if variable.Value == "" || existingVariable.EnvironmentScope != variable.EnvironmentScope {
+				if err := gitlab.deleteVariable(ctx, existingVariable); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := gitlab.request(ctx, http.MethodPut, gitlab.variablePath(variable), variable, nil); err != nil {
+				return err
+			}
+			*existingVariable = *variable
+			updated = true
+		}
+		if variable.Value != "" && !updated {
+			if _, err := gitlab.request(ctx, http.MethodPost, gitlab.variablesPath(), variable, nil); err != nil {
+				return err
+			}
+			createdVariable := *variable
+			gitlab.existingVariables[variable.Key] = append(gitlab.existingVariables[variable.Key], &createdVariable)
+		}
+	}
+	return nil
+}
+
+// ValidateConfiguration verifies that GitLab configuration is valid
+func (gitlab *GitlabTarget) ValidateConfiguration() error {
+	if gitlab.CredentialsID == "" {
+		return fmt.Errorf("the GitLab target `%s` must define `credentials_id`", gitlab.Name)
+	}
+	if (gitlab.Project == "" && gitlab.Group == "") || (gitlab.Project != "" && gitlab.Group != "") {
+		return fmt.Errorf("the GitLab target `%s` must define either a `project` or a `group`", gitlab.Name)
+	}
+	if gitlab.URL != "" {
+		if _, err := url.ParseRequestURI(gitlab.URL); err != nil {
+			return fmt.Errorf("the GitLab target `%s` has an invalid URL: %s", gitlab.Name, gitlab.URL)
+		}
+	}
+	return nil
+}
+
+// gitlabVariableOptions are the options of credentials on GitLab targets: the flags of the variables they are synced to
+// Unset options take the target's value
+type gitlabVariableOptions struct {
+	Masked           *bool  `mapstructure:"masked"`
+	Protected        *bool  `mapstructure:"protected"`
+	EnvironmentScope string `mapstructure:"environment_scope"`
+}
+
+// toGitlabVariables converts the given credentials to the variables that they are synced to
+// The variables are the environment variables of the credentials. Those containing the content of a file are `file` variables
+func (gitlab *GitlabTarget) toGitlabVariables(creds credentials.Credentials) ([]*gitlabVariable, error) {
+	environmentVariables, err := toEnvironmentVariables(creds)
+	if err != nil {
+		return nil, err
+	}
+	fileVariables := fileEnvironmentVariables(creds)
+	variables := []*gitlabVariable{}
+	for key, value := range environmentVariables {
+		variable := &gitlabVariable{Key: key, Value: value, VariableType: "env_var"}
+		if fileVariables[key] {
+			variable.VariableType = "file"
+		}
+		variables = append(variables, variable)
+	}
+
+	options := gitlabVariableOptions{}
+	if err := decodeTargetOptions(creds, "gitlab", &options); err != nil {
+		return nil, err
+	}
+	description := gitlab.markedDescription(creds)
+	for _, variable := range variables {
+		variable.Masked = gitlab.Masked
+		if options.Masked != nil {
+			variable.Masked = *options.Masked
+		}
+		// Multi-line values, such as private keys, cannot be masked
+		if strings.Contains(variable.Value, "\n") {
+			variable.Masked = false
+		}
+		variable.Protected = gitlab.Protected
+		if options.Protected != nil {
+			variable.Protected = *options.Protected
+		}
+		variable.EnvironmentScope = gitlab.getEnvironmentScope()
+		if options.EnvironmentScope != "" {
+			variable.EnvironmentScope = options.EnvironmentScope
+		}
+		variable.Description = description
+	}
+	sort.Slice(variables, func(i, j int) bool { return variables[i].Key < variables[j].Key })
+	return variables, nil
+}
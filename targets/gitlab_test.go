@@ -0,0 +1,292 @@
+package targets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	gosync "sync"
+	"testing"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGitlab is a GitLab API serving the variables of a single project or group
+type fakeGitlab struct {
+	*httptest.Server
+
+	variablesPath string
+
+	mutex gosync.Mutex
+	// Key:Environment scope -> Variable
+	variables map[string]*gitlabVariable
+}
+
+func newFakeGitlab(t *testing.T, variablesPath string, existingVariables ...*gitlabVariable) *fakeGitlab {
+	server := &fakeGitlab{variablesPath: variablesPath, variables: map[string]*gitlabVariable{}}
+	for _, variable := range existingVariables {
+		server.variables[variable.Key+":"+variable.EnvironmentScope] = variable
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (server *fakeGitlab) handle(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if r.Header.Get("PRIVATE-TOKEN") != "the-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	key, found := strings.CutPrefix(r.URL.EscapedPath(), server.variablesPath)
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key = strings.TrimPrefix(key, "/")
+	id := key + ":" + r.URL.Query().Get("filter[environment_scope]")
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		// Pages of 2 variables, whatever the requested page size
+		ids := []string{}
+		for id := range server.variables {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		variables := []*gitlabVariable{}
+		for i := (page - 1) * 2; i < len(ids) && i < page*2; i++ {
+			variables = append(variables, server.variables[ids[i]])
+		}
+		if page*2 < len(ids) {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+		json.NewEncoder(w).Encode(variables)
+	case key == "" && r.Method == http.MethodPost:
+		variable := &gitlabVariable{}
+		json.NewDecoder(r.Body).Decode(variable)
+		if _, ok := server.variables[variable.Key+":"+variable.EnvironmentScope]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message":{"key":["has already been taken"]}}`)
+			return
+		}
+		server.variables[variable.Key+":"+variable.EnvironmentScope] = variable
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut:
+		if _, ok := server.variables[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		variable := &gitlabVariable{}
+		json.NewDecoder(r.Body).Decode(variable)
+		server.variables[id] = variable
+	case r.Method == http.MethodDelete:
+		if _, ok := server.variables[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(server.variables, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newGitlabLoginToken() *credentials.SecretTextCredentials {
+	token := credentials.NewSecretText()
+	token.ID = "gitlab-token"
+	token.Secret = "the-token"
+	return token
+}
+
+func TestGitlabToString(t *testing.T) {
+	t.Parallel()
+
+	gitlab := &GitlabTarget{
+		Base:    Base{Name: "targetName", Tags: map[string]string{"my_tag": "tag_value"}},
+		Project: "coveooss/credentials-sync",
+	}
+	assert.Equal(t, "targetName [Tags: my_tag=tag_value] (GitLab) - project coveooss/credentials-sync on https://gitlab.com", gitlab.ToString())
+}
+
+func TestGitlabValidateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		target        *GitlabTarget
+		expectedError error
+	}{
+		{
+			name:          "Valid project",
+			target:        &GitlabTarget{Base: Base{Name: "test"}, CredentialsID: "token", Project: "group/project"},
+			expectedError: nil,
+		},
+		{
+			name:          "Valid group",
+			target:        &GitlabTarget{Base: Base{Name: "test"}, CredentialsID: "token", Group: "group", URL: "https://gitlab.my-domain.com"},
+			expectedError: nil,
+		},
+		{
+			name:          "Missing credentials",
+			target:        &GitlabTarget{Base: Base{Name: "test"}, Project: "group/project"},
+			expectedError: fmt.Errorf("the GitLab target `test` must define `credentials_id`"),
+		},
+		{
+			name:          "Both project and group",
+			target:        &GitlabTarget{Base: Base{Name: "test"}, CredentialsID: "token", Project: "group/project", Group: "group"},
+			expectedError: fmt.Errorf("the GitLab target `test` must define either a `project` or a `group`"),
+		},
+		{
+			name:          "Invalid URL",
+			target:        &GitlabTarget{Base: Base{Name: "test"}, CredentialsID: "token", Group: "group", URL: "gitlab"},
+			expectedError: fmt.Errorf("the GitLab target `test` has an invalid URL: gitlab"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.target.ValidateConfiguration())
+		})
+	}
+}
+
+func TestToGitlabVariables(t *testing.T) {
+	t.Parallel()
+
+	gitlab := &GitlabTarget{Base: Base{OwnershipMarker: "[managed]"}, Masked: true}
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.Description = "A description"
+	userPass.Username = "user"
+	userPass.Password = "password"
+	userPass.TargetOptions = map[string]map[string]interface{}{"gitlab": {"protected": true, "environment_scope": "production"}}
+	variables, err := gitlab.toGitlabVariables(userPass)
+	assert.NoError(t, err)
+	assert.Equal(t, []*gitlabVariable{
+		{Key: "MY_CRED_PASSWORD", Value: "password", VariableType: "env_var", Masked: true, Protected: true, EnvironmentScope: "production", Description: "A description [managed]"},
+		{Key: "MY_CRED_USERNAME", Value: "user", VariableType: "env_var", Masked: true, Protected: true, EnvironmentScope: "production", Description: "A description [managed]"},
+	}, variables)
+
+	ssh := credentials.NewSSHCredentials()
+	ssh.ID = "ssh"
+	ssh.PrivateKey = "-----BEGIN KEY-----\nkey\n-----END KEY-----"
+	ssh.TargetOptions = map[string]map[string]interface{}{"gitlab": {"masked": true, "protected": false}}
+	variables, err = gitlab.toGitlabVariables(ssh)
+	assert.NoError(t, err)
+	assert.Equal(t, []*gitlabVariable{
+		{Key: "SSH_PASSPHRASE", Value: "", VariableType: "env_var", Masked: true, EnvironmentScope: "*", Description: "ssh [managed]"},
+		{Key: "SSH_PRIVATE_KEY", Value: ssh.PrivateKey, VariableType: "file", Masked: false, EnvironmentScope: "*", Description: "ssh [managed]"},
+		{Key: "SSH_USERNAME", Value: "", VariableType: "env_var", Masked: true, EnvironmentScope: "*", Description: "ssh [managed]"},
+	}, variables)
+
+	secretFile, cert, keystore := newTestFileCredentials(t)
+	variables, err = gitlab.toGitlabVariables(secretFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []*gitlabVariable{
+		{Key: "KUBECONFIG", Value: "apiVersion: v1\nkind: Config", VariableType: "file", Masked: false, EnvironmentScope: "*", Description: "kubeconfig [managed]"},
+	}, variables)
+	variables, err = gitlab.toGitlabVariables(cert)
+	assert.NoError(t, err)
+	assert.Equal(t, []*gitlabVariable{
+		{Key: "CLIENT_CERT_KEYSTORE", Value: keystore, VariableType: "env_var", Masked: true, EnvironmentScope: "*", Description: "client-cert [managed]"},
+		{Key: "CLIENT_CERT_PASSWORD", Value: "hunter42", VariableType: "env_var", Masked: true, EnvironmentScope: "*", Description: "client-cert [managed]"},
+	}, variables)
+
+	// The options of other types of targets are ignored, unknown options are rejected
+	userPass.TargetOptions = map[string]map[string]interface{}{"jenkins": {"domain": "deploy"}}
+	_, err = gitlab.toGitlabVariables(userPass)
+	assert.NoError(t, err)
+	userPass.TargetOptions = map[string]map[string]interface{}{"gitlab": {"domain": "deploy"}}
+	_, err = gitlab.toGitlabVariables(userPass)
+	assert.ErrorContains(t, err, "the credentials with ID my-cred have invalid gitlab options: ")
+	assert.ErrorContains(t, err, "invalid keys: domain")
+}
+
+func TestGitlabSync(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeGitlab(t, "/api/v4/projects/coveooss%2Fcredentials-sync/variables",
+		&gitlabVariable{Key: "MY_CRED_USERNAME", Value: "old", EnvironmentScope: "*"},
+		&gitlabVariable{Key: "MY_CRED_USERNAME", Value: "old", EnvironmentScope: "staging"},
+		&gitlabVariable{Key: "AWS_ROLE_ARN", Value: "old", EnvironmentScope: "*"},
+		&gitlabVariable{Key: "OTHER", Value: "other", EnvironmentScope: "*", Description: "Other"},
+		&gitlabVariable{Key: "UNMANAGED", Value: "unmanaged", EnvironmentScope: "*"},
+	)
+	gitlab := &GitlabTarget{Base: Base{Name: "test"}, CredentialsID: "gitlab-token", URL: server.URL, Project: "coveooss/credentials-sync"}
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.Username = "user"
+	userPass.Password = "pass"
+	aws := credentials.NewAmazonWebServicesCredentials()
+	aws.ID = "aws"
+	aws.AccessKey = "AKIA"
+	aws.SecretKey = "secret"
+
+	assert.NoError(t, gitlab.Initialize(context.Background(), []credentials.Credentials{newGitlabLoginToken(), userPass, aws}))
+	// The variables are reported as the credentials they belong to, all pages are listed
+	assert.Equal(t, []string{"OTHER", "UNMANAGED", "aws", "my-cred"}, gitlab.GetExistingCredentials())
+
+	assert.NoError(t, gitlab.UpdateCredentials(context.Background(), userPass))
+	assert.NoError(t, gitlab.UpdateCredentials(context.Background(), aws))
+	assert.NoError(t, gitlab.DeleteCredentials(context.Background(), "OTHER"))
+
+	values := map[string]string{}
+	for id, variable := range server.variables {
+		values[id] = variable.Value
+	}
+	assert.Equal(t, map[string]string{
+		"AWS_ACCESS_KEY_ID:*":     "AKIA",
+		"AWS_SECRET_ACCESS_KEY:*": "secret",
+		"MY_CRED_PASSWORD:*":      "pass",
+		"MY_CRED_USERNAME:*":      "user",
+		"UNMANAGED:*":             "unmanaged",
+	}, values)
+
+	description, err := gitlab.GetCredentialsDescription(context.Background(), "UNMANAGED")
+	assert.NoError(t, err)
+	assert.Equal(t, "", description)
+	assert.NoError(t, gitlab.SetCredentialsDescription(context.Background(), "UNMANAGED", "[unsynced] description"))
+	assert.Equal(t, &gitlabVariable{Key: "UNMANAGED", Value: "unmanaged", EnvironmentScope: "*", Description: "[unsynced] description"}, server.variables["UNMANAGED:*"])
+
+	assert.NoError(t, gitlab.DeleteCredentials(context.Background(), "my-cred"))
+	assert.NotContains(t, server.variables, "MY_CRED_USERNAME:*")
+}
+
+func TestGitlabInitializeErrors(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeGitlab(t, "/api/v4/groups/coveooss/variables")
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "userpass"
+	invalidToken := credentials.NewSecretText()
+	invalidToken.ID = "invalid-token"
+	invalidToken.Secret = "invalid"
+	allCredentials := []credentials.Credentials{newGitlabLoginToken(), userPass, invalidToken}
+
+	gitlab := &GitlabTarget{Base: Base{Name: "test"}, CredentialsID: "gitlab-token", URL: server.URL, Group: "coveooss"}
+	assert.NoError(t, gitlab.Initialize(context.Background(), allCredentials))
+	assert.Empty(t, gitlab.GetExistingCredentials())
+
+	gitlab.CredentialsID = "missing"
+	assert.EqualError(t, gitlab.Initialize(context.Background(), allCredentials), "the credentials with ID missing, used to log in to GitLab, were not found")
+
+	gitlab.CredentialsID = "userpass"
+	assert.EqualError(t, gitlab.Initialize(context.Background(), allCredentials), "the credentials with ID userpass, used to log in to GitLab, must be of the `secret` type")
+
+	gitlab.CredentialsID = "invalid-token"
+	err := gitlab.Initialize(context.Background(), allCredentials)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unable to list the variables")
+		assert.Contains(t, err.Error(), "invalid response code 401")
+	}
+}
@@ -1,35 +1,197 @@
 package targets
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 
 	"github.com/bndr/gojenkins"
 	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/retry"
 )
 
-const credentialsDomain = "_"
+const defaultCredentialsDomain = "_"
 
 // JenkinsTarget represents a Jenkins instance
+// Credentials are synced to a domain of the system credentials store, or of the store of a folder
+// The store can be overridden by each credentials
 type JenkinsTarget struct {
 	Base `mapstructure:",squash"`
 
 	CredentialsID      *string `mapstructure:"credentials_id"`
 	URL                string
 	InsecureConnection bool `mapstructure:"insecure_connection"`
+	// Path of the folder (e.g. `team/project`) whose store contains the credentials. The system store is used if it is not set
+	Folder string `mapstructure:"folder"`
+	// Credentials domain, created if missing. Defaults to the global domain
+	Domain string `mapstructure:"domain"`
+	// URL of the GitHub API of the GitHub App credentials that do not define one. Defaults to github.com
+	GithubAPIURI string `mapstructure:"github_api_uri"`
 
 	client              *gojenkins.Jenkins
-	credentialsManager  *gojenkins.CredentialsManager
 	existingCredentials []string
-	loginCredentials    credentials.Credentials
+	// Credentials ID -> Store that contains the credentials
+	existingStores   map[string]jenkinsStore
+	missingDomains   map[jenkinsStore]bool
+	loginCredentials credentials.Credentials
+	transport        http.RoundTripper
+}
+
+// jenkinsStore identifies a credentials domain, in the system store or in the store of a folder
+type jenkinsStore struct {
+	Folder string
+	Domain string
+}
+
+// storeURL returns the path of the credentials store, relative to the Jenkins URL
+func (store jenkinsStore) storeURL() string {
+	if store.Folder == "" {
+		return "/credentials/store/system"
+	}
+	return "/job/" + store.folderPath() + "/credentials/store/folder"
+}
+
+// folderPath returns the path of the folder, as expected in Jenkins URLs (`team/job/project`)
+func (store jenkinsStore) folderPath() string {
+	segments := strings.Split(store.Folder, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/job/")
+}
+
+func (store jenkinsStore) String() string {
+	if store.Folder == "" {
+		return fmt.Sprintf("the %s domain of the system store", store.Domain)
+	}
+	return fmt.Sprintf("the %s domain of the %s folder", store.Domain, store.Folder)
+}
+
+// jenkinsContextTransport attaches the context of an operation to the requests sent to Jenkins, since the Jenkins
+// client does not support contexts
+type jenkinsContextTransport struct {
+	base http.RoundTripper
+	ctx  context.Context
+}
+
+func (transport *jenkinsContextTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return transport.base.RoundTrip(request.WithContext(transport.ctx))
+}
+
+func (jenkins *JenkinsTarget) createClient(options *gojenkins.JenkinsOptions) {
+	jenkins.transport = &retry.Transport{Base: http.DefaultTransport, Policy: jenkins.Retry, Name: jenkins.Name}
+	options.Client = &http.Client{Transport: jenkins.transport}
+	jenkins.client = gojenkins.CreateJenkinsWithOptions(jenkins.URL, options)
+}
+
+// clientFor returns a copy of the Jenkins client whose requests use the given context
+// Each operation uses its own copy, so that concurrent operations do not share their context
+func (jenkins *JenkinsTarget) clientFor(ctx context.Context) *gojenkins.Jenkins {
+	client := *jenkins.client
+	requester := *client.Requester
+	requester.Client = &http.Client{Transport: &jenkinsContextTransport{base: jenkins.transport, ctx: ctx}}
+	client.Requester = &requester
+	return &client
+}
+
+func (jenkins *JenkinsTarget) credentialsManager(client *gojenkins.Jenkins, store jenkinsStore) *gojenkins.CredentialsManager {
+	manager := &gojenkins.CredentialsManager{J: client}
+	if store.Folder != "" {
+		manager.Folder = store.folderPath()
+	}
+	return manager
+}
+
+// defaultStore returns the store configured on the target
+func (jenkins *JenkinsTarget) defaultStore() jenkinsStore {
+	store := jenkinsStore{Folder: strings.Trim(jenkins.Folder, "/"), Domain: jenkins.Domain}
+	if store.Domain == "" {
+		store.Domain = defaultCredentialsDomain
+	}
+	return store
+}
+
+// jenkinsStoreOptions are the options of credentials on Jenkins targets: the store they are synced to
+// Unset options take the target's value
+type jenkinsStoreOptions struct {
+	Folder string `mapstructure:"folder"`
+	Domain string `mapstructure:"domain"`
+}
+
+// storeOf returns the store of the given credentials, which may override the target's store
+// Credentials can be synced to the system store of a target that uses a folder by setting their folder to `/`
+func (jenkins *JenkinsTarget) storeOf(creds credentials.Credentials) (jenkinsStore, error) {
+	store := jenkins.defaultStore()
+	options := jenkinsStoreOptions{}
+	if err := decodeTargetOptions(creds, "jenkins", &options); err != nil {
+		return store, err
+	}
+	if options.Folder != "" {
+		store.Folder = strings.Trim(options.Folder, "/")
+	}
+	if options.Domain != "" {
+		store.Domain = options.Domain
+	}
+	return store, nil
+}
+
+// existingStoreOf returns the store that contains the credentials with the given ID, or the target's store
+func (jenkins *JenkinsTarget) existingStoreOf(id string) jenkinsStore {
+	if store, ok := jenkins.existingStores[id]; ok {
+		return store
+	}
+	return jenkins.defaultStore()
+}
+
+// domainExists returns false if the domain of the given store does not exist. The global domain always exists
+func (jenkins *JenkinsTarget) domainExists(client *gojenkins.Jenkins, store jenkinsStore) (bool, error) {
+	if store.Domain == defaultCredentialsDomain {
+		return true, nil
+	}
+	response, err := client.Requester.Get(fmt.Sprintf("%s/domain/%s/api/json", store.storeURL(), url.PathEscape(store.Domain)), nil, map[string]string{})
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if response.StatusCode != 200 {
+		return false, fmt.Errorf("invalid response code %d", response.StatusCode)
+	}
+	return true, nil
+}
+
+// jenkinsDomain is the configuration of a credentials domain, without specifications
+type jenkinsDomain struct {
+	XMLName xml.Name `xml:"com.cloudbees.plugins.credentials.domains.Domain"`
+	Name    string   `xml:"name"`
+}
+
+func (jenkins *JenkinsTarget) createDomain(client *gojenkins.Jenkins, store jenkinsStore) error {
+	payload, err := xml.Marshal(&jenkinsDomain{Name: store.Domain})
+	if err != nil {
+		return err
+	}
+	response, err := client.Requester.PostXML(store.storeURL()+"/createDomain", string(payload), nil, map[string]string{})
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %v", store, err)
+	}
+	if response.StatusCode != 200 {
+		return fmt.Errorf("unable to create %s: invalid response code %d", store, response.StatusCode)
+	}
+	return nil
 }
 
 // Initialize executes all necessary operations to prepare the Jenkins target for sync
-func (jenkins *JenkinsTarget) Initialize(allCredentials []credentials.Credentials) (err error) {
+func (jenkins *JenkinsTarget) Initialize(ctx context.Context, allCredentials []credentials.Credentials) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			switch x := r.(type) {
@@ -53,26 +215,57 @@ func (jenkins *JenkinsTarget) Initialize(allCredentials []credentials.Credential
 		options.Username = &auth.Username
 		options.Password = &auth.Password
 	}
-	jenkins.client = gojenkins.CreateJenkinsWithOptions(jenkins.URL, options)
+	jenkins.createClient(options)
+	client := jenkins.clientFor(ctx)
 
-	_, err = jenkins.client.Init()
+	_, err = client.Init()
 
 	if err != nil {
 		return err
 	}
 
-	jenkins.credentialsManager = &gojenkins.CredentialsManager{
-		J: jenkins.client,
+	// The credentials are listed in all the stores they can be synced to
+	// Missing domains are only created when credentials are synced to them
+	stores := []jenkinsStore{jenkins.defaultStore()}
+	for _, creds := range allCredentials {
+		// The options of credentials are only validated when they are synced, so that they do not fail the whole target
+		if store, err := jenkins.storeOf(creds); err == nil && creds.ShouldSync(jenkins.Name, jenkins.Tags) && !slices.Contains(stores, store) {
+			stores = append(stores, store)
+		}
 	}
-
-	jenkins.existingCredentials, err = jenkins.credentialsManager.List(credentialsDomain)
-
-	return err
+	jenkins.existingCredentials = []string{}
+	jenkins.existingStores = map[string]jenkinsStore{}
+	jenkins.missingDomains = map[jenkinsStore]bool{}
+	for _, store := range stores {
+		exists, err := jenkins.domainExists(client, store)
+		if err != nil {
+			return fmt.Errorf("unable to fetch %s: %v", store, err)
+		}
+		if !exists {
+			jenkins.missingDomains[store] = true
+			continue
+		}
+		ids, err := jenkins.credentialsManager(client, store).List(url.PathEscape(store.Domain))
+		if err != nil {
+			return fmt.Errorf("unable to list the credentials of %s: %v", store, err)
+		}
+		for _, id := range ids {
+			if _, ok := jenkins.existingStores[id]; !ok {
+				jenkins.existingCredentials = append(jenkins.existingCredentials, id)
+				jenkins.existingStores[id] = store
+			}
+		}
+	}
+	return nil
 }
 
 // ToString prints out a description of the Jenkins instance
 func (jenkins *JenkinsTarget) ToString() string {
-	return fmt.Sprintf("%s (Jenkins) - %s", jenkins.BaseToString(), jenkins.URL)
+	description := fmt.Sprintf("%s (Jenkins) - %s", jenkins.BaseToString(), jenkins.URL)
+	if store := jenkins.defaultStore(); store != (jenkinsStore{Domain: defaultCredentialsDomain}) {
+		description += ", " + store.String()
+	}
+	return description
 }
 
 // GetExistingCredentials returns a list of all credential IDs on the target
@@ -80,21 +273,147 @@ func (jenkins *JenkinsTarget) GetExistingCredentials() []string {
 	return jenkins.existingCredentials
 }
 
-// DeleteCredentials deletes the credentials with the given ID on the target
-func (jenkins *JenkinsTarget) DeleteCredentials(id string) error {
-	return jenkins.credentialsManager.Delete(credentialsDomain, id)
+// DeleteCredentials deletes the credentials with the given ID from the store that contains them
+func (jenkins *JenkinsTarget) DeleteCredentials(ctx context.Context, id string) error {
+	store := jenkins.existingStoreOf(id)
+	if err := jenkins.credentialsManager(jenkins.clientFor(ctx), store).Delete(url.PathEscape(store.Domain), id); err != nil {
+		return err
+	}
+	delete(jenkins.existingStores, id)
+	return nil
+}
+
+// jenkinsRawCredentials represents any type of Jenkins credentials as returned by the config.xml endpoint
+// Only the description is parsed, all other fields are kept as is so that they can be sent back to Jenkins
+type jenkinsRawCredentials struct {
+	XMLName     xml.Name
+	Attrs       []xml.Attr          `xml:",any,attr"`
+	Description string              `xml:"description"`
+	Fields      []jenkinsRawElement `xml:",any"`
+}
+
+type jenkinsRawElement struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
+}
+
+func (jenkins *JenkinsTarget) credentialsConfigURL(id string) string {
+	store := jenkins.existingStoreOf(id)
+	return fmt.Sprintf("%s/domain/%s/credential/%s/config.xml", store.storeURL(), url.PathEscape(store.Domain), url.PathEscape(id))
+}
+
+func (jenkins *JenkinsTarget) getRawCredentials(client *gojenkins.Jenkins, id string) (*jenkinsRawCredentials, error) {
+	var content string
+	response, err := client.Requester.Get(jenkins.credentialsConfigURL(id), &content, map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("invalid response code %d", response.StatusCode)
+	}
+	// Jenkins may declare XML 1.1, which is not supported by the parser. The declaration is not needed, so it is dropped
+	if strings.HasPrefix(content, "<?xml") {
+		content = content[strings.Index(content, "?>")+2:]
+	}
+	rawCredentials := &jenkinsRawCredentials{}
+	if err := xml.Unmarshal([]byte(content), rawCredentials); err != nil {
+		return nil, fmt.Errorf("unable to parse the credentials with ID %s: %v", id, err)
+	}
+	return rawCredentials, nil
+}
+
+// GetCredentialsDescription returns the description of the credentials with the given ID on the Jenkins instance
+func (jenkins *JenkinsTarget) GetCredentialsDescription(ctx context.Context, id string) (string, error) {
+	rawCredentials, err := jenkins.getRawCredentials(jenkins.clientFor(ctx), id)
+	if err != nil {
+		return "", err
+	}
+	return rawCredentials.Description, nil
 }
 
-// UpdateCredentials syncs the given credentials to the Jenkins instance
-func (jenkins *JenkinsTarget) UpdateCredentials(cred credentials.Credentials) error {
-	jenkinsCred := toJenkinsCredential(cred)
-	if jenkinsCred == nil {
-		return fmt.Errorf("unable to create jenkins credentials from %s", cred.GetID())
+// SetCredentialsDescription modifies the description of the credentials with the given ID on the Jenkins instance
+// The other fields of the credentials (including encrypted secrets) are sent back as is
+func (jenkins *JenkinsTarget) SetCredentialsDescription(ctx context.Context, id string, description string) error {
+	client := jenkins.clientFor(ctx)
+	rawCredentials, err := jenkins.getRawCredentials(client, id)
+	if err != nil {
+		return err
+	}
+	rawCredentials.Description = description
+	payload, err := xml.Marshal(rawCredentials)
+	if err != nil {
+		return err
 	}
-	if HasCredential(jenkins, cred.GetTargetID()) {
-		return jenkins.credentialsManager.Update(credentialsDomain, cred.GetTargetID(), jenkinsCred)
+	response, err := client.Requester.PostXML(jenkins.credentialsConfigURL(id), string(payload), nil, map[string]string{})
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != 200 {
+		return fmt.Errorf("invalid response code %d", response.StatusCode)
 	}
-	return jenkins.credentialsManager.Add(credentialsDomain, jenkinsCred)
+	return nil
+}
+
+// renderedJenkinsCredentials is the XML payload of credentials, along with the store it is sent to
+type renderedJenkinsCredentials struct {
+	XMLName     xml.Name    `xml:"credentials"`
+	Folder      string      `xml:"folder,attr"`
+	Domain      string      `xml:"domain,attr"`
+	Credentials interface{} `xml:",any"`
+}
+
+// RenderCredentials returns the XML payload that is sent to the Jenkins instance for the given credentials
+// The payload is wrapped in an element with the folder and domain of the store, so that moving credentials changes it
+func (jenkins *JenkinsTarget) RenderCredentials(cred credentials.Credentials) ([]byte, error) {
+	jenkinsCred, err := jenkins.toJenkinsCredential(cred)
+	if err != nil {
+		return nil, err
+	}
+	store, err := jenkins.storeOf(cred)
+	if err != nil {
+		return nil, err
+	}
+	return xml.Marshal(renderedJenkinsCredentials{Folder: store.Folder, Domain: store.Domain, Credentials: jenkinsCred})
+}
+
+// UpdateCredentials syncs the given credentials to their store on the Jenkins instance
+// Credentials that exist in another store are moved, and the domain is created if it is missing
+func (jenkins *JenkinsTarget) UpdateCredentials(ctx context.Context, cred credentials.Credentials) error {
+	jenkinsCred, err := jenkins.toJenkinsCredential(cred)
+	if err != nil {
+		return err
+	}
+	id := cred.GetTargetID()
+	store, err := jenkins.storeOf(cred)
+	if err != nil {
+		return err
+	}
+	if existingStore, exists := jenkins.existingStores[id]; exists && existingStore != store {
+		if err := jenkins.DeleteCredentials(ctx, id); err != nil {
+			return fmt.Errorf("unable to remove the credentials with ID %s from %s: %v", id, existingStore, err)
+		}
+	}
+
+	client := jenkins.clientFor(ctx)
+	if jenkins.missingDomains[store] {
+		if err := jenkins.createDomain(client, store); err != nil {
+			return err
+		}
+		delete(jenkins.missingDomains, store)
+	}
+	manager := jenkins.credentialsManager(client, store)
+	if _, exists := jenkins.existingStores[id]; exists {
+		return manager.Update(url.PathEscape(store.Domain), id, jenkinsCred)
+	}
+	if err := manager.Add(url.PathEscape(store.Domain), jenkinsCred); err != nil {
+		return err
+	}
+	if jenkins.existingStores == nil {
+		jenkins.existingStores = map[string]jenkinsStore{}
+	}
+	jenkins.existingStores[id] = store
+	return nil
 }
 
 // ValidateConfiguration verifies that Jenkins configuration is valid
@@ -102,6 +421,11 @@ func (jenkins *JenkinsTarget) ValidateConfiguration() error {
 	if _, err := url.ParseRequestURI(jenkins.URL); err != nil {
 		return fmt.Errorf("the Jenkins target `%s` has an invalid URL: %s", jenkins.Name, jenkins.URL)
 	}
+	if jenkins.GithubAPIURI != "" {
+		if _, err := url.ParseRequestURI(jenkins.GithubAPIURI); err != nil {
+			return fmt.Errorf("the Jenkins target `%s` has an invalid `github_api_uri`: %s", jenkins.Name, jenkins.GithubAPIURI)
+		}
+	}
 	return nil
 }
 
@@ -127,7 +451,74 @@ type JenkinsGithubAppCredentials struct {
 	Owner       string   `xml:"owner,omitempty"`
 }
 
-func toJenkinsCredential(creds credentials.Credentials) interface{} {
+// JenkinsSecretFileCredentials is the Jenkins Plain Credentials plugin's secret file configuration
+// The content of the file is encoded in base64
+type JenkinsSecretFileCredentials struct {
+	XMLName     xml.Name `xml:"org.jenkinsci.plugins.plaincredentials.impl.FileCredentialsImpl"`
+	ID          string   `xml:"id"`
+	Description string   `xml:"description,omitempty"`
+	FileName    string   `xml:"fileName"`
+	SecretBytes string   `xml:"secretBytes"`
+}
+
+// JenkinsCertificateCredentials is the Jenkins Credentials plugin's certificate configuration
+// The keystore is a PKCS#12 file encoded in base64
+type JenkinsCertificateCredentials struct {
+	XMLName        xml.Name                `xml:"com.cloudbees.plugins.credentials.impl.CertificateCredentialsImpl"`
+	ID             string                  `xml:"id"`
+	Description    string                  `xml:"description,omitempty"`
+	Password       string                  `xml:"password"`
+	KeyStoreSource JenkinsUploadedKeyStore `xml:"keyStoreSource"`
+}
+
+// JenkinsUploadedKeyStore is the source of the keystore of certificate credentials, when it is uploaded
+type JenkinsUploadedKeyStore struct {
+	Class            string `xml:"class,attr"`
+	UploadedKeystore string `xml:"uploadedKeystore"`
+}
+
+const jenkinsUploadedKeyStoreClass = "com.cloudbees.plugins.credentials.impl.CertificateCredentialsImpl$UploadedKeyStoreSource"
+
+// toJenkinsCredential converts the given credentials and adds the target's ownership marker to their description
+// GitHub App credentials that do not define an API URI use the target's default
+func (jenkins *JenkinsTarget) toJenkinsCredential(creds credentials.Credentials) (interface{}, error) {
+	jenkinsCred, err := toJenkinsCredential(creds)
+	if err != nil {
+		return nil, err
+	}
+	if githubApp, ok := creds.(*credentials.GithubAppCredentials); ok && githubApp.APIURI == "" && jenkins.GithubAPIURI != "" {
+		jenkinsCred.(*JenkinsGithubAppCredentials).APIURI = jenkins.GithubAPIURI
+	}
+	description := jenkinsDescriptionOf(jenkinsCred)
+	*description = jenkins.MarkDescription(*description)
+	return jenkinsCred, nil
+}
+
+// jenkinsDescriptionOf returns the description field of the given Jenkins credentials
+// It must handle every type returned by toJenkinsCredential
+func jenkinsDescriptionOf(jenkinsCred interface{}) *string {
+	switch castCred := jenkinsCred.(type) {
+	case *gojenkins.AmazonWebServicesCredentials:
+		return &castCred.Description
+	case *gojenkins.StringCredentials:
+		return &castCred.Description
+	case *gojenkins.UsernameCredentials:
+		return &castCred.Description
+	case *gojenkins.SSHCredentials:
+		return &castCred.Description
+	case *JenkinsGithubAppCredentials:
+		return &castCred.Description
+	case *JenkinsSecretFileCredentials:
+		return &castCred.Description
+	case *JenkinsCertificateCredentials:
+		return &castCred.Description
+	}
+	panic(fmt.Sprintf("unsupported jenkins credentials type %T", jenkinsCred))
+}
+
+// toJenkinsCredential converts the given credentials to the corresponding Jenkins credentials type
+// An error is returned if the type is not supported by Jenkins or if the credentials cannot be converted
+func toJenkinsCredential(creds credentials.Credentials) (interface{}, error) {
 	switch castCreds := creds.(type) {
 	case *credentials.AmazonWebServicesCredentials:
 		return &gojenkins.AmazonWebServicesCredentials{
@@ -137,20 +528,20 @@ func toJenkinsCredential(creds credentials.Credentials) interface{} {
 			SecretKey:          castCreds.SecretKey,
 			IAMRoleARN:         castCreds.RoleARN,
 			IAMMFASerialNumber: castCreds.MFASerialNumber,
-		}
+		}, nil
 	case *credentials.SecretTextCredentials:
 		return &gojenkins.StringCredentials{
 			ID:          creds.GetTargetID(),
 			Description: castCreds.GetDescriptionOrID(),
 			Secret:      castCreds.Secret,
-		}
+		}, nil
 	case *credentials.UsernamePasswordCredentials:
 		return &gojenkins.UsernameCredentials{
 			ID:          castCreds.GetTargetID(),
 			Description: castCreds.GetDescriptionOrID(),
 			Username:    castCreds.Username,
 			Password:    castCreds.Password,
-		}
+		}, nil
 	case *credentials.SSHCredentials:
 		return &gojenkins.SSHCredentials{
 			ID:          castCreds.GetTargetID(),
@@ -161,16 +552,45 @@ func toJenkinsCredential(creds credentials.Credentials) interface{} {
 				Class: gojenkins.KeySourceDirectEntryType,
 				Value: castCreds.PrivateKey,
 			},
-		}
+		}, nil
 	case *credentials.GithubAppCredentials:
+		apiURI := castCreds.APIURI
+		if apiURI == "" {
+			apiURI = defaultGithubAPIURL
+		}
 		return &JenkinsGithubAppCredentials{
 			ID:          castCreds.GetTargetID(),
 			Description: castCreds.GetDescriptionOrID(),
 			AppID:       castCreds.AppID,
 			PrivateKey:  castCreds.PrivateKey,
-			APIURI:      "https://api.github.com",
+			APIURI:      apiURI,
 			Owner:       castCreds.Owner,
+		}, nil
+	case *credentials.SecretFileCredentials:
+		content, err := castCreds.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode the content of the secret file %s: %v", creds.GetID(), err)
 		}
+		return &JenkinsSecretFileCredentials{
+			ID:          castCreds.GetTargetID(),
+			Description: castCreds.GetDescriptionOrID(),
+			FileName:    castCreds.Filename,
+			SecretBytes: base64.StdEncoding.EncodeToString(content),
+		}, nil
+	case *credentials.CertificateCredentials:
+		keystore, err := castCreds.GetPKCS12()
+		if err != nil {
+			return nil, fmt.Errorf("unable to create the keystore of the certificate %s: %v", creds.GetID(), err)
+		}
+		return &JenkinsCertificateCredentials{
+			ID:          castCreds.GetTargetID(),
+			Description: castCreds.GetDescriptionOrID(),
+			Password:    castCreds.Password,
+			KeyStoreSource: JenkinsUploadedKeyStore{
+				Class:            jenkinsUploadedKeyStoreClass,
+				UploadedKeystore: base64.StdEncoding.EncodeToString(keystore),
+			},
+		}, nil
 	}
-	return nil
+	return nil, fmt.Errorf("unable to create jenkins credentials from %s", creds.GetID())
 }
@@ -1,11 +1,29 @@
 package targets
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bndr/gojenkins"
 
 	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/retry"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,7 +44,8 @@ func TestAwsCredentialsToJenkinsCred(t *testing.T) {
 	secret.SecretKey = "test-secret"
 	secret.RoleARN = "my-role"
 
-	jenkinsSecretInterface := toJenkinsCredential(secret)
+	jenkinsSecretInterface, err := toJenkinsCredential(secret)
+	assert.NoError(t, err)
 
 	jenkinsSecret := jenkinsSecretInterface.(*gojenkins.AmazonWebServicesCredentials)
 	assert.Equal(t, secret.ID, jenkinsSecret.ID)
@@ -42,7 +61,8 @@ func TestSecretToJenkinsCred(t *testing.T) {
 	secret.Description = "a test description"
 	secret.Secret = "a-secret"
 
-	jenkinsSecretInterface := toJenkinsCredential(secret)
+	jenkinsSecretInterface, err := toJenkinsCredential(secret)
+	assert.NoError(t, err)
 
 	jenkinsSecret := jenkinsSecretInterface.(*gojenkins.StringCredentials)
 	assert.Equal(t, secret.ID, jenkinsSecret.ID)
@@ -57,7 +77,8 @@ func TestSSHKeyToJenkinsCred(t *testing.T) {
 	secret.Passphrase = "a-password"
 	secret.PrivateKey = "a-key"
 
-	jenkinsSecretInterface := toJenkinsCredential(secret)
+	jenkinsSecretInterface, err := toJenkinsCredential(secret)
+	assert.NoError(t, err)
 
 	jenkinsSecret := jenkinsSecretInterface.(*gojenkins.SSHCredentials)
 	assert.Equal(t, secret.ID, jenkinsSecret.ID)
@@ -75,7 +96,8 @@ func TestUsernamePasswordToJenkinsCred(t *testing.T) {
 	secret.Username = "a-user"
 	secret.Password = "a-password"
 
-	jenkinsSecretInterface := toJenkinsCredential(secret)
+	jenkinsSecretInterface, err := toJenkinsCredential(secret)
+	assert.NoError(t, err)
 
 	jenkinsSecret := jenkinsSecretInterface.(*gojenkins.UsernameCredentials)
 	assert.Equal(t, secret.ID, jenkinsSecret.ID)
@@ -92,7 +114,8 @@ func TestGithubAppToJenkinsCred(t *testing.T) {
 	secret.PrivateKey = "a-key"
 	secret.AppID = 12345
 
-	jenkinsSecretInterface := toJenkinsCredential(secret)
+	jenkinsSecretInterface, err := toJenkinsCredential(secret)
+	assert.NoError(t, err)
 
 	jenkinsSecret := jenkinsSecretInterface.(*JenkinsGithubAppCredentials)
 	assert.Equal(t, secret.ID, jenkinsSecret.ID)
@@ -100,4 +123,440 @@ func TestGithubAppToJenkinsCred(t *testing.T) {
 	assert.Equal(t, secret.Owner, jenkinsSecret.Owner)
 	assert.Equal(t, secret.PrivateKey, jenkinsSecret.PrivateKey)
 	assert.Equal(t, secret.AppID, jenkinsSecret.AppID)
+	assert.Equal(t, "https://api.github.com", jenkinsSecret.APIURI)
+}
+
+func TestGithubAppAPIURIToJenkinsCred(t *testing.T) {
+	app := credentials.NewGithubAppCredentials()
+	app.ID = "test-id"
+	app.PrivateKey = "a-key"
+	app.AppID = 12345
+
+	// The target's default is used when the credentials do not define an API URI
+	jenkins := &JenkinsTarget{Base: Base{Name: "test"}, GithubAPIURI: "https://github.example.com/api/v3"}
+	jenkinsApp, err := jenkins.toJenkinsCredential(app)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.example.com/api/v3", jenkinsApp.(*JenkinsGithubAppCredentials).APIURI)
+
+	app.APIURI = "https://other.example.com/api/v3"
+	jenkinsApp, err = jenkins.toJenkinsCredential(app)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://other.example.com/api/v3", jenkinsApp.(*JenkinsGithubAppCredentials).APIURI)
+	jenkinsApp, err = toJenkinsCredential(app)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://other.example.com/api/v3", jenkinsApp.(*JenkinsGithubAppCredentials).APIURI)
+}
+
+func TestJenkinsValidateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		target        *JenkinsTarget
+		expectedError error
+	}{
+		{
+			name:          "Valid",
+			target:        &JenkinsTarget{Base: Base{Name: "test"}, URL: "https://jenkins.example.com", GithubAPIURI: "https://github.example.com/api/v3"},
+			expectedError: nil,
+		},
+		{
+			name:          "Invalid URL",
+			target:        &JenkinsTarget{Base: Base{Name: "test"}, URL: "jenkins"},
+			expectedError: fmt.Errorf("the Jenkins target `test` has an invalid URL: jenkins"),
+		},
+		{
+			name:          "Invalid GitHub API URI",
+			target:        &JenkinsTarget{Base: Base{Name: "test"}, URL: "https://jenkins.example.com", GithubAPIURI: "github.example.com"},
+			expectedError: fmt.Errorf("the Jenkins target `test` has an invalid `github_api_uri`: github.example.com"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.target.ValidateConfiguration())
+		})
+	}
+}
+
+func TestSecretFileToJenkinsCred(t *testing.T) {
+	secretFile := credentials.NewSecretFileCredentials()
+	secretFile.ID = "test-id"
+	secretFile.Filename = "kubeconfig"
+	secretFile.Content = "apiVersion: v1"
+
+	jenkinsCred, err := toJenkinsCredential(secretFile)
+	assert.NoError(t, err)
+	jenkinsSecretFile := jenkinsCred.(*JenkinsSecretFileCredentials)
+	assert.Equal(t, secretFile.ID, jenkinsSecretFile.ID)
+	assert.Equal(t, secretFile.ID, jenkinsSecretFile.Description)
+	assert.Equal(t, "kubeconfig", jenkinsSecretFile.FileName)
+	assert.Equal(t, "YXBpVmVyc2lvbjogdjE=", jenkinsSecretFile.SecretBytes)
+
+	payload, err := xml.Marshal(jenkinsSecretFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "<org.jenkinsci.plugins.plaincredentials.impl.FileCredentialsImpl><id>test-id</id><description>test-id</description>"+
+		"<fileName>kubeconfig</fileName><secretBytes>YXBpVmVyc2lvbjogdjE=</secretBytes></org.jenkinsci.plugins.plaincredentials.impl.FileCredentialsImpl>", string(payload))
+
+	// Invalid contents cannot be converted, the error is returned instead of skipping the credentials
+	secretFile.Content = ""
+	secretFile.ContentBase64 = "not base64"
+	_, err = toJenkinsCredential(secretFile)
+	assert.ErrorContains(t, err, "unable to decode the content of the secret file test-id")
+	jenkins := &JenkinsTarget{Base: Base{Name: "test"}}
+	assert.ErrorContains(t, jenkins.UpdateCredentials(context.Background(), secretFile), "unable to decode the content of the secret file test-id")
+}
+
+func TestCertificateToJenkinsCred(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	assert.NoError(t, err)
+	privateKeyBytes, err := x509.MarshalECPrivateKey(privateKey)
+	assert.NoError(t, err)
+
+	cert := credentials.NewCertificateCredentials()
+	cert.ID = "test-id"
+	cert.Description = "a client certificate"
+	cert.Certificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}))
+	cert.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateKeyBytes}))
+	cert.Password = "secret"
+
+	jenkinsCred, err := toJenkinsCredential(cert)
+	assert.NoError(t, err)
+	jenkinsCert := jenkinsCred.(*JenkinsCertificateCredentials)
+	assert.Equal(t, cert.ID, jenkinsCert.ID)
+	assert.Equal(t, cert.Description, jenkinsCert.Description)
+	assert.Equal(t, "secret", jenkinsCert.Password)
+	assert.Equal(t, jenkinsUploadedKeyStoreClass, jenkinsCert.KeyStoreSource.Class)
+	keystore, err := cert.GetPKCS12()
+	assert.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(keystore), jenkinsCert.KeyStoreSource.UploadedKeystore)
+
+	// Invalid certificates cannot be converted
+	cert.PrivateKey = "invalid"
+	_, err = toJenkinsCredential(cert)
+	assert.ErrorContains(t, err, "unable to create the keystore of the certificate test-id")
+	_, err = (&JenkinsTarget{Base: Base{Name: "test"}}).RenderCredentials(cert)
+	assert.ErrorContains(t, err, "unable to create the keystore of the certificate test-id")
+}
+
+// fakeJenkinsCredentialsStore serves the credentials endpoints of a Jenkins instance from an in-memory map of config.xml documents
+type fakeJenkinsCredentialsStore struct {
+	t           *testing.T
+	credentials map[string]string
+}
+
+func (store *fakeJenkinsCredentialsStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := "/credentials/store/system/domain/_/credential/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	id := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")[0]
+	content, ok := store.credentials[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Write([]byte(content))
+	case http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(store.t, err)
+		store.credentials[id] = string(body)
+	}
+}
+
+func newFakeJenkinsTarget(t *testing.T, handler http.Handler) *JenkinsTarget {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	jenkins := &JenkinsTarget{Base: Base{Name: "test"}, URL: server.URL}
+	jenkins.createClient(&gojenkins.JenkinsOptions{})
+	return jenkins
+}
+
+func TestJenkinsCredentialsDescription(t *testing.T) {
+	store := &fakeJenkinsCredentialsStore{t: t, credentials: map[string]string{
+		"test-id": `<?xml version='1.1' encoding='UTF-8'?>
+<com.cloudbees.plugins.credentials.impl.UsernamePasswordCredentialsImpl plugin="credentials@2.3.0">
+  <scope>GLOBAL</scope>
+  <id>test-id</id>
+  <description>a description</description>
+  <username>user</username>
+  <password>{AQAAABAAAAAQ}</password>
+</com.cloudbees.plugins.credentials.impl.UsernamePasswordCredentialsImpl>`,
+	}}
+	jenkins := newFakeJenkinsTarget(t, store)
+
+	description, err := jenkins.GetCredentialsDescription(context.Background(), "test-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "a description", description)
+
+	assert.NoError(t, jenkins.SetCredentialsDescription(context.Background(), "test-id", "a new description"))
+	description, err = jenkins.GetCredentialsDescription(context.Background(), "test-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "a new description", description)
+
+	// All other fields, including the encrypted password, are sent back as is
+	assert.Contains(t, store.credentials["test-id"], `<com.cloudbees.plugins.credentials.impl.UsernamePasswordCredentialsImpl plugin="credentials@2.3.0">`)
+	assert.Contains(t, store.credentials["test-id"], "<password>{AQAAABAAAAAQ}</password>")
+	assert.Contains(t, store.credentials["test-id"], "<username>user</username>")
+	assert.Contains(t, store.credentials["test-id"], "<scope>GLOBAL</scope>")
+
+	_, err = jenkins.GetCredentialsDescription(context.Background(), "unknown")
+	assert.EqualError(t, err, "invalid response code 404")
+	assert.EqualError(t, jenkins.SetCredentialsDescription(context.Background(), "unknown", "description"), "invalid response code 404")
+}
+
+func TestJenkinsCredentialsAreMarked(t *testing.T) {
+	secret := credentials.NewSecretText()
+	secret.ID = "test-id"
+	secret.Secret = "a-secret"
+
+	jenkins := &JenkinsTarget{Base: Base{Name: "test", OwnershipMarker: "[managed]"}}
+	jenkinsSecret, err := jenkins.toJenkinsCredential(secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-id [managed]", jenkinsSecret.(*gojenkins.StringCredentials).Description)
+
+	payload, err := jenkins.RenderCredentials(secret)
+	assert.NoError(t, err)
+	assert.Contains(t, string(payload), "<description>test-id [managed]</description>")
+}
+
+func TestJenkinsAllCredentialsTypesAreMarked(t *testing.T) {
+	aws := credentials.NewAmazonWebServicesCredentials()
+	aws.ID = "aws"
+	secret := credentials.NewSecretText()
+	secret.ID = "secret"
+	userpass := credentials.NewUsernamePassword()
+	userpass.ID = "userpass"
+	ssh := credentials.NewSSHCredentials()
+	ssh.ID = "ssh"
+	app := credentials.NewGithubAppCredentials()
+	app.ID = "app"
+	secretFile, cert, _ := newTestFileCredentials(t)
+
+	jenkins := &JenkinsTarget{Base: Base{Name: "test", OwnershipMarker: "[managed]"}}
+	for _, creds := range []credentials.Credentials{aws, secret, userpass, ssh, app, secretFile, cert} {
+		jenkinsCred, err := jenkins.toJenkinsCredential(creds)
+		assert.NoError(t, err)
+		assert.Equal(t, creds.GetID()+" [managed]", *jenkinsDescriptionOf(jenkinsCred))
+	}
+}
+
+func TestJenkinsRenderedCredentialsIncludeTheStore(t *testing.T) {
+	secret := credentials.NewSecretText()
+	secret.ID = "test-id"
+	secret.Secret = "a-secret"
+
+	jenkins := &JenkinsTarget{Base: Base{Name: "test"}, Folder: "team"}
+	payload, err := jenkins.RenderCredentials(secret)
+	assert.NoError(t, err)
+	assert.Contains(t, string(payload), `<credentials folder="team" domain="_">`)
+	assert.Contains(t, string(payload), "<secret>a-secret</secret>")
+	fingerprint, err := Fingerprint(jenkins, secret)
+	assert.NoError(t, err)
+
+	// Moving the credentials to another domain changes their fingerprint
+	secret.TargetOptions = map[string]map[string]interface{}{"jenkins": {"domain": "deploy"}}
+	payload, err = jenkins.RenderCredentials(secret)
+	assert.NoError(t, err)
+	assert.Contains(t, string(payload), `<credentials folder="team" domain="deploy">`)
+	movedFingerprint, err := Fingerprint(jenkins, secret)
+	assert.NoError(t, err)
+	assert.NotEqual(t, fingerprint, movedFingerprint)
+}
+
+func TestJenkinsRequestsUseTheContext(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	jenkins := newFakeJenkinsTarget(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := jenkins.GetCredentialsDescription(ctx, "test-id")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+}
+
+func TestJenkinsConcurrentRequestsUseTheirContext(t *testing.T) {
+	store := &fakeJenkinsCredentialsStore{t: t, credentials: map[string]string{
+		"test-id": `<org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl><description>a description</description></org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl>`,
+	}}
+	slowRequestReceived := make(chan struct{})
+	jenkins := newFakeJenkinsTarget(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/slow-id/") {
+			close(slowRequestReceived)
+			<-r.Context().Done()
+			return
+		}
+		store.ServeHTTP(w, r)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	slowErr := make(chan error)
+	go func() {
+		_, err := jenkins.GetCredentialsDescription(ctx, "slow-id")
+		slowErr <- err
+	}()
+	<-slowRequestReceived
+
+	// The other operation is not affected by the context of the operation in progress, and does not replace it
+	description, err := jenkins.GetCredentialsDescription(context.Background(), "test-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "a description", description)
+	cancel()
+	assert.ErrorIs(t, <-slowErr, context.Canceled)
+}
+
+func TestJenkinsRequestsAreRetried(t *testing.T) {
+	store := &fakeJenkinsCredentialsStore{t: t, credentials: map[string]string{
+		"test-id": `<org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl><description>a description</description></org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl>`,
+	}}
+	failures := 0
+	jenkins := newFakeJenkinsTarget(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The reverse proxy in front of Jenkins fails once
+		if failures == 0 {
+			failures++
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		store.ServeHTTP(w, r)
+	}))
+	initialBackoff := time.Millisecond
+	jenkins.Retry = retry.Policy{InitialBackoff: &initialBackoff}
+	jenkins.createClient(&gojenkins.JenkinsOptions{})
+
+	counter := &retry.Counter{}
+	description, err := jenkins.GetCredentialsDescription(retry.WithCounter(context.Background(), counter), "test-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "a description", description)
+	assert.Equal(t, 1, counter.Count())
+}
+
+// fakeJenkinsStores serves the credentials stores of a Jenkins instance (system and folders)
+// Credentials are kept by domain URL (e.g. `/job/team/credentials/store/folder/domain/_`) and ID
+type fakeJenkinsStores struct {
+	t       *testing.T
+	domains map[string]map[string]string
+}
+
+var fakeJenkinsIDPattern = regexp.MustCompile(`<id>(.*)</id>`)
+var fakeJenkinsDomainNamePattern = regexp.MustCompile(`<name>(.*)</name>`)
+
+func (stores *fakeJenkinsStores) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	body, err := io.ReadAll(r.Body)
+	assert.NoError(stores.t, err)
+
+	if path == "/api/json" {
+		w.Write([]byte("{}"))
+		return
+	}
+	if storeURL, found := strings.CutSuffix(path, "/createDomain"); found && r.Method == http.MethodPost {
+		stores.domains[storeURL+"/domain/"+fakeJenkinsDomainNamePattern.FindStringSubmatch(string(body))[1]] = map[string]string{}
+		return
+	}
+	index := strings.Index(path, "/domain/")
+	if index < 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	domainURL, action, _ := strings.Cut(path[index+len("/domain/"):], "/")
+	domainURL = path[:index] + "/domain/" + domainURL
+	credentialsMap, ok := stores.domains[domainURL]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "api/json":
+		ids := []string{}
+		for id := range credentialsMap {
+			ids = append(ids, fmt.Sprintf(`{"id": %q}`, id))
+		}
+		sort.Strings(ids)
+		w.Write([]byte(`{"credentials": [` + strings.Join(ids, ",") + `]}`))
+	case action == "createCredentials":
+		credentialsMap[fakeJenkinsIDPattern.FindStringSubmatch(string(body))[1]] = string(body)
+	case strings.HasPrefix(action, "credential/"):
+		id, operation, _ := strings.Cut(strings.TrimPrefix(action, "credential/"), "/")
+		if _, ok := credentialsMap[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch {
+		case operation == "doDelete":
+			delete(credentialsMap, id)
+		case r.Method == http.MethodGet:
+			w.Write([]byte(credentialsMap[id]))
+		default:
+			credentialsMap[id] = string(body)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestJenkinsStores(t *testing.T) {
+	stores := &fakeJenkinsStores{t: t, domains: map[string]map[string]string{
+		"/credentials/store/system/domain/_": {
+			"system-cred": "<org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl><id>system-cred</id></org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl>",
+		},
+		"/job/team/job/project/credentials/store/folder/domain/_": {},
+		"/job/team/job/project/credentials/store/folder/domain/ci": {
+			"folder-cred": "<org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl><id>folder-cred</id><description>a description</description></org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl>",
+			"moved":       "<org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl><id>moved</id></org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl>",
+		},
+	}}
+	server := httptest.NewServer(stores)
+	defer server.Close()
+
+	jenkins := &JenkinsTarget{Base: Base{Name: "test"}, URL: server.URL, Folder: "/team/project/", Domain: "ci"}
+	assert.Equal(t, "test [Tags: ] (Jenkins) - "+server.URL+", the ci domain of the team/project folder", jenkins.ToString())
+
+	moved := credentials.NewSecretText()
+	moved.ID = "moved"
+	moved.Secret = "moved"
+	moved.TargetOptions = map[string]map[string]interface{}{"jenkins": {"domain": "deploy"}}
+	system := credentials.NewSecretText()
+	system.ID = "system-cred"
+	system.Secret = "system"
+	system.TargetOptions = map[string]map[string]interface{}{"jenkins": {"folder": "/", "domain": "_"}}
+	other := credentials.NewSecretText()
+	other.ID = "other"
+	other.Secret = "other"
+	other.TargetName = "other-target"
+	other.TargetOptions = map[string]map[string]interface{}{"jenkins": {"folder": "other"}}
+
+	// The stores of the credentials synced to the target are listed. Missing domains are not created yet
+	assert.NoError(t, jenkins.Initialize(context.Background(), []credentials.Credentials{moved, system, other}))
+	assert.Equal(t, []string{"folder-cred", "moved", "system-cred"}, jenkins.GetExistingCredentials())
+	assert.NotContains(t, stores.domains, "/job/team/job/project/credentials/store/folder/domain/deploy")
+
+	description, err := jenkins.GetCredentialsDescription(context.Background(), "folder-cred")
+	assert.NoError(t, err)
+	assert.Equal(t, "a description", description)
+
+	// The credentials are moved to the domain they are now synced to, which is created
+	assert.NoError(t, jenkins.UpdateCredentials(context.Background(), moved))
+	assert.Contains(t, stores.domains["/job/team/job/project/credentials/store/folder/domain/deploy"], "moved")
+	assert.NotContains(t, stores.domains["/job/team/job/project/credentials/store/folder/domain/ci"], "moved")
+
+	// The credentials are updated in the system store
+	assert.NoError(t, jenkins.UpdateCredentials(context.Background(), system))
+	assert.Contains(t, stores.domains["/credentials/store/system/domain/_"]["system-cred"], "<secret>system</secret>")
+
+	assert.NoError(t, jenkins.DeleteCredentials(context.Background(), "folder-cred"))
+	assert.Empty(t, stores.domains["/job/team/job/project/credentials/store/folder/domain/ci"])
 }
@@ -0,0 +1,448 @@
+package targets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/retry"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	kubernetesManagedByLabel        = "app.kubernetes.io/managed-by"
+	kubernetesManagedByValue        = "credentials-sync"
+	kubernetesIDAnnotation          = "credentials-sync/id"
+	kubernetesDescriptionAnnotation = "credentials-sync/description"
+)
+
+var invalidKubernetesNameCharacters = regexp.MustCompile("[^a-z0-9.-]+")
+
+// KubernetesTarget represents the secrets of a Kubernetes namespace
+// Each credentials is synced to its own secret, or all credentials are synced to a single combined secret
+// Only the secrets labelled as managed by credentials-sync are considered as existing credentials
+type KubernetesTarget struct {
+	Base `mapstructure:",squash"`
+
+	// Path to the kubeconfig file. Defaults to the KUBECONFIG env variable, then to ~/.kube/config, then to the in-cluster config
+	Kubeconfig string `mapstructure:"kubeconfig"`
+	Context    string `mapstructure:"context"`
+	InCluster  bool   `mapstructure:"in_cluster"`
+	Namespace  string `mapstructure:"namespace"`
+	// Name of the secret containing all credentials. If not set, each credentials is synced to its own secret
+	CombinedSecret string `mapstructure:"combined_secret"`
+	// Additional labels of the managed secrets
+	Labels map[string]string `mapstructure:"labels"`
+
+	client              kubernetes.Interface
+	existingCredentials []string
+	// Credentials target ID -> Secret (when each credentials has its own secret)
+	existingSecrets map[string]*corev1.Secret
+	// When all credentials are synced to a single secret
+	combinedSecret     *corev1.Secret
+	credentialsEntries map[string][]string
+}
+
+func (kube *KubernetesTarget) createClient() error {
+	var (
+		config *rest.Config
+		err    error
+	)
+	if kube.InCluster {
+		config, err = rest.InClusterConfig()
+	} else {
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		loadingRules.ExplicitPath = kube.Kubeconfig
+		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: kube.Context}).ClientConfig()
+	}
+	if err != nil {
+		return fmt.Errorf("unable to load the Kubernetes configuration: %v", err)
+	}
+	config.Wrap(func(base http.RoundTripper) http.RoundTripper {
+		return &retry.Transport{Base: base, Policy: kube.Retry, Name: kube.Name}
+	})
+	kube.client, err = kubernetes.NewForConfig(config)
+	return err
+}
+
+func (kube *KubernetesTarget) secrets() typedcorev1.SecretInterface {
+	return kube.client.CoreV1().Secrets(kube.Namespace)
+}
+
+// Initialize executes all necessary operations to prepare the Kubernetes target for sync
+func (kube *KubernetesTarget) Initialize(ctx context.Context, allCredentials []credentials.Credentials) error {
+	if kube.client == nil {
+		if err := kube.createClient(); err != nil {
+			return err
+		}
+	}
+
+	if kube.CombinedSecret != "" {
+		return kube.initializeCombinedSecret(ctx, allCredentials)
+	}
+
+	kube.existingSecrets = map[string]*corev1.Secret{}
+	kube.existingCredentials = []string{}
+	listOptions := metav1.ListOptions{LabelSelector: kubernetesManagedByLabel + "=" + kubernetesManagedByValue}
+	for {
+		secrets, err := kube.secrets().List(ctx, listOptions)
+		if err != nil {
+			return fmt.Errorf("unable to list the secrets: %v", err)
+		}
+		for i := range secrets.Items {
+			secret := &secrets.Items[i]
+			id, ok := secret.Annotations[kubernetesIDAnnotation]
+			if !ok {
+				id = secret.Name
+			}
+			kube.existingSecrets[id] = secret
+			kube.existingCredentials = append(kube.existingCredentials, id)
+		}
+		if listOptions.Continue = secrets.Continue; listOptions.Continue == "" {
+			break
+		}
+	}
+	sort.Strings(kube.existingCredentials)
+	return nil
+}
+
+func (kube *KubernetesTarget) initializeCombinedSecret(ctx context.Context, allCredentials []credentials.Credentials) error {
+	secret, err := kube.secrets().Get(ctx, kube.CombinedSecret, metav1.GetOptions{})
+	if kubeerrors.IsNotFound(err) {
+		secret = nil
+	} else if err != nil {
+		return fmt.Errorf("unable to fetch the %s secret: %v", kube.CombinedSecret, err)
+	} else if secret.Labels[kubernetesManagedByLabel] != kubernetesManagedByValue {
+		return fmt.Errorf("the %s secret exists and is not managed by credentials-sync", kube.CombinedSecret)
+	}
+	kube.combinedSecret = secret
+
+	existingEntries := map[string]bool{}
+	if secret != nil {
+		for key := range secret.Data {
+			existingEntries[key] = true
+		}
+	}
+	kube.existingCredentials, kube.credentialsEntries = groupExistingCredentials(syncedCredentials(kube, allCredentials), existingEntries, func(creds credentials.Credentials) []string {
+		// Credentials that cannot be converted have no keys, the error is returned when they are synced
+		variables, err := toEnvironmentVariables(creds)
+		if err != nil {
+			return nil
+		}
+		keys := []string{}
+		for key := range variables {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	})
+	return nil
+}
+
+// ToString prints out a description of the Kubernetes target
+func (kube *KubernetesTarget) ToString() string {
+	description := fmt.Sprintf("%s (Kubernetes) - namespace %s", kube.BaseToString(), kube.Namespace)
+	if kube.Context != "" {
+		description += " on " + kube.Context
+	}
+	return description
+}
+
+// GetExistingCredentials returns a list of all credential IDs on the target
+func (kube *KubernetesTarget) GetExistingCredentials() []string {
+	return kube.existingCredentials
+}
+
+// DeleteCredentials deletes the secret of the credentials with the given ID, or its keys from the combined secret
+func (kube *KubernetesTarget) DeleteCredentials(ctx context.Context, id string) error {
+	if kube.CombinedSecret != "" {
+		data := map[string]string{}
+		for _, key := range kube.entriesOf(id) {
+			data[key] = ""
+		}
+		return kube.updateCombinedSecret(ctx, data)
+	}
+
+	secret, ok := kube.existingSecrets[id]
+	if !ok {
+		return fmt.Errorf("no managed secret found for the credentials with ID %s", id)
+	}
+	if err := kube.secrets().Delete(ctx, secret.Name, metav1.DeleteOptions{}); err != nil && !kubeerrors.IsNotFound(err) {
+		return err
+	}
+	delete(kube.existingSecrets, id)
+	return nil
+}
+
+// entriesOf returns the existing keys of the credentials with the given ID in the combined secret
+// Other credentials are synced to their own secret, so they have no keys
+func (kube *KubernetesTarget) entriesOf(id string) []string {
+	if kube.CombinedSecret == "" || kube.combinedSecret == nil {
+		return nil
+	}
+	return groupedEntriesOf(id, kube.credentialsEntries, func(key string) bool {
+		_, ok := kube.combinedSecret.Data[key]
+		return ok
+	})
+}
+
+// GetCredentialsDescription returns the description of the secret of the credentials with the given ID
+func (kube *KubernetesTarget) GetCredentialsDescription(ctx context.Context, id string) (string, error) {
+	if kube.CombinedSecret != "" {
+		return kube.Base.GetCredentialsDescription(ctx, id)
+	}
+	secret, ok := kube.existingSecrets[id]
+	if !ok {
+		return "", fmt.Errorf("no managed secret found for the credentials with ID %s", id)
+	}
+	return secret.Annotations[kubernetesDescriptionAnnotation], nil
+}
+
+// SetCredentialsDescription modifies the description of the secret of the credentials with the given ID
+func (kube *KubernetesTarget) SetCredentialsDescription(ctx context.Context, id string, description string) error {
+	if kube.CombinedSecret != "" {
+		return kube.Base.SetCredentialsDescription(ctx, id, description)
+	}
+	secret, ok := kube.existingSecrets[id]
+	if !ok {
+		return fmt.Errorf("no managed secret found for the credentials with ID %s", id)
+	}
+	updatedSecret := secret.DeepCopy()
+	if updatedSecret.Annotations == nil {
+		updatedSecret.Annotations = map[string]string{}
+	}
+	updatedSecret.Annotations[kubernetesDescriptionAnnotation] = description
+	updatedSecret, err := kube.secrets().Update(ctx, updatedSecret, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	kube.existingSecrets[id] = updatedSecret
+	return nil
+}
+
+// RenderCredentials returns the secret (or the keys of the combined secret) that is sent to Kubernetes for the given credentials
+func (kube *KubernetesTarget) RenderCredentials(cred credentials.Credentials) ([]byte, error) {
+	if kube.CombinedSecret != "" {
+		variables, err := toEnvironmentVariables(cred)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create kubernetes secret data from %s: %v", cred.GetID(), err)
+		}
+		return json.Marshal(variables)
+	}
+	secret, err := kube.toKubernetesSecret(cred)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create a kubernetes secret from %s: %v", cred.GetID(), err)
+	}
+	return json.Marshal(secret)
+}
+
+// UpdateCredentials syncs the given credentials to Kubernetes
+// Since the type of a secret cannot be changed, the secret is recreated if the type of the credentials changed
+func (kube *KubernetesTarget) UpdateCredentials(ctx context.Context, cred credentials.Credentials) error {
+	if kube.CombinedSecret != "" {
+		variables, err := toEnvironmentVariables(cred)
+		if err != nil {
+			return fmt.Errorf("unable to create kubernetes secret data from %s: %v", cred.GetID(), err)
+		}
+		return kube.updateCombinedSecret(ctx, variables)
+	}
+
+	secret, err := kube.toKubernetesSecret(cred)
+	if err != nil {
+		return fmt.Errorf("unable to create a kubernetes secret from %s: %v", cred.GetID(), err)
+	}
+	existingSecret, exists := kube.existingSecrets[cred.GetTargetID()]
+	if exists && (existingSecret.Type != secret.Type || existingSecret.Name != secret.Name) {
+		if err := kube.DeleteCredentials(ctx, cred.GetTargetID()); err != nil {
+			return err
+		}
+		exists = false
+	}
+
+	if exists {
+		secret.ResourceVersion = existingSecret.ResourceVersion
+		secret, err = kube.secrets().Update(ctx, secret, metav1.UpdateOptions{})
+	} else {
+		secret, err = kube.secrets().Create(ctx, secret, metav1.CreateOptions{})
+		if kubeerrors.IsAlreadyExists(err) {
+			return fmt.Errorf("the %s secret exists and is not managed by credentials-sync", kube.secretName(cred))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	kube.existingSecrets[cred.GetTargetID()] = secret
+	return nil
+}
+
+// updateCombinedSecret sets the given keys of the combined secret, or removes them if their value is empty
+func (kube *KubernetesTarget) updateCombinedSecret(ctx context.Context, data map[string]string) error {
+	secret := kube.combinedSecret
+	if secret == nil {
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: kube.CombinedSecret, Namespace: kube.Namespace}, Type: corev1.SecretTypeOpaque}
+	} else {
+		secret = secret.DeepCopy()
+	}
+	secret.Labels = kube.labels()
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	for key, value := range data {
+		if value == "" {
+			delete(secret.Data, key)
+		} else {
+			secret.Data[key] = []byte(value)
+		}
+	}
+
+	var err error
+	if kube.combinedSecret == nil {
+		secret, err = kube.secrets().Create(ctx, secret, metav1.CreateOptions{})
+	} else {
+		secret, err = kube.secrets().Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	kube.combinedSecret = secret
+	return nil
+}
+
+// ValidateConfiguration verifies that Kubernetes configuration is valid
+func (kube *KubernetesTarget) ValidateConfiguration() error {
+	if kube.Namespace == "" {
+		return fmt.Errorf("the Kubernetes target `%s` must define a `namespace`", kube.Name)
+	}
+	if kube.InCluster && (kube.Kubeconfig != "" || kube.Context != "") {
+		return fmt.Errorf("the Kubernetes target `%s` cannot define `kubeconfig` or `context` with `in_cluster`", kube.Name)
+	}
+	if kube.CombinedSecret != "" && (kube.TagUnsynced || kube.DeleteOnlyMarked) {
+		return fmt.Errorf("the Kubernetes target `%s` does not support `tag_unsynced` and `delete_only_marked` with a `combined_secret`", kube.Name)
+	}
+	if _, ok := kube.Labels[kubernetesManagedByLabel]; ok {
+		return fmt.Errorf("the `%s` label is reserved on the Kubernetes target `%s`", kubernetesManagedByLabel, kube.Name)
+	}
+	return nil
+}
+
+func (kube *KubernetesTarget) labels() map[string]string {
+	labels := map[string]string{kubernetesManagedByLabel: kubernetesManagedByValue}
+	for key, value := range kube.Labels {
+		labels[key] = value
+	}
+	return labels
+}
+
+// secretName converts the target ID of the given credentials to a valid secret name
+func (kube *KubernetesTarget) secretName(creds credentials.Credentials) string {
+	name := invalidKubernetesNameCharacters.ReplaceAllString(strings.ToLower(creds.GetTargetID()), "-")
+	name = strings.Trim(name, ".-")
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return name
+}
+
+// toKubernetesSecret converts the given credentials to the secret that they are synced to
+// The secret type is chosen from the credentials type, so that Kubernetes validates and understands its content
+func (kube *KubernetesTarget) toKubernetesSecret(creds credentials.Credentials) (*corev1.Secret, error) {
+	var (
+		secretType corev1.SecretType
+		data       map[string]string
+	)
+	switch castCreds := creds.(type) {
+	case *credentials.AmazonWebServicesCredentials:
+		secretType = corev1.SecretTypeOpaque
+		data = map[string]string{
+			"AWS_ACCESS_KEY_ID":     castCreds.AccessKey,
+			"AWS_SECRET_ACCESS_KEY": castCreds.SecretKey,
+			"AWS_ROLE_ARN":          castCreds.RoleARN,
+			"AWS_MFA_SERIAL":        castCreds.MFASerialNumber,
+		}
+	case *credentials.SecretTextCredentials:
+		secretType = corev1.SecretTypeOpaque
+		data = map[string]string{
+			"secret": castCreds.Secret,
+		}
+	case *credentials.UsernamePasswordCredentials:
+		secretType = corev1.SecretTypeBasicAuth
+		data = map[string]string{
+			corev1.BasicAuthUsernameKey: castCreds.Username,
+			corev1.BasicAuthPasswordKey: castCreds.Password,
+		}
+	case *credentials.SSHCredentials:
+		secretType = corev1.SecretTypeSSHAuth
+		data = map[string]string{
+			corev1.SSHAuthPrivateKey: castCreds.PrivateKey,
+			"username":               castCreds.Username,
+			"passphrase":             castCreds.Passphrase,
+		}
+	case *credentials.GithubAppCredentials:
+		secretType = corev1.SecretTypeOpaque
+		data = map[string]string{
+			"app_id":      strconv.Itoa(castCreds.AppID),
+			"private_key": castCreds.PrivateKey,
+			"owner":       castCreds.Owner,
+		}
+	case *credentials.SecretFileCredentials:
+		// The file is stored under its name, so that it keeps it when the secret is mounted as a volume
+		if errs := validation.IsConfigMapKey(castCreds.Filename); len(errs) > 0 {
+			return nil, fmt.Errorf("the filename %q of the secret file is not a valid key of a Kubernetes secret: %s", castCreds.Filename, strings.Join(errs, ", "))
+		}
+		content, err := castCreds.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode the content of the secret file: %v", err)
+		}
+		secretType = corev1.SecretTypeOpaque
+		data = map[string]string{
+			castCreds.Filename: string(content),
+		}
+	case *credentials.CertificateCredentials:
+		keystore, err := castCreds.GetPKCS12()
+		if err != nil {
+			return nil, fmt.Errorf("unable to create the keystore of the certificate: %v", err)
+		}
+		secretType = corev1.SecretTypeOpaque
+		data = map[string]string{
+			"keystore.p12": string(keystore),
+			"password":     castCreds.Password,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported credentials type %T", creds)
+	}
+
+	description := kube.markedDescription(creds)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kube.secretName(creds),
+			Namespace: kube.Namespace,
+			Labels:    kube.labels(),
+			Annotations: map[string]string{
+				kubernetesIDAnnotation:          creds.GetTargetID(),
+				kubernetesDescriptionAnnotation: description,
+			},
+		},
+		Type: secretType,
+		Data: map[string][]byte{},
+	}
+	// Optional fields are omitted when they are not set
+	for key, value := range data {
+		if value != "" {
+			secret.Data[key] = []byte(value)
+		}
+	}
+	return secret, nil
+}
@@ -0,0 +1,263 @@
+package targets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newManagedKubernetesSecret(name string, id string, description string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "ci",
+			Labels:      map[string]string{kubernetesManagedByLabel: kubernetesManagedByValue},
+			Annotations: map[string]string{kubernetesIDAnnotation: id, kubernetesDescriptionAnnotation: description},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{"secret": []byte("old")},
+	}
+}
+
+func TestKubernetesToString(t *testing.T) {
+	t.Parallel()
+
+	kube := &KubernetesTarget{
+		Base:      Base{Name: "targetName", Tags: map[string]string{"my_tag": "tag_value"}},
+		Namespace: "ci",
+		Context:   "prod",
+	}
+	assert.Equal(t, "targetName [Tags: my_tag=tag_value] (Kubernetes) - namespace ci on prod", kube.ToString())
+}
+
+func TestKubernetesValidateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		target        *KubernetesTarget
+		expectedError error
+	}{
+		{
+			name:          "Valid",
+			target:        &KubernetesTarget{Base: Base{Name: "test", TagUnsynced: true}, Namespace: "ci", Labels: map[string]string{"team": "ci"}},
+			expectedError: nil,
+		},
+		{
+			name:          "Missing namespace",
+			target:        &KubernetesTarget{Base: Base{Name: "test"}},
+			expectedError: fmt.Errorf("the Kubernetes target `test` must define a `namespace`"),
+		},
+		{
+			name:          "In cluster with kubeconfig",
+			target:        &KubernetesTarget{Base: Base{Name: "test"}, Namespace: "ci", InCluster: true, Kubeconfig: "config"},
+			expectedError: fmt.Errorf("the Kubernetes target `test` cannot define `kubeconfig` or `context` with `in_cluster`"),
+		},
+		{
+			name:          "Tag unsynced with combined secret",
+			target:        &KubernetesTarget{Base: Base{Name: "test", TagUnsynced: true}, Namespace: "ci", CombinedSecret: "credentials"},
+			expectedError: fmt.Errorf("the Kubernetes target `test` does not support `tag_unsynced` and `delete_only_marked` with a `combined_secret`"),
+		},
+		{
+			name:          "Reserved label",
+			target:        &KubernetesTarget{Base: Base{Name: "test"}, Namespace: "ci", Labels: map[string]string{kubernetesManagedByLabel: "me"}},
+			expectedError: fmt.Errorf("the `app.kubernetes.io/managed-by` label is reserved on the Kubernetes target `test`"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.target.ValidateConfiguration())
+		})
+	}
+}
+
+func TestKubernetesCreateClientFromKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	assert.NoError(t, os.WriteFile(kubeconfig, []byte(`
+apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://kubernetes.example.com
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+users:
+- name: test
+  user:
+    token: a-token
+`), 0600))
+
+	kube := &KubernetesTarget{Kubeconfig: kubeconfig, Context: "test"}
+	assert.NoError(t, kube.createClient())
+	assert.NotNil(t, kube.client)
+
+	kube = &KubernetesTarget{Kubeconfig: kubeconfig, Context: "unknown"}
+	assert.Error(t, kube.createClient())
+}
+
+func TestToKubernetesSecret(t *testing.T) {
+	t.Parallel()
+
+	kube := &KubernetesTarget{Base: Base{OwnershipMarker: "[managed]"}, Namespace: "ci", Labels: map[string]string{"team": "ci"}}
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "My_Cred"
+	userPass.Description = "A description"
+	userPass.Username = "user"
+	userPass.Password = "pass"
+	secret, err := kube.toKubernetesSecret(userPass)
+	assert.NoError(t, err)
+	assert.Equal(t, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "my-cred",
+			Namespace:   "ci",
+			Labels:      map[string]string{kubernetesManagedByLabel: kubernetesManagedByValue, "team": "ci"},
+			Annotations: map[string]string{kubernetesIDAnnotation: "My_Cred", kubernetesDescriptionAnnotation: "A description [managed]"},
+		},
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+	}, secret)
+
+	ssh := credentials.NewSSHCredentials()
+	ssh.ID = "ssh"
+	ssh.PrivateKey = "key"
+	secret, err = kube.toKubernetesSecret(ssh)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeSSHAuth, secret.Type)
+	assert.Equal(t, map[string][]byte{"ssh-privatekey": []byte("key")}, secret.Data)
+
+	app := credentials.NewGithubAppCredentials()
+	app.ID = "app"
+	app.AppID = 1234
+	app.PrivateKey = "key"
+	secret, err = kube.toKubernetesSecret(app)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeOpaque, secret.Type)
+	assert.Equal(t, map[string][]byte{"app_id": []byte("1234"), "private_key": []byte("key")}, secret.Data)
+
+	secretFile, cert, keystore := newTestFileCredentials(t)
+	secret, err = kube.toKubernetesSecret(secretFile)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeOpaque, secret.Type)
+	assert.Equal(t, map[string][]byte{"config": []byte("apiVersion: v1\nkind: Config")}, secret.Data)
+	secret, err = kube.toKubernetesSecret(cert)
+	assert.NoError(t, err)
+	keystoreBytes, err := base64.StdEncoding.DecodeString(keystore)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeOpaque, secret.Type)
+	assert.Equal(t, map[string][]byte{"keystore.p12": keystoreBytes, "password": []byte("hunter42")}, secret.Data)
+
+	// The filename of secret files is a key of the secret, so it must be valid
+	secretFile.Filename = "kube/config"
+	_, err = kube.toKubernetesSecret(secretFile)
+	assert.ErrorContains(t, err, `the filename "kube/config" of the secret file is not a valid key of a Kubernetes secret: a valid config key must consist of alphanumeric characters, '-', '_' or '.'`)
+}
+
+func TestKubernetesSync(t *testing.T) {
+	t.Parallel()
+
+	unmanaged := newManagedKubernetesSecret("unmanaged", "unmanaged", "")
+	unmanaged.Labels = nil
+	client := fake.NewClientset(
+		newManagedKubernetesSecret("my-cred", "my-cred", "Old description"),
+		newManagedKubernetesSecret("other", "other", ""),
+		unmanaged,
+	)
+	kube := &KubernetesTarget{Base: Base{Name: "test"}, Namespace: "ci", client: client}
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.Username = "user"
+	userPass.Password = "pass"
+	secret := credentials.NewSecretText()
+	secret.ID = "slack"
+	secret.Secret = "xoxb"
+	conflicting := credentials.NewSecretText()
+	conflicting.ID = "unmanaged"
+	conflicting.Secret = "value"
+
+	assert.NoError(t, kube.Initialize(context.Background(), nil))
+	// Only the secrets labelled as managed are considered
+	assert.Equal(t, []string{"my-cred", "other"}, kube.GetExistingCredentials())
+
+	description, err := kube.GetCredentialsDescription(context.Background(), "my-cred")
+	assert.NoError(t, err)
+	assert.Equal(t, "Old description", description)
+	assert.NoError(t, kube.SetCredentialsDescription(context.Background(), "other", "[unsynced] description"))
+
+	// The type of my-cred changes, so it is recreated
+	assert.NoError(t, kube.UpdateCredentials(context.Background(), userPass))
+	assert.NoError(t, kube.UpdateCredentials(context.Background(), secret))
+	assert.EqualError(t, kube.UpdateCredentials(context.Background(), conflicting), "the unmanaged secret exists and is not managed by credentials-sync")
+
+	secrets := client.CoreV1().Secrets("ci")
+	myCred, err := secrets.Get(context.Background(), "my-cred", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeBasicAuth, myCred.Type)
+	assert.Equal(t, map[string][]byte{"username": []byte("user"), "password": []byte("pass")}, myCred.Data)
+	slack, err := secrets.Get(context.Background(), "slack", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"secret": []byte("xoxb")}, slack.Data)
+	other, err := secrets.Get(context.Background(), "other", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "[unsynced] description", other.Annotations[kubernetesDescriptionAnnotation])
+
+	assert.NoError(t, kube.DeleteCredentials(context.Background(), "other"))
+	_, err = secrets.Get(context.Background(), "other", metav1.GetOptions{})
+	assert.Error(t, err)
+	assert.EqualError(t, kube.DeleteCredentials(context.Background(), "unmanaged"), "no managed secret found for the credentials with ID unmanaged")
+}
+
+func TestKubernetesSyncCombinedSecret(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset()
+	kube := &KubernetesTarget{Base: Base{Name: "test"}, Namespace: "ci", CombinedSecret: "credentials", client: client}
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.Username = "user"
+	userPass.Password = "pass"
+	secret := credentials.NewSecretText()
+	secret.ID = "slack"
+	secret.Secret = "xoxb"
+
+	assert.NoError(t, kube.Initialize(context.Background(), []credentials.Credentials{userPass, secret}))
+	assert.Empty(t, kube.GetExistingCredentials())
+	assert.NoError(t, kube.UpdateCredentials(context.Background(), userPass))
+	assert.NoError(t, kube.UpdateCredentials(context.Background(), secret))
+
+	assert.NoError(t, kube.Initialize(context.Background(), []credentials.Credentials{userPass}))
+	assert.Equal(t, []string{"SLACK", "my-cred"}, kube.GetExistingCredentials())
+	assert.NoError(t, kube.DeleteCredentials(context.Background(), "SLACK"))
+
+	combinedSecret, err := client.CoreV1().Secrets("ci").Get(context.Background(), "credentials", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, kubernetesManagedByValue, combinedSecret.Labels[kubernetesManagedByLabel])
+	assert.Equal(t, map[string][]byte{"MY_CRED_USERNAME": []byte("user"), "MY_CRED_PASSWORD": []byte("pass")}, combinedSecret.Data)
+
+	_, err = kube.GetCredentialsDescription(context.Background(), "my-cred")
+	assert.EqualError(t, err, "the target `test` does not support credentials descriptions")
+
+	// A combined secret that is not managed by credentials-sync is never modified
+	combinedSecret.Labels = nil
+	_, err = client.CoreV1().Secrets("ci").Update(context.Background(), combinedSecret, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.EqualError(t, kube.Initialize(context.Background(), nil), "the credentials secret exists and is not managed by credentials-sync")
+}
@@ -0,0 +1,87 @@
+package targets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/plugin"
+)
+
+// PluginTarget represents an external executable to which credentials are synced, through the plugin protocol
+// Credentials are sent to the plugin as documents, in the format of the sources
+type PluginTarget struct {
+	Base                 `mapstructure:",squash"`
+	plugin.Configuration `mapstructure:",squash"`
+
+	client              *plugin.Client
+	existingCredentials []string
+}
+
+// Initialize executes all necessary operations to prepare the plugin target for sync
+// The plugin is given the target IDs of the credentials to sync, and returns the IDs of the existing credentials
+func (target *PluginTarget) Initialize(ctx context.Context, allCredentials []credentials.Credentials) error {
+	if target.client == nil {
+		target.client = target.NewClient(plugin.KindTarget, target.Name)
+	}
+	params := &plugin.InitializeParams{Credentials: []string{}}
+	for _, creds := range allCredentials {
+		if creds.ShouldSync(target.Name, target.Tags) {
+			params.Credentials = append(params.Credentials, creds.GetTargetID())
+		}
+	}
+	result := &plugin.InitializeResult{}
+	if err := target.client.Call(ctx, plugin.MethodInitialize, params, result); err != nil {
+		return err
+	}
+	target.existingCredentials = result.ExistingCredentials
+	if target.existingCredentials == nil {
+		target.existingCredentials = []string{}
+	}
+	return nil
+}
+
+// ToString prints out a description of the plugin target
+func (target *PluginTarget) ToString() string {
+	return fmt.Sprintf("%s (Plugin) - %s", target.BaseToString(), target.Command)
+}
+
+// GetExistingCredentials returns a list of all credential IDs on the target
+func (target *PluginTarget) GetExistingCredentials() []string {
+	return target.existingCredentials
+}
+
+// DeleteCredentials deletes the credentials with the given ID through the plugin
+func (target *PluginTarget) DeleteCredentials(ctx context.Context, id string) error {
+	return target.client.Call(ctx, plugin.MethodDeleteCredentials, &plugin.DeleteCredentialsParams{ID: id}, nil)
+}
+
+// RenderCredentials returns the document that is sent to the plugin for the given credentials
+func (target *PluginTarget) RenderCredentials(cred credentials.Credentials) ([]byte, error) {
+	document, err := toCredentialsDocument(cred, target.markedDescription(cred))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(document)
+}
+
+// UpdateCredentials sends the document of the given credentials to the plugin
+func (target *PluginTarget) UpdateCredentials(ctx context.Context, cred credentials.Credentials) error {
+	document, err := toCredentialsDocument(cred, target.markedDescription(cred))
+	if err != nil {
+		return err
+	}
+	return target.client.Call(ctx, plugin.MethodUpdateCredentials, &plugin.UpdateCredentialsParams{Credentials: document}, nil)
+}
+
+// ValidateConfiguration verifies that the plugin target's configuration is valid
+func (target *PluginTarget) ValidateConfiguration() error {
+	if err := target.Configuration.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration on the plugin target `%s`: %v", target.Name, err)
+	}
+	if target.TagUnsynced || target.DeleteOnlyMarked {
+		return fmt.Errorf("the plugin target `%s` does not support `tag_unsynced` and `delete_only_marked`, since the protocol has no descriptions", target.Name)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package targets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+// The test binary is also used as the reference plugin, when this variable is set
+const testPluginEnv = "CREDENTIALS_SYNC_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) == "reference" {
+		plugin.Main(plugin.ReferenceHandler)
+	}
+	os.Exit(m.Run())
+}
+
+func TestPluginTargetValidateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		target        *PluginTarget
+		expectedError error
+	}{
+		{
+			name:          "Valid",
+			target:        &PluginTarget{Base: Base{Name: "test"}, Configuration: plugin.Configuration{Command: "my-plugin"}},
+			expectedError: nil,
+		},
+		{
+			name:          "Missing command",
+			target:        &PluginTarget{Base: Base{Name: "test"}},
+			expectedError: fmt.Errorf("invalid configuration on the plugin target `test`: `command` must be defined to run a plugin"),
+		},
+		{
+			name:          "Tag unsynced",
+			target:        &PluginTarget{Base: Base{Name: "test", TagUnsynced: true}, Configuration: plugin.Configuration{Command: "my-plugin"}},
+			expectedError: fmt.Errorf("the plugin target `test` does not support `tag_unsynced` and `delete_only_marked`, since the protocol has no descriptions"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.target.ValidateConfiguration())
+		})
+	}
+}
+
+func TestPluginTargetSync(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"other": {"type": "secret", "secret": "other"}}`), 0600))
+	target := &PluginTarget{
+		Base: Base{Name: "test"},
+		Configuration: plugin.Configuration{
+			Command: os.Args[0],
+			Env:     map[string]string{testPluginEnv: "reference"},
+			Options: map[string]interface{}{"path": path},
+		},
+	}
+	assert.Equal(t, fmt.Sprintf("test [Tags: ] (Plugin) - %s", os.Args[0]), target.ToString())
+
+	secret := credentials.NewSecretText()
+	secret.ID = "slack"
+	secret.TargetID = "slack-token"
+	secret.Secret = "xoxb"
+	assert.NoError(t, target.Initialize(context.Background(), []credentials.Credentials{secret}))
+	assert.Equal(t, []string{"other"}, target.GetExistingCredentials())
+
+	assert.NoError(t, target.UpdateCredentials(context.Background(), secret))
+	assert.NoError(t, target.DeleteCredentials(context.Background(), "other"))
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"slack-token": {"type": "secret", "description": "slack", "secret": "xoxb"}}`, string(content))
+
+	rendered, err := target.RenderCredentials(secret)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": "slack-token", "type": "secret", "description": "slack", "secret": "xoxb"}`, string(rendered))
+}
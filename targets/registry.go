@@ -0,0 +1,60 @@
+package targets
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	registryMutex sync.RWMutex
+	// Target type (as given in the `targets` section of the configuration) -> Constructor of the target
+	targetTypes = map[string]func() Target{}
+	// Target types, in the order in which they are registered (and synced)
+	targetTypeNames = []string{}
+)
+
+func init() {
+	Register("jenkins", func() Target { return &JenkinsTarget{} })
+	Register("github", func() Target { return &GithubTarget{} })
+	Register("gitlab", func() Target { return &GitlabTarget{} })
+	Register("kubernetes", func() Target { return &KubernetesTarget{} })
+	Register("aws_secretsmanager", func() Target { return &AWSSecretsManagerTarget{} })
+	Register("aws_ssm", func() Target { return &AWSSSMTarget{} })
+	Register("vault", func() Target { return &VaultTarget{} })
+	Register("file", func() Target { return &FileTarget{} })
+	Register("plugin", func() Target { return &PluginTarget{} })
+}
+
+// Register makes a type of target available in the `targets` section of the configuration, under the given name
+// The constructor must return a new target on each call, to which the configuration of the target is decoded
+// It panics if the name is already registered, so it is meant to be called from the `init` function of a package
+func Register(name string, constructor func() Target) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if name == "" || constructor == nil {
+		panic("targets: Register requires a name and a constructor")
+	}
+	if _, ok := targetTypes[name]; ok {
+		panic(fmt.Sprintf("targets: the target type %s is already registered", name))
+	}
+	targetTypes[name] = constructor
+	targetTypeNames = append(targetTypeNames, name)
+}
+
+// Registered returns the names of all registered types of targets, in the order in which they were registered
+func Registered() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return append([]string{}, targetTypeNames...)
+}
+
+// New returns a new target of the given registered type
+func New(name string) (Target, error) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	constructor, ok := targetTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown target type: %s", name)
+	}
+	return constructor(), nil
+}
@@ -0,0 +1,24 @@
+package targets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"jenkins", "github", "gitlab", "kubernetes", "aws_secretsmanager", "aws_ssm", "vault", "file", "plugin"}, Registered()[:9])
+	assert.Panics(t, func() { Register("jenkins", func() Target { return &JenkinsTarget{} }) })
+	assert.Panics(t, func() { Register("nil", nil) })
+
+	Register("registry_test", func() Target { return &FileTarget{Format: fileFormatJSON} })
+	assert.Contains(t, Registered(), "registry_test")
+	target, err := New("registry_test")
+	assert.NoError(t, err)
+	assert.Equal(t, &FileTarget{Format: fileFormatJSON}, target)
+
+	_, err = New("unregistered")
+	assert.EqualError(t, err, "unknown target type: unregistered")
+}
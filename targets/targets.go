@@ -1,39 +1,108 @@
 package targets
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/retry"
 	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
 )
 
 // Target represents an endpoint where credentials can be synced
 type Target interface {
 	// Base
 	BaseValidateConfiguration() error
+	GetDeletionLimits() DeletionLimits
 	GetName() string
+	GetOwnershipMarker() string
+	GetProtectedCredentials() []string
+	GetRetryPolicy() retry.Policy
 	GetTags() map[string]string
+	GetTimeout() time.Duration
+	SetDefaultRetryPolicy(defaults retry.Policy)
+	ShouldDeleteOnlyMarked() bool
 	ShouldDeleteUnsynced() bool
-	ShouldTagUnsynced() bool // Not implemented
+	ShouldTagUnsynced() bool
+
+	// Base, to override if the target supports credentials descriptions
+	GetCredentialsDescription(ctx context.Context, id string) (string, error)
+	SetCredentialsDescription(ctx context.Context, id string, description string) error
 
 	// To implement
 	GetExistingCredentials() []string
-	Initialize([]credentials.Credentials) error
+	Initialize(context.Context, []credentials.Credentials) error
 	ToString() string
-	DeleteCredentials(id string) error
-	UpdateCredentials(credentials.Credentials) error
+	DeleteCredentials(ctx context.Context, id string) error
+	UpdateCredentials(context.Context, credentials.Credentials) error
 	ValidateConfiguration() error
 }
 
+// DeletionLimits defines the maximum number of credentials that can be deleted from a target in a single sync
+// The limits are not enforced when they are not set
+type DeletionLimits struct {
+	MaxDeletions           *int     `mapstructure:"max_deletions"`
+	MaxDeletionsPercentage *float64 `mapstructure:"max_deletions_percentage"`
+}
+
+// Check returns an error if deleting the given number of credentials out of the given number of existing credentials
+// exceeds the limits
+func (limits DeletionLimits) Check(deletions int, existing int) error {
+	if limits.MaxDeletions != nil && deletions > *limits.MaxDeletions {
+		return fmt.Errorf("%d credentials would be deleted, which exceeds the maximum of %d", deletions, *limits.MaxDeletions)
+	}
+	if limits.MaxDeletionsPercentage != nil && existing > 0 {
+		percentage := float64(deletions) / float64(existing) * 100
+		if percentage > *limits.MaxDeletionsPercentage {
+			return fmt.Errorf("%d out of %d credentials (%.1f%%) would be deleted, which exceeds the maximum of %.1f%%", deletions, existing, percentage, *limits.MaxDeletionsPercentage)
+		}
+	}
+	return nil
+}
+
+// Validate verifies that the limits have valid values
+func (limits DeletionLimits) Validate() error {
+	if limits.MaxDeletions != nil && *limits.MaxDeletions < 0 {
+		return fmt.Errorf("`max_deletions` cannot be negative")
+	}
+	if limits.MaxDeletionsPercentage != nil && (*limits.MaxDeletionsPercentage < 0 || *limits.MaxDeletionsPercentage > 100) {
+		return fmt.Errorf("`max_deletions_percentage` must be between 0 and 100")
+	}
+	return nil
+}
+
 // Base contains attributes which are common to all targets
 type Base struct {
-	DeleteUnsynced bool              `mapstructure:"delete_unsynced"`
-	TagUnsynced    bool              `mapstructure:"tag_unsynced"`
-	Name           string            `mapstructure:"name"`
-	Tags           map[string]string `mapstructure:"tags"`
+	DeletionLimits       `mapstructure:",squash"`
+	DeleteOnlyMarked     bool              `mapstructure:"delete_only_marked"`
+	DeleteUnsynced       bool              `mapstructure:"delete_unsynced"`
+	OwnershipMarker      string            `mapstructure:"ownership_marker"`
+	ProtectedCredentials []string          `mapstructure:"protected_credentials"`
+	Retry                retry.Policy      `mapstructure:"retry"`
+	TagUnsynced          bool              `mapstructure:"tag_unsynced"`
+	Name                 string            `mapstructure:"name"`
+	Tags                 map[string]string `mapstructure:"tags"`
+	Timeout              time.Duration     `mapstructure:"timeout"`
 }
 
+var invalidEnvironmentVariableNameCharacters = regexp.MustCompile("[^A-Z0-9_]")
+
+// DefaultOwnershipMarker is the marker added to the description of synced credentials when `delete_only_marked` is set
+// but no `ownership_marker` is configured
+const DefaultOwnershipMarker = "[managed by credentials-sync]"
+
 // BaseToString prints out the target fields common to all types of targets
 func (targetBase *Base) BaseToString() string {
 	tagString := ""
@@ -51,19 +120,93 @@ func (targetBase *Base) BaseValidateConfiguration() error {
 	if targetBase.DeleteUnsynced && targetBase.TagUnsynced {
 		return fmt.Errorf("Cannot set both `tag_unsynced` and `delete_unsynced` on %v", targetBase.Name)
 	}
+	if targetBase.DeleteOnlyMarked && !targetBase.DeleteUnsynced {
+		return fmt.Errorf("`delete_only_marked` requires `delete_unsynced` to be set on %v", targetBase.Name)
+	}
+	if targetBase.Timeout < 0 {
+		return fmt.Errorf("`timeout` cannot be negative on %v", targetBase.Name)
+	}
+	if err := targetBase.DeletionLimits.Validate(); err != nil {
+		return fmt.Errorf("Invalid deletion limits on %v: %v", targetBase.Name, err)
+	}
+	if err := targetBase.Retry.Validate(); err != nil {
+		return fmt.Errorf("Invalid retry policy on %v: %v", targetBase.Name, err)
+	}
+	for _, pattern := range targetBase.ProtectedCredentials {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid protected credentials pattern `%s` on %v: %v", pattern, targetBase.Name, err)
+		}
+	}
 	return nil
 }
 
+// GetDeletionLimits returns the maximum number of credentials that can be deleted from the target in a single sync
+func (targetBase *Base) GetDeletionLimits() DeletionLimits {
+	return targetBase.DeletionLimits
+}
+
 // GetName returns the target's name
 func (targetBase *Base) GetName() string {
 	return targetBase.Name
 }
 
+// GetOwnershipMarker returns the marker that is added to the description of the credentials synced to the target
+// An empty string means that credentials are not marked
+func (targetBase *Base) GetOwnershipMarker() string {
+	if targetBase.OwnershipMarker == "" && targetBase.DeleteOnlyMarked {
+		return DefaultOwnershipMarker
+	}
+	return targetBase.OwnershipMarker
+}
+
+// MarkDescription appends the ownership marker to the given description, if the target defines one
+func (targetBase *Base) MarkDescription(description string) string {
+	marker := targetBase.GetOwnershipMarker()
+	if marker == "" || strings.HasSuffix(description, marker) {
+		return description
+	}
+	return strings.TrimSpace(description + " " + marker)
+}
+
+// markedDescription returns the description of the given credentials (or their ID), with the target's ownership marker
+func (targetBase *Base) markedDescription(creds credentials.Credentials) string {
+	description := creds.GetID()
+	if describer, ok := creds.(interface{ GetDescriptionOrID() string }); ok {
+		description = describer.GetDescriptionOrID()
+	}
+	return targetBase.MarkDescription(description)
+}
+
+// GetProtectedCredentials returns the list of IDs (or glob patterns) of the credentials that must never be deleted from the target
+func (targetBase *Base) GetProtectedCredentials() []string {
+	return targetBase.ProtectedCredentials
+}
+
+// GetRetryPolicy returns the policy used to retry the requests that fail with a transient error
+func (targetBase *Base) GetRetryPolicy() retry.Policy {
+	return targetBase.Retry
+}
+
+// SetDefaultRetryPolicy sets the attributes of the retry policy that are not configured on the target
+func (targetBase *Base) SetDefaultRetryPolicy(defaults retry.Policy) {
+	targetBase.Retry = targetBase.Retry.WithDefaults(defaults)
+}
+
 // GetTags returns the target's tags
 func (targetBase *Base) GetTags() map[string]string {
 	return targetBase.Tags
 }
 
+// GetTimeout returns the maximum duration of the initialization and of the sync of the target. Zero means no timeout
+func (targetBase *Base) GetTimeout() time.Duration {
+	return targetBase.Timeout
+}
+
+// ShouldDeleteOnlyMarked returns true if only the unsynced credentials that have the ownership marker should be deleted
+func (targetBase *Base) ShouldDeleteOnlyMarked() bool {
+	return targetBase.DeleteOnlyMarked
+}
+
 // ShouldDeleteUnsynced returns true if the unsynced credentials should be deleted from the target
 func (targetBase *Base) ShouldDeleteUnsynced() bool {
 	return targetBase.DeleteUnsynced
@@ -74,16 +217,365 @@ func (targetBase *Base) ShouldTagUnsynced() bool {
 	return targetBase.TagUnsynced
 }
 
+// GetCredentialsDescription returns the description of the credentials with the given ID on the target
+// Targets that support descriptions must override this method
+func (targetBase *Base) GetCredentialsDescription(ctx context.Context, id string) (string, error) {
+	return "", fmt.Errorf("the target `%s` does not support credentials descriptions", targetBase.Name)
+}
+
+// SetCredentialsDescription modifies the description of the credentials with the given ID on the target
+// Targets that support descriptions must override this method
+func (targetBase *Base) SetCredentialsDescription(ctx context.Context, id string, description string) error {
+	return fmt.Errorf("the target `%s` does not support credentials descriptions", targetBase.Name)
+}
+
+const (
+	unsyncedTagPrefix = "[unsynced since "
+	unsyncedTagSuffix = "] "
+)
+
+// TagUnsynced returns the given description prefixed with a tag containing the time at which the credentials were first
+// seen as unsynced. If the description is already tagged, it is returned as is, to keep the original time
+func TagUnsynced(description string, now time.Time) string {
+	if _, isTagged := GetUnsyncedTime(description); isTagged {
+		return description
+	}
+	return unsyncedTagPrefix + now.UTC().Format(time.RFC3339) + unsyncedTagSuffix + description
+}
+
+// GetUnsyncedTime returns the time contained in the unsynced tag of the given description
+// The second return value is false if the description is not tagged
+func GetUnsyncedTime(description string) (time.Time, bool) {
+	if !strings.HasPrefix(description, unsyncedTagPrefix) {
+		return time.Time{}, false
+	}
+	end := strings.Index(description, unsyncedTagSuffix)
+	if end < 0 {
+		return time.Time{}, false
+	}
+	unsyncedTime, err := time.Parse(time.RFC3339, description[len(unsyncedTagPrefix):end])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return unsyncedTime, true
+}
+
+// IsMarked returns true if the given description ends with the target's ownership marker
+func IsMarked(target Target, description string) bool {
+	marker := target.GetOwnershipMarker()
+	return marker != "" && strings.HasSuffix(description, marker)
+}
+
+// GetProtectingPattern returns the protected credentials pattern that matches the given ID
+// The second return value is false if the credentials are not protected
+func GetProtectingPattern(target Target, id string) (string, bool) {
+	for _, pattern := range target.GetProtectedCredentials() {
+		if matched, _ := path.Match(pattern, id); matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
 // HasCredential returns true if the given ID is found on the target
+// On targets that group several entries by credentials, credentials that are not in the sources are found from the
+// names of their entries
 func HasCredential(target Target, id string) bool {
 	for _, existingID := range target.GetExistingCredentials() {
 		if existingID == id {
 			return true
 		}
 	}
+	if grouper, ok := target.(entriesGrouper); ok {
+		return len(grouper.entriesOf(id)) > 0
+	}
 	return false
 }
 
+// entriesGrouper is implemented by the targets that sync each credentials to several entries (secrets, variables...)
+type entriesGrouper interface {
+	// entriesOf returns the existing entries of the credentials with the given target ID
+	entriesOf(id string) []string
+}
+
+// groupedEntriesOf returns the existing entries of the credentials with the given target ID, given the entries of the
+// credentials in the sources (see groupExistingCredentials). Since the entries of credentials that are not in the sources
+// are reported by name, they are found from the ID itself, and from its environment variable name with the suffix of
+// any credentials type. Entries that belong to credentials in the sources are never returned for another ID
+func groupedEntriesOf(id string, credentialsEntries map[string][]string, entryExists func(string) bool) []string {
+	if entries, ok := credentialsEntries[id]; ok {
+		return entries
+	}
+	claimedEntries := map[string]bool{}
+	for _, entries := range credentialsEntries {
+		for _, entry := range entries {
+			claimedEntries[entry] = true
+		}
+	}
+	candidates := []string{id}
+	for _, suffix := range environmentVariableSuffixes {
+		candidates = append(candidates, environmentVariableName(id)+suffix)
+	}
+	entries := []string{}
+	for _, candidate := range candidates {
+		if entryExists(candidate) && !claimedEntries[candidate] && !slices.Contains(entries, candidate) {
+			entries = append(entries, candidate)
+		}
+	}
+	return entries
+}
+
+// syncedCredentials returns the credentials that are synced to the given target
+// Targets that group their entries by credentials only consider these, since other credentials may share their target ID
+func syncedCredentials(target Target, allCredentials []credentials.Credentials) []credentials.Credentials {
+	synced := []credentials.Credentials{}
+	for _, creds := range allCredentials {
+		if creds.ShouldSync(target.GetName(), target.GetTags()) {
+			synced = append(synced, creds)
+		}
+	}
+	return synced
+}
+
+// groupExistingCredentials maps the entries (secrets, variables...) existing on a target to the credentials they are synced from
+// Each credentials is synced to the entries returned by entriesOf. Entries that do not match any credentials are reported by name
+// The second return value maps the target ID of all credentials to the names of their entries
+func groupExistingCredentials(allCredentials []credentials.Credentials, existingEntries map[string]bool, entriesOf func(credentials.Credentials) []string) ([]string, map[string][]string) {
+	credentialsEntries := map[string][]string{}
+	for _, creds := range allCredentials {
+		if entries := entriesOf(creds); entries != nil {
+			credentialsEntries[creds.GetTargetID()] = entries
+		}
+	}
+
+	existingCredentials := []string{}
+	claimedEntries := map[string]bool{}
+	for id, entries := range credentialsEntries {
+		isExisting := false
+		for _, entry := range entries {
+			if existingEntries[entry] {
+				claimedEntries[entry] = true
+				isExisting = true
+			}
+		}
+		if isExisting {
+			existingCredentials = append(existingCredentials, id)
+		}
+	}
+	for entry := range existingEntries {
+		if !claimedEntries[entry] {
+			existingCredentials = append(existingCredentials, entry)
+		}
+	}
+	sort.Strings(existingCredentials)
+	return existingCredentials, credentialsEntries
+}
+
+// environmentVariableName converts a credentials ID to a valid environment variable name
+func environmentVariableName(id string) string {
+	return invalidEnvironmentVariableNameCharacters.ReplaceAllString(strings.ToUpper(id), "_")
+}
+
+// environmentVariableSuffixes are the suffixes of the environment variables of all credentials types (see toEnvironmentVariables)
+var environmentVariableSuffixes = []string{
+	"",
+	"_ACCESS_KEY_ID", "_SECRET_ACCESS_KEY", "_ROLE_ARN", "_MFA_SERIAL",
+	"_USERNAME", "_PASSWORD", "_PASSPHRASE", "_PRIVATE_KEY",
+	"_APP_ID", "_OWNER", "_KEYSTORE",
+}
+
+// toEnvironmentVariables returns the environment variables (name -> value) that the given credentials are synced to, on
+// targets that store each field separately (GitHub secrets, combined Kubernetes secrets)
+// Optional fields map to an empty value when they are not set. An error is returned if the type of the credentials is
+// not supported or if the credentials cannot be converted
+func toEnvironmentVariables(creds credentials.Credentials) (map[string]string, error) {
+	name := environmentVariableName(creds.GetTargetID())
+	switch castCreds := creds.(type) {
+	case *credentials.AmazonWebServicesCredentials:
+		return map[string]string{
+			name + "_ACCESS_KEY_ID":     castCreds.AccessKey,
+			name + "_SECRET_ACCESS_KEY": castCreds.SecretKey,
+			name + "_ROLE_ARN":          castCreds.RoleARN,
+			name + "_MFA_SERIAL":        castCreds.MFASerialNumber,
+		}, nil
+	case *credentials.SecretTextCredentials:
+		return map[string]string{
+			name: castCreds.Secret,
+		}, nil
+	case *credentials.UsernamePasswordCredentials:
+		return map[string]string{
+			name + "_USERNAME": castCreds.Username,
+			name + "_PASSWORD": castCreds.Password,
+		}, nil
+	case *credentials.SSHCredentials:
+		return map[string]string{
+			name + "_USERNAME":    castCreds.Username,
+			name + "_PASSPHRASE":  castCreds.Passphrase,
+			name + "_PRIVATE_KEY": castCreds.PrivateKey,
+		}, nil
+	case *credentials.GithubAppCredentials:
+		return map[string]string{
+			name + "_APP_ID":      strconv.Itoa(castCreds.AppID),
+			name + "_PRIVATE_KEY": castCreds.PrivateKey,
+			name + "_OWNER":       castCreds.Owner,
+		}, nil
+	case *credentials.SecretFileCredentials:
+		content, err := castCreds.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode the content of the secret file: %v", err)
+		}
+		return map[string]string{
+			name: string(content),
+		}, nil
+	case *credentials.CertificateCredentials:
+		keystore, err := castCreds.GetPKCS12()
+		if err != nil {
+			return nil, fmt.Errorf("unable to create the keystore of the certificate: %v", err)
+		}
+		return map[string]string{
+			name + "_KEYSTORE": base64.StdEncoding.EncodeToString(keystore),
+			name + "_PASSWORD": castCreds.Password,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported credentials type %T", creds)
+}
+
+// decodeTargetOptions decodes the options that the given credentials define for the given type of target (in their
+// `target_options`) into result. The result is left as is if the credentials do not define any
+func decodeTargetOptions(creds credentials.Credentials, targetType string, result interface{}) error {
+	optionsGetter, ok := creds.(interface {
+		GetTargetOptions(targetType string) map[string]interface{}
+	})
+	if !ok {
+		return nil
+	}
+	options := optionsGetter.GetTargetOptions(targetType)
+	if options == nil {
+		return nil
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{ErrorUnused: true, Result: result})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(options); err != nil {
+		return fmt.Errorf("the credentials with ID %s have invalid %s options: %v", creds.GetID(), targetType, err)
+	}
+	return nil
+}
+
+// fileEnvironmentVariables returns the names of the environment variables of the given credentials (see
+// toEnvironmentVariables) that contain the content of a file, such as a private key
+func fileEnvironmentVariables(creds credentials.Credentials) map[string]bool {
+	name := environmentVariableName(creds.GetTargetID())
+	switch creds.(type) {
+	case *credentials.SSHCredentials, *credentials.GithubAppCredentials:
+		return map[string]bool{name + "_PRIVATE_KEY": true}
+	case *credentials.SecretFileCredentials:
+		return map[string]bool{name: true}
+	}
+	return map[string]bool{}
+}
+
+// toCredentialsDocument returns the given credentials as a single credentials document, in the format of the sources
+// Optional fields are omitted when they are not set
+func toCredentialsDocument(creds credentials.Credentials, description string) (map[string]interface{}, error) {
+	var document map[string]interface{}
+	switch castCreds := creds.(type) {
+	case *credentials.AmazonWebServicesCredentials:
+		document = map[string]interface{}{
+			"type":       "aws",
+			"access_key": castCreds.AccessKey,
+			"secret_key": castCreds.SecretKey,
+			"role_arn":   castCreds.RoleARN,
+			"mfa_serial": castCreds.MFASerialNumber,
+		}
+	case *credentials.SecretTextCredentials:
+		document = map[string]interface{}{
+			"type":   "secret",
+			"secret": castCreds.Secret,
+		}
+	case *credentials.UsernamePasswordCredentials:
+		document = map[string]interface{}{
+			"type":     "usernamepassword",
+			"username": castCreds.Username,
+			"password": castCreds.Password,
+		}
+	case *credentials.SSHCredentials:
+		document = map[string]interface{}{
+			"type":        "ssh",
+			"username":    castCreds.Username,
+			"passphrase":  castCreds.Passphrase,
+			"private_key": castCreds.PrivateKey,
+		}
+	case *credentials.GithubAppCredentials:
+		document = map[string]interface{}{
+			"type":        "github_app",
+			"app_id":      castCreds.AppID,
+			"private_key": castCreds.PrivateKey,
+			"owner":       castCreds.Owner,
+			"api_uri":     castCreds.APIURI,
+		}
+	case *credentials.SecretFileCredentials:
+		document = map[string]interface{}{
+			"type":           "secret_file",
+			"filename":       castCreds.Filename,
+			"content":        castCreds.Content,
+			"content_base64": castCreds.ContentBase64,
+		}
+	case *credentials.CertificateCredentials:
+		document = map[string]interface{}{
+			"type":        "certificate",
+			"pkcs12":      castCreds.PKCS12,
+			"certificate": castCreds.Certificate,
+			"private_key": castCreds.PrivateKey,
+			"password":    castCreds.Password,
+		}
+	default:
+		return nil, fmt.Errorf("unable to serialize the credentials with ID %s", creds.GetID())
+	}
+
+	document["id"] = creds.GetTargetID()
+	document["description"] = description
+	for key, value := range document {
+		if value == "" {
+			delete(document, key)
+		}
+	}
+	return document, nil
+}
+
+// PayloadRenderer can be implemented by targets to define the exact payload that is sent when syncing credentials
+// This payload is used to compute the fingerprint of the credentials on the target
+type PayloadRenderer interface {
+	RenderCredentials(credentials.Credentials) ([]byte, error)
+}
+
+// ChangesBuffer can be implemented by targets that buffer the changes made to their credentials (UpdateCredentials,
+// DeleteCredentials and SetCredentialsDescription) instead of applying them one by one
+// FlushChanges applies the buffered changes. It is called once the changes of a sync are applied, even if some failed
+type ChangesBuffer interface {
+	FlushChanges(ctx context.Context) error
+}
+
+// Fingerprint returns a hash of the payload that would be sent to the target when syncing the given credentials
+// Targets that do not implement PayloadRenderer are fingerprinted using the JSON representation of the credentials
+func Fingerprint(target Target, creds credentials.Credentials) (string, error) {
+	var (
+		err     error
+		payload []byte
+	)
+	if renderer, ok := target.(PayloadRenderer); ok {
+		payload, err = renderer.RenderCredentials(creds)
+	} else {
+		payload, err = json.Marshal(creds)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to render credentials with ID %s: %v", creds.GetID(), err)
+	}
+	hash := sha256.Sum256(payload)
+	return hex.EncodeToString(hash[:]), nil
+}
+
 // TargetCollection represents a collection of targets to which credentials can be synced
 type TargetCollection interface {
 	AllTargets() []Target
@@ -92,16 +584,22 @@ type TargetCollection interface {
 
 // Configuration contains all configured targets
 type Configuration struct {
-	JenkinsTargets []*JenkinsTarget `mapstructure:"jenkins"`
+	targets []Target
+}
+
+// NewConfiguration creates a configuration with the given targets
+func NewConfiguration(targets ...Target) *Configuration {
+	return &Configuration{targets: targets}
+}
+
+// AddTargets adds targets to the configuration
+func (config *Configuration) AddTargets(targets ...Target) {
+	config.targets = append(config.targets, targets...)
 }
 
 // AllTargets returns all configured targets
 func (config *Configuration) AllTargets() []Target {
-	targets := []Target{}
-	for _, target := range config.JenkinsTargets {
-		targets = append(targets, target)
-	}
-	return targets
+	return config.targets
 }
 
 // ValidateConfiguration verifies that all targets are correctly configured
@@ -5,9 +5,12 @@
 package targets
 
 import (
+	context "context"
 	credentials "github.com/coveooss/credentials-sync/credentials"
+	retry "github.com/coveooss/credentials-sync/retry"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	time "time"
 )
 
 // MockTarget is a mock of Target interface
@@ -47,6 +50,20 @@ func (mr *MockTargetMockRecorder) BaseValidateConfiguration() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BaseValidateConfiguration", reflect.TypeOf((*MockTarget)(nil).BaseValidateConfiguration))
 }
 
+// GetDeletionLimits mocks base method
+func (m *MockTarget) GetDeletionLimits() DeletionLimits {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletionLimits")
+	ret0, _ := ret[0].(DeletionLimits)
+	return ret0
+}
+
+// GetDeletionLimits indicates an expected call of GetDeletionLimits
+func (mr *MockTargetMockRecorder) GetDeletionLimits() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletionLimits", reflect.TypeOf((*MockTarget)(nil).GetDeletionLimits))
+}
+
 // GetName mocks base method
 func (m *MockTarget) GetName() string {
 	m.ctrl.T.Helper()
@@ -61,6 +78,60 @@ func (mr *MockTargetMockRecorder) GetName() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetName", reflect.TypeOf((*MockTarget)(nil).GetName))
 }
 
+// GetOwnershipMarker mocks base method
+func (m *MockTarget) GetOwnershipMarker() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOwnershipMarker")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetOwnershipMarker indicates an expected call of GetOwnershipMarker
+func (mr *MockTargetMockRecorder) GetOwnershipMarker() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwnershipMarker", reflect.TypeOf((*MockTarget)(nil).GetOwnershipMarker))
+}
+
+// GetProtectedCredentials mocks base method
+func (m *MockTarget) GetProtectedCredentials() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProtectedCredentials")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetProtectedCredentials indicates an expected call of GetProtectedCredentials
+func (mr *MockTargetMockRecorder) GetProtectedCredentials() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProtectedCredentials", reflect.TypeOf((*MockTarget)(nil).GetProtectedCredentials))
+}
+
+// GetRetryPolicy mocks base method
+func (m *MockTarget) GetRetryPolicy() retry.Policy {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRetryPolicy")
+	ret0, _ := ret[0].(retry.Policy)
+	return ret0
+}
+
+// GetRetryPolicy indicates an expected call of GetRetryPolicy
+func (mr *MockTargetMockRecorder) GetRetryPolicy() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRetryPolicy", reflect.TypeOf((*MockTarget)(nil).GetRetryPolicy))
+}
+
+// SetDefaultRetryPolicy mocks base method
+func (m *MockTarget) SetDefaultRetryPolicy(defaults retry.Policy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetDefaultRetryPolicy", defaults)
+}
+
+// SetDefaultRetryPolicy indicates an expected call of SetDefaultRetryPolicy
+func (mr *MockTargetMockRecorder) SetDefaultRetryPolicy(defaults interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDefaultRetryPolicy", reflect.TypeOf((*MockTarget)(nil).SetDefaultRetryPolicy), defaults)
+}
+
 // GetTags mocks base method
 func (m *MockTarget) GetTags() map[string]string {
 	m.ctrl.T.Helper()
@@ -75,6 +146,34 @@ func (mr *MockTargetMockRecorder) GetTags() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTags", reflect.TypeOf((*MockTarget)(nil).GetTags))
 }
 
+// GetTimeout mocks base method
+func (m *MockTarget) GetTimeout() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTimeout")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// GetTimeout indicates an expected call of GetTimeout
+func (mr *MockTargetMockRecorder) GetTimeout() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTimeout", reflect.TypeOf((*MockTarget)(nil).GetTimeout))
+}
+
+// ShouldDeleteOnlyMarked mocks base method
+func (m *MockTarget) ShouldDeleteOnlyMarked() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShouldDeleteOnlyMarked")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// ShouldDeleteOnlyMarked indicates an expected call of ShouldDeleteOnlyMarked
+func (mr *MockTargetMockRecorder) ShouldDeleteOnlyMarked() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShouldDeleteOnlyMarked", reflect.TypeOf((*MockTarget)(nil).ShouldDeleteOnlyMarked))
+}
+
 // ShouldDeleteUnsynced mocks base method
 func (m *MockTarget) ShouldDeleteUnsynced() bool {
 	m.ctrl.T.Helper()
@@ -103,6 +202,35 @@ func (mr *MockTargetMockRecorder) ShouldTagUnsynced() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShouldTagUnsynced", reflect.TypeOf((*MockTarget)(nil).ShouldTagUnsynced))
 }
 
+// GetCredentialsDescription mocks base method
+func (m *MockTarget) GetCredentialsDescription(ctx context.Context, id string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCredentialsDescription", ctx, id)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCredentialsDescription indicates an expected call of GetCredentialsDescription
+func (mr *MockTargetMockRecorder) GetCredentialsDescription(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCredentialsDescription", reflect.TypeOf((*MockTarget)(nil).GetCredentialsDescription), ctx, id)
+}
+
+// SetCredentialsDescription mocks base method
+func (m *MockTarget) SetCredentialsDescription(ctx context.Context, id, description string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCredentialsDescription", ctx, id, description)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCredentialsDescription indicates an expected call of SetCredentialsDescription
+func (mr *MockTargetMockRecorder) SetCredentialsDescription(ctx, id, description interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCredentialsDescription", reflect.TypeOf((*MockTarget)(nil).SetCredentialsDescription), ctx, id, description)
+}
+
 // GetExistingCredentials mocks base method
 func (m *MockTarget) GetExistingCredentials() []string {
 	m.ctrl.T.Helper()
@@ -118,17 +246,17 @@ func (mr *MockTargetMockRecorder) GetExistingCredentials() *gomock.Call {
 }
 
 // Initialize mocks base method
-func (m *MockTarget) Initialize(arg0 []credentials.Credentials) error {
+func (m *MockTarget) Initialize(arg0 context.Context, arg1 []credentials.Credentials) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Initialize", arg0)
+	ret := m.ctrl.Call(m, "Initialize", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Initialize indicates an expected call of Initialize
-func (mr *MockTargetMockRecorder) Initialize(arg0 interface{}) *gomock.Call {
+func (mr *MockTargetMockRecorder) Initialize(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Initialize", reflect.TypeOf((*MockTarget)(nil).Initialize), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Initialize", reflect.TypeOf((*MockTarget)(nil).Initialize), arg0, arg1)
 }
 
 // ToString mocks base method
@@ -146,31 +274,31 @@ func (mr *MockTargetMockRecorder) ToString() *gomock.Call {
 }
 
 // DeleteCredentials mocks base method
-func (m *MockTarget) DeleteCredentials(id string) error {
+func (m *MockTarget) DeleteCredentials(ctx context.Context, id string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteCredentials", id)
+	ret := m.ctrl.Call(m, "DeleteCredentials", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteCredentials indicates an expected call of DeleteCredentials
-func (mr *MockTargetMockRecorder) DeleteCredentials(id interface{}) *gomock.Call {
+func (mr *MockTargetMockRecorder) DeleteCredentials(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCredentials", reflect.TypeOf((*MockTarget)(nil).DeleteCredentials), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCredentials", reflect.TypeOf((*MockTarget)(nil).DeleteCredentials), ctx, id)
 }
 
 // UpdateCredentials mocks base method
-func (m *MockTarget) UpdateCredentials(arg0 credentials.Credentials) error {
+func (m *MockTarget) UpdateCredentials(arg0 context.Context, arg1 credentials.Credentials) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateCredentials", arg0)
+	ret := m.ctrl.Call(m, "UpdateCredentials", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateCredentials indicates an expected call of UpdateCredentials
-func (mr *MockTargetMockRecorder) UpdateCredentials(arg0 interface{}) *gomock.Call {
+func (mr *MockTargetMockRecorder) UpdateCredentials(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCredentials", reflect.TypeOf((*MockTarget)(nil).UpdateCredentials), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCredentials", reflect.TypeOf((*MockTarget)(nil).UpdateCredentials), arg0, arg1)
 }
 
 // ValidateConfiguration mocks base method
@@ -1,7 +1,23 @@
 package targets
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"sort"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/retry"
+	"github.com/golang/mock/gomock"
+	"github.com/mitchellh/mapstructure"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -62,7 +78,10 @@ func TestConfigValidateConfiguration(t *testing.T) {
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			config := &Configuration{JenkinsTargets: tt.targets}
+			config := NewConfiguration()
+			for _, target := range tt.targets {
+				config.AddTargets(target)
+			}
 			assert.Equal(t, tt.expectError, config.ValidateConfiguration() != nil)
 			for i, gottenItem := range config.AllTargets() {
 				assert.Equal(t, tt.targets[i], gottenItem)
@@ -70,3 +89,323 @@ func TestConfigValidateConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	secret := credentials.NewSecretText()
+	secret.ID = "test-id"
+	secret.Secret = "a-secret"
+
+	jenkins := &JenkinsTarget{Base: Base{Name: "test"}}
+	fingerprint, err := Fingerprint(jenkins, secret)
+	assert.NoError(t, err)
+	assert.Len(t, fingerprint, 64)
+
+	sameFingerprint, _ := Fingerprint(jenkins, secret)
+	assert.Equal(t, fingerprint, sameFingerprint)
+
+	secret.Secret = "another-secret"
+	newFingerprint, _ := Fingerprint(jenkins, secret)
+	assert.NotEqual(t, fingerprint, newFingerprint)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	unknownCredentials := credentials.NewMockCredentials(ctrl)
+	unknownCredentials.EXPECT().GetID().Return("unknown").AnyTimes()
+	_, err = Fingerprint(jenkins, unknownCredentials)
+	assert.Error(t, err)
+}
+
+func TestUnsyncedTags(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, 5, 17, 10, 30, 0, 0, time.UTC)
+	tagged := TagUnsynced("a description", now)
+	assert.Equal(t, "[unsynced since 2020-05-17T10:30:00Z] a description", tagged)
+
+	// The original time is kept when tagging again
+	assert.Equal(t, tagged, TagUnsynced(tagged, now.Add(time.Hour)))
+
+	unsyncedTime, isTagged := GetUnsyncedTime(tagged)
+	assert.True(t, isTagged)
+	assert.Equal(t, now, unsyncedTime)
+
+	_, isTagged = GetUnsyncedTime("[unsynced since yesterday] a description")
+	assert.False(t, isTagged)
+}
+
+func TestBaseDoesNotSupportDescriptions(t *testing.T) {
+	t.Parallel()
+
+	base := &Base{Name: "test"}
+	_, err := base.GetCredentialsDescription(context.Background(), "id")
+	assert.EqualError(t, err, "the target `test` does not support credentials descriptions")
+	assert.EqualError(t, base.SetCredentialsDescription(context.Background(), "id", "description"), "the target `test` does not support credentials descriptions")
+}
+
+func TestOwnershipMarker(t *testing.T) {
+	t.Parallel()
+
+	base := &Base{Name: "test"}
+	assert.Equal(t, "", base.GetOwnershipMarker())
+	assert.Equal(t, "a description", base.MarkDescription("a description"))
+
+	base.DeleteOnlyMarked = true
+	assert.Equal(t, DefaultOwnershipMarker, base.GetOwnershipMarker())
+
+	base.OwnershipMarker = "(managed)"
+	assert.Equal(t, "a description (managed)", base.MarkDescription("a description"))
+	assert.Equal(t, "a description (managed)", base.MarkDescription("a description (managed)"))
+
+	jenkins := &JenkinsTarget{Base: *base}
+	assert.True(t, IsMarked(jenkins, "a description (managed)"))
+	assert.True(t, IsMarked(jenkins, TagUnsynced("a description (managed)", time.Now())))
+	assert.False(t, IsMarked(jenkins, "a description"))
+}
+
+func TestDeleteOnlyMarkedRequiresDeleteUnsynced(t *testing.T) {
+	t.Parallel()
+
+	base := &Base{Name: "test", DeleteOnlyMarked: true}
+	assert.EqualError(t, base.BaseValidateConfiguration(), "`delete_only_marked` requires `delete_unsynced` to be set on test")
+
+	base.DeleteUnsynced = true
+	assert.NoError(t, base.BaseValidateConfiguration())
+}
+
+func TestDeletionLimits(t *testing.T) {
+	t.Parallel()
+
+	maxDeletions, maxPercentage, negative := 2, 50.0, -1
+	cases := []struct {
+		name          string
+		limits        DeletionLimits
+		deletions     int
+		existing      int
+		expectedError string
+	}{
+		{name: "No limits", limits: DeletionLimits{}, deletions: 100, existing: 100},
+		{name: "Under absolute limit", limits: DeletionLimits{MaxDeletions: &maxDeletions}, deletions: 2, existing: 10},
+		{
+			name:          "Over absolute limit",
+			limits:        DeletionLimits{MaxDeletions: &maxDeletions},
+			deletions:     3,
+			existing:      10,
+			expectedError: "3 credentials would be deleted, which exceeds the maximum of 2",
+		},
+		{name: "Under percentage limit", limits: DeletionLimits{MaxDeletionsPercentage: &maxPercentage}, deletions: 5, existing: 10},
+		{
+			name:          "Over percentage limit",
+			limits:        DeletionLimits{MaxDeletionsPercentage: &maxPercentage},
+			deletions:     6,
+			existing:      10,
+			expectedError: "6 out of 10 credentials (60.0%) would be deleted, which exceeds the maximum of 50.0%",
+		},
+		{name: "Nothing on the target", limits: DeletionLimits{MaxDeletionsPercentage: &maxPercentage}, deletions: 0, existing: 0},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Check(tt.deletions, tt.existing)
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+
+	assert.NoError(t, DeletionLimits{MaxDeletions: &maxDeletions, MaxDeletionsPercentage: &maxPercentage}.Validate())
+	assert.EqualError(t, DeletionLimits{MaxDeletions: &negative}.Validate(), "`max_deletions` cannot be negative")
+	tooHigh := 150.0
+	assert.EqualError(t, DeletionLimits{MaxDeletionsPercentage: &tooHigh}.Validate(), "`max_deletions_percentage` must be between 0 and 100")
+}
+
+func TestProtectedCredentials(t *testing.T) {
+	t.Parallel()
+
+	jenkins := &JenkinsTarget{Base: Base{Name: "test", ProtectedCredentials: []string{"admin", "team-*"}}, URL: "https://test.com"}
+	assert.NoError(t, jenkins.BaseValidateConfiguration())
+
+	pattern, isProtected := GetProtectingPattern(jenkins, "team-a")
+	assert.True(t, isProtected)
+	assert.Equal(t, "team-*", pattern)
+	_, isProtected = GetProtectingPattern(jenkins, "admin")
+	assert.True(t, isProtected)
+	_, isProtected = GetProtectingPattern(jenkins, "other")
+	assert.False(t, isProtected)
+
+	jenkins.ProtectedCredentials = []string{"[bad"}
+	assert.Error(t, jenkins.BaseValidateConfiguration())
+}
+
+func TestDecodeTargetSafetyOptions(t *testing.T) {
+	t.Parallel()
+
+	target, err := New("jenkins")
+	assert.NoError(t, err)
+	assert.NoError(t, mapstructure.Decode(map[string]interface{}{
+		"name":                     "test",
+		"url":                      "https://test.com",
+		"max_deletions":            5,
+		"max_deletions_percentage": 10.5,
+		"protected_credentials":    []string{"admin"},
+	}, target))
+	limits := target.GetDeletionLimits()
+	assert.Equal(t, 5, *limits.MaxDeletions)
+	assert.Equal(t, 10.5, *limits.MaxDeletionsPercentage)
+	assert.Equal(t, []string{"admin"}, target.GetProtectedCredentials())
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	base := &Base{Name: "test", Timeout: 30 * time.Second}
+	assert.Equal(t, 30*time.Second, base.GetTimeout())
+	assert.NoError(t, base.BaseValidateConfiguration())
+
+	base.Timeout = -time.Second
+	assert.EqualError(t, base.BaseValidateConfiguration(), "`timeout` cannot be negative on test")
+}
+
+func TestRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	base := &Base{Name: "test", Retry: retry.Policy{MaxAttempts: aws.Int(5)}}
+	base.SetDefaultRetryPolicy(retry.Policy{MaxAttempts: aws.Int(2), Jitter: aws.Float64(0)})
+	assert.Equal(t, 5, base.GetRetryPolicy().GetMaxAttempts())
+	assert.Equal(t, 0.0, *base.GetRetryPolicy().Jitter)
+	assert.NoError(t, base.BaseValidateConfiguration())
+
+	base.Retry.Jitter = aws.Float64(2)
+	assert.EqualError(t, base.BaseValidateConfiguration(), "Invalid retry policy on test: `jitter` must be between 0 and 1")
+}
+
+func TestToEnvironmentVariables(t *testing.T) {
+	t.Parallel()
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.Username = "user"
+	userPass.Password = "pass"
+	variables, err := toEnvironmentVariables(userPass)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"MY_CRED_USERNAME": "user", "MY_CRED_PASSWORD": "pass"}, variables)
+
+	secret := credentials.NewSecretText()
+	secret.ID = "slack.token"
+	secret.TargetID = "slack_bot"
+	secret.Secret = "xoxb"
+	variables, err = toEnvironmentVariables(secret)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"SLACK_BOT": "xoxb"}, variables)
+
+	aws := credentials.NewAmazonWebServicesCredentials()
+	aws.ID = "aws"
+	aws.AccessKey = "AKIA"
+	aws.SecretKey = "secret"
+	variables, err = toEnvironmentVariables(aws)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"AWS_ACCESS_KEY_ID": "AKIA", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_ROLE_ARN": "", "AWS_MFA_SERIAL": ""}, variables)
+
+	app := credentials.NewGithubAppCredentials()
+	app.ID = "app"
+	app.AppID = 1234
+	app.PrivateKey = "key"
+	variables, err = toEnvironmentVariables(app)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"APP_APP_ID": "1234", "APP_PRIVATE_KEY": "key", "APP_OWNER": ""}, variables)
+
+	secretFile, cert, keystore := newTestFileCredentials(t)
+	variables, err = toEnvironmentVariables(secretFile)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"KUBECONFIG": "apiVersion: v1\nkind: Config"}, variables)
+	assert.Equal(t, map[string]bool{"KUBECONFIG": true}, fileEnvironmentVariables(secretFile))
+	variables, err = toEnvironmentVariables(cert)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"CLIENT_CERT_KEYSTORE": keystore, "CLIENT_CERT_PASSWORD": "hunter42"}, variables)
+	assert.Equal(t, map[string]bool{}, fileEnvironmentVariables(cert))
+}
+
+// newTestFileCredentials returns secret file and certificate credentials, along with the base64 keystore of the certificate
+func newTestFileCredentials(t *testing.T) (*credentials.SecretFileCredentials, *credentials.CertificateCredentials, string) {
+	secretFile := credentials.NewSecretFileCredentials()
+	secretFile.ID = "kubeconfig"
+	secretFile.Filename = "config"
+	secretFile.ContentBase64 = base64.StdEncoding.EncodeToString([]byte("apiVersion: v1\nkind: Config"))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	assert.NoError(t, err)
+	privateKeyBytes, err := x509.MarshalECPrivateKey(privateKey)
+	assert.NoError(t, err)
+
+	cert := credentials.NewCertificateCredentials()
+	cert.ID = "client-cert"
+	cert.Certificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}))
+	cert.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateKeyBytes}))
+	cert.Password = "hunter42"
+	keystore, err := cert.GetPKCS12()
+	assert.NoError(t, err)
+	return secretFile, cert, base64.StdEncoding.EncodeToString(keystore)
+}
+
+func TestGroupExistingCredentialsIgnoresCredentialsOfOtherTargets(t *testing.T) {
+	t.Parallel()
+
+	synced := credentials.NewUsernamePassword()
+	synced.ID = "synced"
+	synced.TargetID = "shared"
+	synced.TargetName = "test"
+	other := credentials.NewSecretText()
+	other.ID = "other"
+	other.TargetID = "shared"
+	other.TargetName = "other-target"
+
+	target := &GithubTarget{Base: Base{Name: "test"}}
+	existingEntries := map[string]bool{"SHARED": true, "SHARED_USERNAME": true}
+	existing, credentialsEntries := groupExistingCredentials(syncedCredentials(target, []credentials.Credentials{synced, other}), existingEntries, func(creds credentials.Credentials) []string {
+		names := []string{}
+		variables, err := toEnvironmentVariables(creds)
+		assert.NoError(t, err)
+		for name := range variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	})
+
+	// The `SHARED` entry is not owned by the credentials of the other target, so it is reported as unsynced
+	assert.Equal(t, []string{"SHARED", "shared"}, existing)
+	assert.Equal(t, map[string][]string{"shared": {"SHARED_PASSWORD", "SHARED_USERNAME"}}, credentialsEntries)
+}
+
+func TestConversionErrorsAreReturned(t *testing.T) {
+	t.Parallel()
+
+	secretFile := credentials.NewSecretFileCredentials()
+	secretFile.ID = "kubeconfig"
+	secretFile.Filename = "config"
+	secretFile.ContentBase64 = "not base64"
+
+	cases := []struct {
+		target        Target
+		expectedError string
+	}{
+		{&GithubTarget{Base: Base{Name: "github"}}, "unable to create github secrets from kubeconfig: unable to decode the content of the secret file: "},
+		{&GitlabTarget{Base: Base{Name: "gitlab"}}, "unable to create gitlab variables from kubeconfig: unable to decode the content of the secret file: "},
+		{&KubernetesTarget{Base: Base{Name: "kubernetes"}}, "unable to create a kubernetes secret from kubeconfig: unable to decode the content of the secret file: "},
+		{&KubernetesTarget{Base: Base{Name: "combined"}, CombinedSecret: "credentials"}, "unable to create kubernetes secret data from kubeconfig: unable to decode the content of the secret file: "},
+		{&FileTarget{Base: Base{Name: "file"}, Format: "dotenv"}, "unable to create environment variables from kubeconfig: unable to decode the content of the secret file: "},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.target.GetName(), func(t *testing.T) {
+			_, err := tt.target.(PayloadRenderer).RenderCredentials(secretFile)
+			assert.ErrorContains(t, err, tt.expectedError)
+			assert.ErrorContains(t, tt.target.UpdateCredentials(context.Background(), secretFile), tt.expectedError)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package targets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/vault"
+)
+
+const (
+	defaultVaultTargetMount = "secret"
+
+	vaultManagedByMetadata   = "managed-by"
+	vaultSourceMetadata      = "source"
+	vaultSyncedAtMetadata    = "synced-at"
+	vaultDescriptionMetadata = "description"
+)
+
+// VaultTarget represents the secrets of a HashiCorp Vault KV v2 secrets engine, under a path prefix
+// Each credentials is synced to a secret named after the prefix and the credentials' target ID
+// The secret's data is a single credentials document, and its custom metadata records the source and the time of the sync
+type VaultTarget struct {
+	Base                `mapstructure:",squash"`
+	vault.Configuration `mapstructure:",squash"`
+
+	Mount  string `mapstructure:"mount"`
+	Prefix string `mapstructure:"prefix"`
+	// Deleted credentials are destroyed (all versions and metadata) instead of only deleting the latest version
+	Destroy bool `mapstructure:"destroy"`
+
+	kv                  *vault.KV
+	existingCredentials []string
+	// Credentials target ID -> Custom metadata of the secret
+	customMetadata map[string]map[string]string
+}
+
+func (target *VaultTarget) getMount() string {
+	if target.Mount == "" {
+		return defaultVaultTargetMount
+	}
+	return strings.Trim(target.Mount, "/")
+}
+
+func (target *VaultTarget) secretPath(id string) string {
+	return strings.Trim(target.Prefix, "/") + "/" + id
+}
+
+// Initialize executes all necessary operations to prepare the Vault target for sync
+// Secrets whose latest version is deleted are not considered as existing
+func (target *VaultTarget) Initialize(ctx context.Context, allCredentials []credentials.Credentials) error {
+	if target.kv == nil {
+		client, err := target.NewClient(ctx, target.Retry, target.Name)
+		if err != nil {
+			return err
+		}
+		target.kv = &vault.KV{Client: client, Mount: target.getMount(), Version: 2}
+	}
+
+	secretPaths, err := target.kv.List(ctx, strings.Trim(target.Prefix, "/"))
+	if err != nil {
+		return fmt.Errorf("unable to list the secrets under %s: %v", target.Prefix, err)
+	}
+
+	target.existingCredentials = []string{}
+	target.customMetadata = map[string]map[string]string{}
+	for _, secretPath := range secretPaths {
+		metadata, err := target.kv.ReadMetadata(ctx, secretPath)
+		if err != nil {
+			return fmt.Errorf("unable to read the metadata of the secret %s: %v", secretPath, err)
+		}
+		if metadata == nil || metadata.Deleted {
+			continue
+		}
+		id := strings.TrimPrefix(secretPath, strings.Trim(target.Prefix, "/")+"/")
+		target.existingCredentials = append(target.existingCredentials, id)
+		target.customMetadata[id] = metadata.CustomMetadata
+	}
+	return nil
+}
+
+// ToString prints out a description of the Vault target
+func (target *VaultTarget) ToString() string {
+	return fmt.Sprintf("%s (HashiCorp Vault) - %s/%s on %s", target.BaseToString(), target.getMount(), strings.Trim(target.Prefix, "/"), target.Address)
+}
+
+// GetExistingCredentials returns a list of all credential IDs on the target
+func (target *VaultTarget) GetExistingCredentials() []string {
+	return target.existingCredentials
+}
+
+// DeleteCredentials deletes the latest version of the secret of the credentials with the given ID, or destroys the
+// secret if the target is configured to
+func (target *VaultTarget) DeleteCredentials(ctx context.Context, id string) error {
+	if target.Destroy {
+		return target.kv.Destroy(ctx, target.secretPath(id))
+	}
+	return target.kv.Delete(ctx, target.secretPath(id))
+}
+
+// GetCredentialsDescription returns the description of the secret of the credentials with the given ID
+func (target *VaultTarget) GetCredentialsDescription(ctx context.Context, id string) (string, error) {
+	customMetadata, ok := target.customMetadata[id]
+	if !ok {
+		return "", fmt.Errorf("the secret %s does not exist", target.secretPath(id))
+	}
+	return customMetadata[vaultDescriptionMetadata], nil
+}
+
+// SetCredentialsDescription modifies the description of the secret of the credentials with the given ID
+// The description is stored in the custom metadata of the secret, so no new version is created
+func (target *VaultTarget) SetCredentialsDescription(ctx context.Context, id string, description string) error {
+	customMetadata := map[string]string{}
+	for key, value := range target.customMetadata[id] {
+		customMetadata[key] = value
+	}
+	customMetadata[vaultDescriptionMetadata] = description
+	if err := target.kv.WriteCustomMetadata(ctx, target.secretPath(id), customMetadata); err != nil {
+		return err
+	}
+	target.customMetadata[id] = customMetadata
+	return nil
+}
+
+// RenderCredentials returns the data of the secret of the given credentials
+func (target *VaultTarget) RenderCredentials(cred credentials.Credentials) ([]byte, error) {
+	document, err := toCredentialsDocument(cred, target.markedDescription(cred))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(document)
+}
+
+// UpdateCredentials writes a new version of the secret of the given credentials and updates its custom metadata
+func (target *VaultTarget) UpdateCredentials(ctx context.Context, cred credentials.Credentials) error {
+	description := target.markedDescription(cred)
+	document, err := toCredentialsDocument(cred, description)
+	if err != nil {
+		return err
+	}
+	secretPath := target.secretPath(cred.GetTargetID())
+	if err := target.kv.Write(ctx, secretPath, document); err != nil {
+		return err
+	}
+
+	customMetadata := map[string]string{
+		vaultManagedByMetadata:   "credentials-sync",
+		vaultSyncedAtMetadata:    time.Now().UTC().Format(time.RFC3339),
+		vaultDescriptionMetadata: description,
+	}
+	if sourced, ok := cred.(interface{ GetSource() string }); ok && sourced.GetSource() != "" {
+		customMetadata[vaultSourceMetadata] = sourced.GetSource()
+	}
+	if err := target.kv.WriteCustomMetadata(ctx, secretPath, customMetadata); err != nil {
+		return fmt.Errorf("unable to write the metadata of the secret %s: %v", secretPath, err)
+	}
+	target.customMetadata[cred.GetTargetID()] = customMetadata
+	return nil
+}
+
+// ValidateConfiguration verifies that the Vault target's configuration is valid
+func (target *VaultTarget) ValidateConfiguration() error {
+	if strings.Trim(target.Prefix, "/") == "" {
+		return fmt.Errorf("the Vault target `%s` must define a `prefix`", target.Name)
+	}
+	if err := target.Configuration.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration on the Vault target `%s`: %v", target.Name, err)
+	}
+	return nil
+}
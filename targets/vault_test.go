@@ -0,0 +1,127 @@
+package targets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coveooss/credentials-sync/credentials"
+	"github.com/coveooss/credentials-sync/vault"
+	"github.com/coveooss/credentials-sync/vault/vaulttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultTargetValidateConfiguration(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		target        *VaultTarget
+		expectedError error
+	}{
+		{
+			name:          "Valid",
+			target:        &VaultTarget{Base: Base{Name: "test"}, Prefix: "ci/"},
+			expectedError: nil,
+		},
+		{
+			name:          "Missing prefix",
+			target:        &VaultTarget{Base: Base{Name: "test"}, Prefix: "/"},
+			expectedError: fmt.Errorf("the Vault target `test` must define a `prefix`"),
+		},
+		{
+			name:          "Invalid auth",
+			target:        &VaultTarget{Base: Base{Name: "test"}, Prefix: "ci/", Configuration: vault.Configuration{Auth: vault.Auth{Method: "approle"}}},
+			expectedError: fmt.Errorf("invalid configuration on the Vault target `test`: `role_id` and `secret_id` must be defined to use the approle auth method"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, tt.target.ValidateConfiguration())
+		})
+	}
+}
+
+func TestVaultTargetSync(t *testing.T) {
+	t.Parallel()
+
+	server := vaulttest.NewServer("the-token", map[string]int{"kv": 2})
+	defer server.Close()
+	server.SetSecret("kv", "ci/my-cred", map[string]interface{}{"type": "secret", "secret": "old"})
+	server.SetSecret("kv", "ci/nested/other", map[string]interface{}{"type": "secret", "secret": "other"})
+	server.SetSecret("kv", "ci/deleted", map[string]interface{}{"type": "secret", "secret": "deleted"})
+	server.SetSecret("kv", "other", map[string]interface{}{"key": "not under the prefix"})
+
+	// Secrets whose latest version is deleted are not existing credentials
+	deletedTarget := &VaultTarget{Configuration: vault.Configuration{Address: server.URL, Auth: vault.Auth{Token: "the-token"}}, Mount: "kv", Prefix: "ci"}
+	assert.NoError(t, deletedTarget.Initialize(context.Background(), nil))
+	assert.NoError(t, deletedTarget.DeleteCredentials(context.Background(), "deleted"))
+
+	target := &VaultTarget{
+		Base:          Base{Name: "test"},
+		Configuration: vault.Configuration{Address: server.URL, Auth: vault.Auth{Token: "the-token"}},
+		Mount:         "/kv/",
+		Prefix:        "/ci/",
+	}
+	assert.Equal(t, fmt.Sprintf("test [Tags: ] (HashiCorp Vault) - kv/ci on %s", server.URL), target.ToString())
+	assert.NoError(t, target.Initialize(context.Background(), nil))
+	assert.Equal(t, []string{"my-cred", "nested/other"}, target.GetExistingCredentials())
+
+	description, err := target.GetCredentialsDescription(context.Background(), "my-cred")
+	assert.NoError(t, err)
+	assert.Equal(t, "", description)
+	assert.NoError(t, target.SetCredentialsDescription(context.Background(), "nested/other", "[unsynced] other"))
+	assert.Equal(t, map[string]interface{}{"description": "[unsynced] other"}, server.CustomMetadata("kv", "ci/nested/other"))
+	assert.Equal(t, map[string]interface{}{"type": "secret", "secret": "other"}, server.Secret("kv", "ci/nested/other"))
+	_, err = target.GetCredentialsDescription(context.Background(), "unknown")
+	assert.EqualError(t, err, "the secret ci/unknown does not exist")
+
+	userPass := credentials.NewUsernamePassword()
+	userPass.ID = "my-cred"
+	userPass.Description = "My credentials"
+	userPass.Username = "user"
+	userPass.Password = "pass"
+	userPass.SetSource("Local file")
+	assert.NoError(t, target.UpdateCredentials(context.Background(), userPass))
+	assert.Equal(t, map[string]interface{}{
+		"id":          "my-cred",
+		"type":        "usernamepassword",
+		"description": "My credentials",
+		"username":    "user",
+		"password":    "pass",
+	}, server.Secret("kv", "ci/my-cred"))
+	metadata := server.CustomMetadata("kv", "ci/my-cred")
+	syncedAt, err := time.Parse(time.RFC3339, fmt.Sprint(metadata["synced-at"]))
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), syncedAt, time.Minute)
+	delete(metadata, "synced-at")
+	assert.Equal(t, map[string]interface{}{"managed-by": "credentials-sync", "source": "Local file", "description": "My credentials"}, metadata)
+	description, err = target.GetCredentialsDescription(context.Background(), "my-cred")
+	assert.NoError(t, err)
+	assert.Equal(t, "My credentials", description)
+
+	// Soft delete keeps the secret's metadata and previous versions
+	assert.NoError(t, target.DeleteCredentials(context.Background(), "nested/other"))
+	assert.Nil(t, server.Secret("kv", "ci/nested/other"))
+	assert.NotNil(t, server.CustomMetadata("kv", "ci/nested/other"))
+
+	target.Destroy = true
+	assert.NoError(t, target.DeleteCredentials(context.Background(), "my-cred"))
+	assert.Nil(t, server.CustomMetadata("kv", "ci/my-cred"))
+}
+
+func TestVaultTargetRenderCredentials(t *testing.T) {
+	t.Parallel()
+
+	secret := credentials.NewSecretText()
+	secret.ID = "slack"
+	secret.TargetID = "slack-token"
+	secret.Secret = "xoxb"
+
+	target := &VaultTarget{Base: Base{Name: "test", OwnershipMarker: "[managed]"}, Prefix: "ci"}
+	rendered, err := target.RenderCredentials(secret)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": "slack-token", "type": "secret", "description": "slack [managed]", "secret": "xoxb"}`, string(rendered))
+}
@@ -0,0 +1,148 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// KV reads and writes secrets in a KV secrets engine
+type KV struct {
+	Client *api.Client
+	// Path where the secrets engine is mounted
+	Mount string
+	// Version of the secrets engine, 1 or 2
+	Version int
+}
+
+func (kv *KV) dataPath(secretPath string) string {
+	if kv.Version == 1 {
+		return path.Join(kv.Mount, secretPath)
+	}
+	return path.Join(kv.Mount, "data", secretPath)
+}
+
+func (kv *KV) metadataPath(secretPath string) string {
+	if kv.Version == 1 {
+		return path.Join(kv.Mount, secretPath)
+	}
+	return path.Join(kv.Mount, "metadata", secretPath)
+}
+
+// Read returns the data of the latest version of a secret, or nil if the secret does not exist
+func (kv *KV) Read(ctx context.Context, secretPath string) (map[string]interface{}, error) {
+	secret, err := kv.Client.Logical().ReadWithContext(ctx, kv.dataPath(secretPath))
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+	if kv.Version == 1 {
+		return secret.Data, nil
+	}
+
+	// The data of deleted secrets is null
+	data, _ := secret.Data["data"].(map[string]interface{})
+	return data, nil
+}
+
+// List returns the paths of all secrets under the given prefix, recursively
+func (kv *KV) List(ctx context.Context, prefix string) ([]string, error) {
+	secret, err := kv.Client.Logical().ListWithContext(ctx, kv.metadataPath(prefix))
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+	keys, ok := secret.Data["keys"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Unexpected list of keys under %s: %v", prefix, secret.Data["keys"])
+	}
+
+	secretPaths := []string{}
+	for _, key := range keys {
+		keyAsString := fmt.Sprint(key)
+		keyPath := strings.TrimSuffix(prefix, "/") + "/" + keyAsString
+		if !strings.HasSuffix(keyAsString, "/") {
+			secretPaths = append(secretPaths, strings.TrimPrefix(keyPath, "/"))
+			continue
+		}
+		subPaths, err := kv.List(ctx, keyPath)
+		if err != nil {
+			return nil, err
+		}
+		secretPaths = append(secretPaths, subPaths...)
+	}
+	return secretPaths, nil
+}
+
+// Write creates a new version of a secret with the given data
+func (kv *KV) Write(ctx context.Context, secretPath string, data map[string]interface{}) error {
+	if kv.Version != 1 {
+		data = map[string]interface{}{"data": data}
+	}
+	_, err := kv.Client.Logical().WriteWithContext(ctx, kv.dataPath(secretPath), data)
+	return err
+}
+
+// Delete deletes a secret. On KV v2, only the latest version is deleted and it can be undeleted
+func (kv *KV) Delete(ctx context.Context, secretPath string) error {
+	_, err := kv.Client.Logical().DeleteWithContext(ctx, kv.dataPath(secretPath))
+	return err
+}
+
+// Destroy permanently deletes a secret. On KV v2, all its versions and its metadata are removed
+func (kv *KV) Destroy(ctx context.Context, secretPath string) error {
+	_, err := kv.Client.Logical().DeleteWithContext(ctx, kv.metadataPath(secretPath))
+	return err
+}
+
+// Metadata contains the attributes of a KV v2 secret that are not versioned
+type Metadata struct {
+	CustomMetadata map[string]string
+	// True if the latest version of the secret is deleted or destroyed
+	Deleted bool
+}
+
+// ReadMetadata returns the metadata of a KV v2 secret, or nil if the secret does not exist
+func (kv *KV) ReadMetadata(ctx context.Context, secretPath string) (*Metadata, error) {
+	if kv.Version == 1 {
+		return nil, fmt.Errorf("Metadata is only supported by KV v2 secrets engines")
+	}
+	secret, err := kv.Client.Logical().ReadWithContext(ctx, kv.metadataPath(secretPath))
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	metadata := &Metadata{CustomMetadata: map[string]string{}}
+	customMetadata, _ := secret.Data["custom_metadata"].(map[string]interface{})
+	for key, value := range customMetadata {
+		metadata.CustomMetadata[key] = fmt.Sprint(value)
+	}
+	versions, _ := secret.Data["versions"].(map[string]interface{})
+	currentVersion, _ := versions[fmt.Sprint(secret.Data["current_version"])].(map[string]interface{})
+	if deletionTime, _ := currentVersion["deletion_time"].(string); deletionTime != "" {
+		metadata.Deleted = true
+	}
+	if destroyed, _ := currentVersion["destroyed"].(bool); destroyed {
+		metadata.Deleted = true
+	}
+	return metadata, nil
+}
+
+// WriteCustomMetadata replaces the custom metadata of a KV v2 secret
+func (kv *KV) WriteCustomMetadata(ctx context.Context, secretPath string, customMetadata map[string]string) error {
+	if kv.Version == 1 {
+		return fmt.Errorf("Metadata is only supported by KV v2 secrets engines")
+	}
+	_, err := kv.Client.Logical().WriteWithContext(ctx, kv.metadataPath(secretPath), map[string]interface{}{"custom_metadata": customMetadata})
+	return err
+}
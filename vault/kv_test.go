@@ -0,0 +1,122 @@
+package vault
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coveooss/credentials-sync/retry"
+	"github.com/coveooss/credentials-sync/vault/vaulttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKV(t *testing.T) {
+	t.Parallel()
+
+	server := vaulttest.NewServer("the-token", map[string]int{"kv1": 1, "kv2": 2})
+	defer server.Close()
+
+	config := &Configuration{Address: server.URL, Auth: Auth{Token: "the-token"}}
+	client, err := config.NewClient(context.Background(), retry.Policy{}, "test")
+	assert.NoError(t, err)
+
+	for _, version := range []int{1, 2} {
+		mount := map[int]string{1: "kv1", 2: "kv2"}[version]
+		server.SetSecret(mount, "ci/slack", map[string]interface{}{"secret": "token"})
+		server.SetSecret(mount, "ci/github/bot", map[string]interface{}{"username": "bot", "password": "pass"})
+		server.SetSecret(mount, "other", map[string]interface{}{"key": "value"})
+		kv := &KV{Client: client, Mount: mount, Version: version}
+
+		data, err := kv.Read(context.Background(), "ci/slack")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"secret": "token"}, data)
+
+		data, err = kv.Read(context.Background(), "ci/unknown")
+		assert.NoError(t, err)
+		assert.Nil(t, data)
+
+		paths, err := kv.List(context.Background(), "ci/")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ci/github/bot", "ci/slack"}, paths)
+
+		paths, err = kv.List(context.Background(), "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ci/github/bot", "ci/slack", "other"}, paths)
+
+		paths, err = kv.List(context.Background(), "unknown")
+		assert.NoError(t, err)
+		assert.Empty(t, paths)
+	}
+}
+
+func TestKVWrite(t *testing.T) {
+	t.Parallel()
+
+	server := vaulttest.NewServer("the-token", map[string]int{"kv1": 1, "kv2": 2})
+	defer server.Close()
+
+	config := &Configuration{Address: server.URL, Auth: Auth{Token: "the-token"}}
+	client, err := config.NewClient(context.Background(), retry.Policy{}, "test")
+	assert.NoError(t, err)
+
+	for _, version := range []int{1, 2} {
+		mount := map[int]string{1: "kv1", 2: "kv2"}[version]
+		kv := &KV{Client: client, Mount: mount, Version: version}
+
+		assert.NoError(t, kv.Write(context.Background(), "ci/slack", map[string]interface{}{"secret": "token"}))
+		assert.Equal(t, map[string]interface{}{"secret": "token"}, server.Secret(mount, "ci/slack"))
+
+		data, err := kv.Read(context.Background(), "ci/slack")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"secret": "token"}, data)
+
+		assert.NoError(t, kv.Delete(context.Background(), "ci/slack"))
+		data, err = kv.Read(context.Background(), "ci/slack")
+		assert.NoError(t, err)
+		assert.Nil(t, data)
+	}
+}
+
+func TestKVMetadata(t *testing.T) {
+	t.Parallel()
+
+	server := vaulttest.NewServer("the-token", map[string]int{"kv1": 1, "kv2": 2})
+	defer server.Close()
+
+	config := &Configuration{Address: server.URL, Auth: Auth{Token: "the-token"}}
+	client, err := config.NewClient(context.Background(), retry.Policy{}, "test")
+	assert.NoError(t, err)
+
+	kv := &KV{Client: client, Mount: "kv2", Version: 2}
+	assert.NoError(t, kv.Write(context.Background(), "ci/slack", map[string]interface{}{"secret": "token"}))
+	assert.NoError(t, kv.WriteCustomMetadata(context.Background(), "ci/slack", map[string]string{"owner": "ci"}))
+
+	metadata, err := kv.ReadMetadata(context.Background(), "ci/slack")
+	assert.NoError(t, err)
+	assert.Equal(t, &Metadata{CustomMetadata: map[string]string{"owner": "ci"}}, metadata)
+
+	// Soft deleted secrets keep their metadata and are still listed
+	assert.NoError(t, kv.Delete(context.Background(), "ci/slack"))
+	metadata, err = kv.ReadMetadata(context.Background(), "ci/slack")
+	assert.NoError(t, err)
+	assert.Equal(t, &Metadata{CustomMetadata: map[string]string{"owner": "ci"}, Deleted: true}, metadata)
+	paths, err := kv.List(context.Background(), "ci")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ci/slack"}, paths)
+
+	// Writing a new version undeletes the secret
+	assert.NoError(t, kv.Write(context.Background(), "ci/slack", map[string]interface{}{"secret": "new-token"}))
+	metadata, err = kv.ReadMetadata(context.Background(), "ci/slack")
+	assert.NoError(t, err)
+	assert.False(t, metadata.Deleted)
+
+	assert.NoError(t, kv.Destroy(context.Background(), "ci/slack"))
+	metadata, err = kv.ReadMetadata(context.Background(), "ci/slack")
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+	paths, err = kv.List(context.Background(), "ci")
+	assert.NoError(t, err)
+	assert.Empty(t, paths)
+
+	_, err = (&KV{Client: client, Mount: "kv1", Version: 1}).ReadMetadata(context.Background(), "ci/slack")
+	assert.Error(t, err)
+}
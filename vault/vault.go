@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coveooss/credentials-sync/retry"
+	"github.com/hashicorp/vault/api"
+)
+
+const defaultKubernetesJWTFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Configuration defines how to connect and authenticate to a Vault server
+type Configuration struct {
+	// Address of the Vault server. Defaults to the VAULT_ADDR env variable
+	Address   string `mapstructure:"address"`
+	Namespace string `mapstructure:"namespace"`
+	Auth      Auth   `mapstructure:"auth"`
+}
+
+// Auth defines how to authenticate to a Vault server
+// Supported methods are `token` (the default), `approle` and `kubernetes`
+type Auth struct {
+	Method string `mapstructure:"method"`
+	// Path where the auth method is mounted. Defaults to the name of the method
+	MountPath string `mapstructure:"mount_path"`
+
+	// Token auth. Defaults to the VAULT_TOKEN env variable
+	Token string `mapstructure:"token"`
+
+	// AppRole auth
+	RoleID   string `mapstructure:"role_id"`
+	SecretID string `mapstructure:"secret_id"`
+
+	// Kubernetes auth
+	Role    string `mapstructure:"role"`
+	JWTFile string `mapstructure:"jwt_file"`
+}
+
+// Validate verifies that the configuration's attributes are valid
+func (config *Configuration) Validate() error {
+	switch config.Auth.Method {
+	case "", "token":
+	case "approle":
+		if config.Auth.RoleID == "" || config.Auth.SecretID == "" {
+			return fmt.Errorf("`role_id` and `secret_id` must be defined to use the approle auth method")
+		}
+	case "kubernetes":
+		if config.Auth.Role == "" {
+			return fmt.Errorf("`role` must be defined to use the kubernetes auth method")
+		}
+	default:
+		return fmt.Errorf("Unknown auth method: %s. Valid methods are `token`, `approle` and `kubernetes`", config.Auth.Method)
+	}
+	return nil
+}
+
+// NewClient creates a client for the configured Vault server and authenticates it
+// Requests that fail with a transient error are retried according to the given policy
+func (config *Configuration) NewClient(ctx context.Context, policy retry.Policy, name string) (*api.Client, error) {
+	clientConfig := api.DefaultConfig()
+	if clientConfig.Error != nil {
+		return nil, fmt.Errorf("Error while configuring the Vault client: %v", clientConfig.Error)
+	}
+	if config.Address != "" {
+		clientConfig.Address = config.Address
+	}
+	// Retries are handled by the transport, so that they follow the same policy as other systems
+	clientConfig.MaxRetries = 0
+	clientConfig.HttpClient.Transport = &retry.Transport{Base: clientConfig.HttpClient.Transport, Policy: policy, Name: name}
+
+	client, err := api.NewClient(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Error while creating the Vault client: %v", err)
+	}
+	if config.Namespace != "" {
+		client.SetNamespace(config.Namespace)
+	}
+
+	if err := config.login(ctx, client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (config *Configuration) login(ctx context.Context, client *api.Client) error {
+	var data map[string]interface{}
+	switch config.Auth.Method {
+	case "", "token":
+		if config.Auth.Token != "" {
+			client.SetToken(config.Auth.Token)
+		}
+		if client.Token() == "" {
+			return fmt.Errorf("No Vault token found. Set the `token` attribute or the VAULT_TOKEN env variable")
+		}
+		return nil
+	case "approle":
+		data = map[string]interface{}{"role_id": config.Auth.RoleID, "secret_id": config.Auth.SecretID}
+	case "kubernetes":
+		jwtFile := config.Auth.JWTFile
+		if jwtFile == "" {
+			jwtFile = defaultKubernetesJWTFile
+		}
+		jwt, err := os.ReadFile(jwtFile)
+		if err != nil {
+			return fmt.Errorf("Error while reading the service account token: %v", err)
+		}
+		data = map[string]interface{}{"role": config.Auth.Role, "jwt": strings.TrimSpace(string(jwt))}
+	}
+
+	mountPath := config.Auth.MountPath
+	if mountPath == "" {
+		mountPath = config.Auth.Method
+	}
+	secret, err := client.Logical().WriteWithContext(ctx, fmt.Sprintf("auth/%s/login", strings.Trim(mountPath, "/")), data)
+	if err != nil {
+		return fmt.Errorf("Error while logging in to Vault with the %s auth method: %v", config.Auth.Method, err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return fmt.Errorf("Vault did not return a token when logging in with the %s auth method", config.Auth.Method)
+	}
+	client.SetToken(secret.Auth.ClientToken)
+	return nil
+}
@@ -0,0 +1,119 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coveooss/credentials-sync/retry"
+	"github.com/coveooss/credentials-sync/vault/vaulttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		auth          Auth
+		expectedError error
+	}{
+		{
+			name:          "Default (token)",
+			auth:          Auth{},
+			expectedError: nil,
+		},
+		{
+			name:          "AppRole",
+			auth:          Auth{Method: "approle", RoleID: "role", SecretID: "secret"},
+			expectedError: nil,
+		},
+		{
+			name:          "AppRole without secret ID",
+			auth:          Auth{Method: "approle", RoleID: "role"},
+			expectedError: fmt.Errorf("`role_id` and `secret_id` must be defined to use the approle auth method"),
+		},
+		{
+			name:          "Kubernetes without role",
+			auth:          Auth{Method: "kubernetes"},
+			expectedError: fmt.Errorf("`role` must be defined to use the kubernetes auth method"),
+		},
+		{
+			name:          "Unknown method",
+			auth:          Auth{Method: "ldap"},
+			expectedError: fmt.Errorf("Unknown auth method: ldap. Valid methods are `token`, `approle` and `kubernetes`"),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Configuration{Auth: tt.auth}
+			assert.Equal(t, tt.expectedError, config.Validate())
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+
+	server := vaulttest.NewServer("the-token", map[string]int{"secret": 2})
+	defer server.Close()
+	server.Logins["approle"] = map[string]interface{}{"role_id": "role", "secret_id": "secret"}
+	server.Logins["k8s"] = map[string]interface{}{"role": "credentials-sync", "jwt": "a-jwt"}
+	server.SetSecret("secret", "test", map[string]interface{}{"key": "value"})
+
+	jwtFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(jwtFile, []byte("a-jwt\n"), 0600))
+
+	cases := []struct {
+		name          string
+		auth          Auth
+		expectedError string
+	}{
+		{
+			name: "Token",
+			auth: Auth{Token: "the-token"},
+		},
+		{
+			name:          "Invalid token",
+			auth:          Auth{Token: "other-token"},
+			expectedError: "permission denied",
+		},
+		{
+			name: "AppRole",
+			auth: Auth{Method: "approle", RoleID: "role", SecretID: "secret"},
+		},
+		{
+			name:          "AppRole with invalid secret ID",
+			auth:          Auth{Method: "approle", RoleID: "role", SecretID: "other"},
+			expectedError: "Error while logging in to Vault with the approle auth method",
+		},
+		{
+			name: "Kubernetes",
+			auth: Auth{Method: "kubernetes", MountPath: "k8s", Role: "credentials-sync", JWTFile: jwtFile},
+		},
+		{
+			name:          "Kubernetes with missing token file",
+			auth:          Auth{Method: "kubernetes", Role: "credentials-sync", JWTFile: filepath.Join(t.TempDir(), "missing")},
+			expectedError: "Error while reading the service account token",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Configuration{Address: server.URL, Auth: tt.auth}
+			client, err := config.NewClient(context.Background(), retry.Policy{}, "test")
+			if err == nil {
+				kv := &KV{Client: client, Mount: "secret", Version: 2}
+				_, err = kv.Read(context.Background(), "test")
+			}
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.expectedError)
+			}
+		})
+	}
+}
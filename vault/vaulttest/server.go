@@ -0,0 +1,237 @@
+// Package vaulttest provides a fake Vault server to test the systems that read and write secrets
+package vaulttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	gosync "sync"
+)
+
+// Server is an in-memory Vault server
+// It implements the login endpoint of auth methods and KV secrets engines (v1 and v2)
+type Server struct {
+	*httptest.Server
+
+	// Token accepted by the server. It is also returned by successful logins
+	Token string
+	// Logins maps the mount path of auth methods to the data that must be sent to log in
+	Logins map[string]map[string]interface{}
+
+	mutex gosync.Mutex
+	// Version of the KV secrets engine of each mount
+	mounts  map[string]int
+	secrets map[string]*fakeSecret
+}
+
+type fakeSecret struct {
+	data           map[string]interface{}
+	customMetadata map[string]interface{}
+	version        int
+	// True if the latest version is (soft) deleted
+	deleted bool
+}
+
+// NewServer starts a Vault server with the given KV mounts (mount path -> version)
+// It must be closed after use
+func NewServer(token string, mounts map[string]int) *Server {
+	server := &Server{
+		Token:   token,
+		Logins:  map[string]map[string]interface{}{},
+		mounts:  mounts,
+		secrets: map[string]*fakeSecret{},
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+	return server
+}
+
+// SetSecret sets the data of a secret
+func (server *Server) SetSecret(mount string, path string, data map[string]interface{}) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.writeSecret(mount+"/"+path, data)
+}
+
+// DeleteSecret deletes a secret. On KV v2 mounts, only its latest version is deleted, as Vault does by default
+func (server *Server) DeleteSecret(mount string, path string) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if server.mounts[mount] == 2 {
+		if secret, ok := server.secrets[mount+"/"+path]; ok {
+			secret.deleted = true
+		}
+		return
+	}
+	delete(server.secrets, mount+"/"+path)
+}
+
+// Secret returns the data of a secret, or nil if it does not exist or if its latest version is deleted
+func (server *Server) Secret(mount string, path string) map[string]interface{} {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if secret, ok := server.secrets[mount+"/"+path]; ok && !secret.deleted {
+		return secret.data
+	}
+	return nil
+}
+
+// CustomMetadata returns the custom metadata of a KV v2 secret, or nil if the secret does not exist
+func (server *Server) CustomMetadata(mount string, path string) map[string]interface{} {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if secret, ok := server.secrets[mount+"/"+path]; ok {
+		return secret.customMetadata
+	}
+	return nil
+}
+
+func (server *Server) writeSecret(key string, data map[string]interface{}) {
+	secret, ok := server.secrets[key]
+	if !ok {
+		secret = &fakeSecret{customMetadata: map[string]interface{}{}}
+		server.secrets[key] = secret
+	}
+	secret.data = data
+	secret.version++
+	secret.deleted = false
+}
+
+func (server *Server) handle(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if strings.HasPrefix(path, "auth/") && strings.HasSuffix(path, "/login") {
+		server.login(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "auth/"), "/login"))
+		return
+	}
+	if r.Header.Get("X-Vault-Token") != server.Token {
+		writeResponse(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+
+	for mount, version := range server.mounts {
+		// The client trims the trailing slash of paths, so the root of a mount is requested without it
+		secretPath, found := strings.CutPrefix(path+"/", mount+"/")
+		if !found {
+			continue
+		}
+		endpoint := ""
+		if version == 2 {
+			if endpoint, secretPath, found = strings.Cut(secretPath, "/"); !found || (endpoint != "data" && endpoint != "metadata") {
+				break
+			}
+		}
+		server.handleKV(w, r, mount, endpoint, strings.TrimSuffix(secretPath, "/"))
+		return
+	}
+	writeResponse(w, http.StatusNotFound, map[string]interface{}{"errors": []string{"no handler for route"}})
+}
+
+func (server *Server) login(w http.ResponseWriter, r *http.Request, mountPath string) {
+	var data map[string]interface{}
+	expected, ok := server.Logins[mountPath]
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || !ok || !reflect.DeepEqual(data, expected) {
+		writeResponse(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid credentials"}})
+		return
+	}
+	writeResponse(w, http.StatusOK, map[string]interface{}{"auth": map[string]interface{}{"client_token": server.Token}})
+}
+
+// handleKV handles the requests to a KV mount. The endpoint is empty on KV v1, and `data` or `metadata` on KV v2
+func (server *Server) handleKV(w http.ResponseWriter, r *http.Request, mount string, endpoint string, secretPath string) {
+	key := mount + "/" + secretPath
+	secret, exists := server.secrets[key]
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("list") == "true":
+		keys := map[string]bool{}
+		prefix := key + "/"
+		if secretPath == "" {
+			prefix = mount + "/"
+		}
+		for existingKey := range server.secrets {
+			if relativeKey, found := strings.CutPrefix(existingKey, prefix); found {
+				if index := strings.Index(relativeKey, "/"); index >= 0 {
+					relativeKey = relativeKey[:index+1]
+				}
+				keys[relativeKey] = true
+			}
+		}
+		if len(keys) == 0 {
+			writeResponse(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		sortedKeys := []string{}
+		for relativeKey := range keys {
+			sortedKeys = append(sortedKeys, relativeKey)
+		}
+		sort.Strings(sortedKeys)
+		writeResponse(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"keys": sortedKeys}})
+	case r.Method == http.MethodGet && endpoint == "metadata":
+		if !exists {
+			writeResponse(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		deletionTime := ""
+		if secret.deleted {
+			deletionTime = "2024-01-01T00:00:00Z"
+		}
+		writeResponse(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"current_version": secret.version,
+			"custom_metadata": secret.customMetadata,
+			"versions": map[string]interface{}{
+				strconv.Itoa(secret.version): map[string]interface{}{"deletion_time": deletionTime, "destroyed": false},
+			},
+		}})
+	case r.Method == http.MethodGet:
+		if !exists || secret.deleted {
+			writeResponse(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		if endpoint == "data" {
+			writeResponse(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"data": secret.data, "metadata": map[string]interface{}{}}})
+			return
+		}
+		writeResponse(w, http.StatusOK, map[string]interface{}{"data": secret.data})
+	case r.Method == http.MethodPost || r.Method == http.MethodPut:
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeResponse(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{err.Error()}})
+			return
+		}
+		switch endpoint {
+		case "metadata":
+			if !exists {
+				secret = &fakeSecret{}
+				server.secrets[key] = secret
+			}
+			secret.customMetadata, _ = body["custom_metadata"].(map[string]interface{})
+		case "data":
+			data, _ := body["data"].(map[string]interface{})
+			server.writeSecret(key, data)
+		default:
+			server.writeSecret(key, body)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && endpoint == "data":
+		if exists {
+			secret.deleted = true
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		delete(server.secrets, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeResponse(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}